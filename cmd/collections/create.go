@@ -0,0 +1,42 @@
+package collections
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/collection"
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/fs"
+	"github.com/spf13/cobra"
+)
+
+func newCreateCmd() *cobra.Command {
+	var goldenPath string
+
+	cmd := &cobra.Command{
+		Use:   "create <name> [--golden <path>]",
+		Short: "Create an empty collection (fill it with `hoopla ingest`)",
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) < 1 {
+				fmt.Println("❌ Please provide a collection name.")
+				return
+			}
+			name := args[0]
+
+			if _, err := collection.Create(name, goldenPath); err != nil {
+				log.Fatalf("❌ Failed to create collection: %v\n", err)
+			}
+
+			fmt.Printf("✅ Collection '%s' created at %s\n", name, fs.CollectionDir(name))
+			fmt.Printf("👉 Add documents with: hoopla ingest <source> --collection %s\n", name)
+		},
+	}
+
+	cmd.Flags().StringVar(&goldenPath, "golden", "", "Golden dataset to copy into the collection (used by evaluation)")
+
+	return cmd
+}
+
+func init() {
+	createCmd := newCreateCmd()
+	CollectionsCmd.AddCommand(createCmd)
+}
@@ -0,0 +1,46 @@
+package collections
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/collection"
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/fs"
+	"github.com/spf13/cobra"
+)
+
+func newDeleteCmd() *cobra.Command {
+	var yes bool
+
+	cmd := &cobra.Command{
+		Use:   "delete <name> --yes",
+		Short: "Delete a collection with its documents, index and embeddings",
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) < 1 {
+				fmt.Println("❌ Please provide a collection name.")
+				return
+			}
+			name := args[0]
+
+			if !yes {
+				fmt.Printf("⚠️ This removes %s and everything in it. Re-run with --yes to confirm.\n", fs.CollectionDir(name))
+				return
+			}
+
+			if err := collection.Delete(name); err != nil {
+				log.Fatalf("❌ Failed to delete collection: %v\n", err)
+			}
+
+			fmt.Printf("🗑️ Collection '%s' deleted.\n", name)
+		},
+	}
+
+	cmd.Flags().BoolVar(&yes, "yes", false, "Confirm the deletion")
+
+	return cmd
+}
+
+func init() {
+	deleteCmd := newDeleteCmd()
+	CollectionsCmd.AddCommand(deleteCmd)
+}
@@ -0,0 +1,39 @@
+package collections
+
+import (
+	"fmt"
+	"log"
+	"path/filepath"
+	"strings"
+
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/collection"
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/fs"
+	"github.com/spf13/cobra"
+)
+
+var listCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List all collections",
+	Run: func(cmd *cobra.Command, args []string) {
+		summaries, err := collection.List()
+		if err != nil {
+			log.Fatalf("❌ Failed to list collections: %v\n", err)
+		}
+
+		fmt.Printf("* (default) - %s\n", filepath.Join(fs.ProjectRoot, "data", "movies.json"))
+		for _, s := range summaries {
+			fmt.Printf("* %s - %d documents\n", s.Name, s.Documents)
+			if s.Source != "" {
+				fmt.Printf("\tSource: %s (%s)\n", s.Source, s.Format)
+			}
+			fmt.Printf("\tUpdated: %s\n", s.UpdatedAt.Format("2006-01-02 15:04:05"))
+			if len(s.Artifacts) > 0 {
+				fmt.Printf("\tArtifacts: %s\n", strings.Join(s.Artifacts, ", "))
+			}
+		}
+	},
+}
+
+func init() {
+	CollectionsCmd.AddCommand(listCmd)
+}
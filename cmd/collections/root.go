@@ -0,0 +1,14 @@
+package collections
+
+import (
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/collection"
+	"github.com/spf13/cobra"
+)
+
+var CollectionsCmd = &cobra.Command{
+	Use:     "collections",
+	Aliases: []string{"col"},
+	Short:   "Manage named collections (one data source and cache per collection)",
+	// The configured collection may be missing: these commands fix that
+	Annotations: map[string]string{collection.AnnotationManages: "true"},
+}
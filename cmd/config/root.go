@@ -0,0 +1,9 @@
+package config
+
+import "github.com/spf13/cobra"
+
+var ConfigCmd = &cobra.Command{
+	Use:     "config",
+	Aliases: []string{"cfg"},
+	Short:   "Inspect the resolved configuration (defaults < hoopla.yaml < HOOPLA_* env < flags)",
+}
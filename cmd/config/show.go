@@ -0,0 +1,43 @@
+package config
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/config"
+	"github.com/spf13/cobra"
+)
+
+var showCmd = &cobra.Command{
+	Use:   "show",
+	Short: "Print the effective configuration and where overridden values come from",
+	Run: func(cmd *cobra.Command, args []string) {
+		out, err := config.Marshal()
+		if err != nil {
+			log.Fatalf("❌ Failed to render configuration: %v\n", err)
+		}
+
+		if config.File() != "" {
+			fmt.Printf("# config file: %s\n", config.File())
+		} else {
+			fmt.Printf("# config file: none (looked for --config, $%s, ./%s)\n", config.EnvConfig, config.FileName)
+		}
+		fmt.Println(string(out))
+
+		fmt.Println("Overrides:")
+		overridden := 0
+		for _, key := range config.Keys() {
+			if origin := config.Origin(key); origin != "default" {
+				fmt.Printf("\t- %s: %s\n", key, origin)
+				overridden++
+			}
+		}
+		if overridden == 0 {
+			fmt.Println("\t(none, all values are defaults)")
+		}
+	},
+}
+
+func init() {
+	ConfigCmd.AddCommand(showCmd)
+}
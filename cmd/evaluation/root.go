@@ -6,32 +6,51 @@ import (
 	"slices"
 	"strings"
 
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/cli"
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/config"
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/embed"
 	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/fs"
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/logging"
 	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/methods"
+	"github.com/google/uuid"
 	"github.com/spf13/cobra"
 )
 
-var limit int
+var (
+	limit     int
+	aggregate string
+	debug     bool
+)
 
 var EvaluationCmd = &cobra.Command{
-	Use:     "evaluation [--limit <int>]",
+	Use:     "evaluation [--limit <int>] [--aggregate <max|topMean|decaySum|softmax|maxMean>]",
 	Aliases: []string{"eval"},
 	Short:   "Evaluation of the golden dataset",
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		return cli.ValidateFlagEnum(aggregate, "aggregate", methods.Aggregations...)
+	},
 	Run: func(cmd *cobra.Command, args []string) {
+		cfg := config.Get()
+		limit = cli.ResolveInt(cmd, "limit", limit, cfg.Search.Limit)
+
 		testCases, err := fs.LoadGoldenDataset()
 		if err != nil {
 			log.Fatalf("❌ Failed to load golden dataset: %v\n", err)
 		}
 
-		hs, err := methods.NewHybridSearch("nomic-embed-text")
+		hs, err := methods.NewHybridSearch()
 		if err != nil {
 			log.Fatalf("❌ Failed to create hybrid search client: %v\n", err)
 		}
+		hs.Css.Aggregation.Method = cli.ResolveString(cmd, "aggregate", aggregate, hs.Css.Aggregation.Method)
 
-		fmt.Printf("k=%d\n\n", limit)
+		logger := logging.New(debug)
+		execCtx := logging.ExecutionContext{RunID: uuid.New().String()}
+
+		fmt.Printf("k=%d | chunk aggregation: %s\n\n", limit, hs.Css.Aggregation)
 		for i, testCase := range testCases {
 			query := testCase.Query
-			k := 60
+			k := cfg.Hybrid.RRFK
 			rrfSearchResults, err := hs.RRFSearch(query, k, limit)
 			if err != nil {
 				log.Fatalf("❌ Failed to perform rrf search: %v\n", err)
@@ -62,9 +81,17 @@ var EvaluationCmd = &cobra.Command{
 			fmt.Printf("\t- Relevant: %s\n\n", strings.Join(testCase.RelevantDocs, ", "))
 		}
 
+		stats := embed.QueryCacheStats()
+		logging.LogQueryCache(logger, execCtx, stats.Hits, stats.Misses)
+		if cfg.Embedding.QueryCache > 0 {
+			fmt.Printf("🗃️ Query embedding cache: %d hits, %d embedded\n", stats.Hits, stats.Misses)
+		}
+
 	},
 }
 
 func init() {
 	EvaluationCmd.Flags().IntVar(&limit, "limit", 5, "Limit the amount of results")
+	EvaluationCmd.Flags().BoolVar(&debug, "debug", false, "Enable debug logging")
+	EvaluationCmd.Flags().StringVar(&aggregate, "aggregate", methods.AggregateMax, "How chunk scores become a document score (default from aggregation.method). [choices: max|topMean|decaySum|softmax|maxMean]")
 }
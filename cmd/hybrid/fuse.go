@@ -0,0 +1,320 @@
+package hybrid
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/cli"
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/config"
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/fs"
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/methods"
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/model"
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/trec"
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/utils"
+	"github.com/spf13/cobra"
+)
+
+func newFuseCmd() *cobra.Command {
+	var (
+		limit       int
+		k           int
+		retrievers  string
+		aggregate   string
+		method      string
+		norm        string
+		persistence float64
+		runs        string
+		saveRuns    string
+		qid         string
+		output      string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "fuse [query] [--retrievers <name=weight[@depth],...>] [--method <name>] [--runs <path[=weight],...>] [--limit <int>]",
+		Short: "Fuse any number of retrievers (bm25, rm3, semantic, chunked, hyde) or saved runs",
+		Example: `hybrid fuse "space adventure" --retrievers bm25=1,rm3=0.5,chunked=1
+hybrid fuse "heist movie" --retrievers bm25=1@100,semantic=1,hyde=0.5@50 --method combmnz --norm dbsf
+hybrid fuse "heist movie" --saveRuns runs --qid heist
+hybrid fuse --runs runs/bm25.run,runs/chunked.run=0.5 --method rbf --output runs/fused.run`,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if cmd.Flags().Changed("retrievers") {
+				if _, err := methods.ParseLegSpecs(retrievers); err != nil {
+					return fmt.Errorf("invalid --retrievers: %w", err)
+				}
+			}
+			if runs != "" {
+				if _, err := parseRunSpecs(runs); err != nil {
+					return fmt.Errorf("invalid --runs: %w", err)
+				}
+				if saveRuns != "" {
+					return fmt.Errorf("--saveRuns saves the retrievers' runs; it can't be used with --runs")
+				}
+			}
+			if qid == "" || strings.ContainsFunc(qid, unicode.IsSpace) {
+				return fmt.Errorf("invalid --qid: %q (must be a non-empty word)", qid)
+			}
+			if cmd.Flags().Changed("persistence") && (persistence <= 0 || persistence >= 1) {
+				return fmt.Errorf("invalid --persistence: %g (must be between 0 and 1, exclusive)", persistence)
+			}
+			if err := cli.ValidateFlagEnum(method, "method", methods.FusionMethods...); err != nil {
+				return err
+			}
+			if err := cli.ValidateFlagEnum(norm, "norm", methods.Normalizations...); err != nil {
+				return err
+			}
+			return cli.ValidateFlagEnum(aggregate, "aggregate", methods.Aggregations...)
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			cfg := config.Get()
+			limit = cli.ResolveInt(cmd, "limit", limit, cfg.Search.Limit)
+			opts := methods.FuseOptionsFromConfig()
+			opts.Method = cli.ResolveString(cmd, "method", method, opts.Method)
+			opts.Normalization = cli.ResolveString(cmd, "norm", norm, opts.Normalization)
+			opts.K = cli.ResolveInt(cmd, "k", k, opts.K)
+			opts.Persistence = cli.ResolveFloat(cmd, "persistence", persistence, opts.Persistence)
+			if err := opts.Validate(); err != nil {
+				log.Fatalf("❌ Invalid fusion: %v\n", err)
+			}
+
+			if runs != "" {
+				fuseRuns(runs, opts, limit, output)
+				return
+			}
+
+			if len(args) < 1 {
+				fmt.Println("❌ Please provide a query (or --runs).")
+				return
+			}
+			query := args[0]
+			retrievers = cli.ResolveString(cmd, "retrievers", retrievers, cfg.Fusion.Retrievers)
+
+			hs, err := methods.NewHybridSearch()
+			if err != nil {
+				log.Fatalf("❌ Failed to create hybrid search client: %v\n", err)
+			}
+			hs.Css.Aggregation.Method = cli.ResolveString(cmd, "aggregate", aggregate, hs.Css.Aggregation.Method)
+
+			fusion, err := hs.Fusion(retrievers, opts, limit)
+			if err != nil {
+				log.Fatalf("❌ Failed to set up the retrievers: %v\n", err)
+			}
+			rankings, err := fusion.Retrieve(context.Background(), query)
+			if err != nil {
+				log.Fatalf("❌ Failed to perform fused search: %v\n", err)
+			}
+			results, err := methods.Fuse(rankings, fusion.Weights(), opts)
+			if err != nil {
+				log.Fatalf("❌ Failed to fuse the rankings: %v\n", err)
+			}
+
+			if saveRuns != "" {
+				for i, leg := range fusion.Legs {
+					path := filepath.Join(saveRuns, leg.Retriever.Name()+".run")
+					if err := trec.Update(path, leg.Retriever.Name(), qid, candidatesToEntries(rankings[i])); err != nil {
+						log.Fatalf("❌ Failed to save run %s: %v\n", path, err)
+					}
+				}
+				if output == "" {
+					output = filepath.Join(saveRuns, "fused.run")
+				}
+				fmt.Printf("💾 Saved the runs of query %s in %s\n", qid, saveRuns)
+			}
+			if output != "" {
+				if err := trec.Update(output, "fused", qid, fusedToEntries(results)); err != nil {
+					log.Fatalf("❌ Failed to save run %s: %v\n", output, err)
+				}
+			}
+
+			if limit < len(results) {
+				results = results[:limit]
+			}
+			if len(results) == 0 {
+				fmt.Println("No results found.")
+				return
+			}
+			names := make([]string, len(fusion.Legs))
+			for i, leg := range fusion.Legs {
+				names[i] = leg.Retriever.Name()
+			}
+			fmt.Printf("Fused results for '%s' (%s; %s):\n\n", query, opts, retrievers)
+			printFused(results, names, hs.Css.DocumentMap)
+		},
+	}
+	cmd.Flags().IntVar(&limit, "limit", 5, "Limit the amount of results")
+	cmd.Flags().IntVar(&k, "k", 60, "RRF constant (default from fusion.k)")
+	cmd.Flags().StringVar(&retrievers, "retrievers", "", "Retrievers as name=weight[@depth], e.g. bm25=1,chunked=1,rm3=0.5@100 (default from fusion.retrievers). [names: bm25|rm3|semantic|chunked|hyde]")
+	cmd.Flags().StringVar(&aggregate, "aggregate", methods.AggregateMax, "How chunk scores become a document score (default from aggregation.method). [choices: max|topMean|decaySum|softmax|maxMean]")
+	cmd.Flags().StringVar(&method, "method", methods.FuseRRF, "How rankings are merged (default from fusion.method). [choices: rrf|rbf|combsum|combmnz]")
+	cmd.Flags().StringVar(&norm, "norm", methods.NormMinMax, "Score normalization for combsum/combmnz (default from fusion.normalization). [choices: minmax|zscore|dbsf]")
+	cmd.Flags().Float64Var(&persistence, "persistence", 0.8, "rbf persistence, between 0 and 1 (default from fusion.rbf_persistence)")
+	cmd.Flags().StringVar(&runs, "runs", "", "Fuse TREC run files instead of retrievers, as path[=weight],... (one leg per file)")
+	cmd.Flags().StringVar(&saveRuns, "saveRuns", "", "Directory to save every retriever's run (<retriever>.run) and the fused run in")
+	cmd.Flags().StringVar(&qid, "qid", "1", "Query ID written to saved runs")
+	cmd.Flags().StringVar(&output, "output", "", "File to write the fused run to")
+
+	return cmd
+}
+
+// runSpec is one entry of --runs.
+type runSpec struct {
+	Path   string
+	Weight float64
+}
+
+// parseRunSpecs parses "path[=weight],..."; weights default to 1.
+func parseRunSpecs(spec string) ([]runSpec, error) {
+	var specs []runSpec
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		path, weightStr, hasWeight := strings.Cut(part, "=")
+		weight := 1.0
+		if hasWeight {
+			w, err := strconv.ParseFloat(strings.TrimSpace(weightStr), 64)
+			if err != nil || w < 0 {
+				return nil, fmt.Errorf("invalid weight for run %s: %q (must be a number >= 0)", path, weightStr)
+			}
+			weight = w
+		}
+		specs = append(specs, runSpec{Path: strings.TrimSpace(path), Weight: weight})
+	}
+	if len(specs) == 0 {
+		return nil, fmt.Errorf("no runs in %q", spec)
+	}
+	return specs, nil
+}
+
+// fuseRuns fuses saved run files query by query, printing the limit best
+// results of each and writing the whole fused run to output if set.
+func fuseRuns(spec string, opts methods.FuseOptions, limit int, output string) {
+	specs, err := parseRunSpecs(spec)
+	if err != nil {
+		log.Fatalf("❌ Invalid --runs: %v\n", err)
+	}
+
+	loaded := make([]*trec.Run, len(specs))
+	names := make([]string, len(specs))
+	weights := make([]float64, len(specs))
+	qids := make(map[string]bool)
+	for i, s := range specs {
+		run, err := trec.Read(s.Path)
+		if err != nil {
+			log.Fatalf("❌ Failed to read run %s: %v\n", s.Path, err)
+		}
+		loaded[i], weights[i] = run, s.Weight
+		names[i] = strings.TrimSuffix(filepath.Base(s.Path), filepath.Ext(s.Path))
+		for qid := range run.Queries {
+			qids[qid] = true
+		}
+	}
+
+	movies, err := fs.LoadMovies()
+	if err != nil {
+		log.Fatalf("❌ Failed to load movies: %v\n", err)
+	}
+	documentMap := make(map[int]model.Movie, len(movies))
+	for _, m := range movies {
+		documentMap[m.ID] = m
+	}
+
+	fused := trec.NewRun("fused")
+	for qid := range qids {
+		fused.Queries[qid] = nil
+	}
+	for _, qid := range fused.QueryIDs() {
+		rankings := make([][]methods.Candidate, len(loaded))
+		for i, run := range loaded {
+			rankings[i], err = entriesToCandidates(run.Queries[qid])
+			if err != nil {
+				log.Fatalf("❌ Invalid run %s: %v\n", specs[i].Path, err)
+			}
+		}
+		results, err := methods.Fuse(rankings, weights, opts)
+		if err != nil {
+			log.Fatalf("❌ Failed to fuse the runs: %v\n", err)
+		}
+		fused.Queries[qid] = fusedToEntries(results)
+
+		if limit < len(results) {
+			results = results[:limit]
+		}
+		fmt.Printf("Fused results for query %s (%s; %s):\n\n", qid, opts, strings.Join(names, ", "))
+		if len(results) == 0 {
+			fmt.Print("No results found.\n\n")
+			continue
+		}
+		printFused(results, names, documentMap)
+	}
+
+	if output != "" {
+		if err := fused.Write(output); err != nil {
+			log.Fatalf("❌ Failed to save run %s: %v\n", output, err)
+		}
+		fmt.Printf("💾 Saved the fused run in %s\n", output)
+	}
+}
+
+func printFused(results []methods.FusedResult, names []string, documentMap map[int]model.Movie) {
+	for i, result := range results {
+		doc := documentMap[result.DocID]
+		fmt.Printf("%d. %s\n", i+1, doc.Title)
+		fmt.Printf("\tFused Score: %.4f\n", result.Score)
+		fmt.Printf("\t%s\n", legRanksToStr(names, result))
+		desc := utils.Truncate(doc.Description, 100)
+		fmt.Printf("\t%s...\n\n", desc)
+	}
+}
+
+// legRanksToStr lists the rank of a result in every leg (1-based), e.g.
+// "bm25 #1, chunked #4, rm3 -".
+func legRanksToStr(names []string, result methods.FusedResult) string {
+	parts := make([]string, len(names))
+	for i, name := range names {
+		if result.Ranks[i] < 0 {
+			parts[i] = name + " -"
+			continue
+		}
+		parts[i] = fmt.Sprintf("%s #%d", name, result.Ranks[i]+1)
+	}
+	return strings.Join(parts, ", ")
+}
+
+func candidatesToEntries(candidates []methods.Candidate) []trec.Entry {
+	entries := make([]trec.Entry, len(candidates))
+	for i, c := range candidates {
+		entries[i] = trec.Entry{DocID: strconv.Itoa(c.DocID), Score: c.Score}
+	}
+	return entries
+}
+
+func fusedToEntries(results []methods.FusedResult) []trec.Entry {
+	entries := make([]trec.Entry, len(results))
+	for i, r := range results {
+		entries[i] = trec.Entry{DocID: strconv.Itoa(r.DocID), Score: r.Score}
+	}
+	return entries
+}
+
+// entriesToCandidates reads the doc IDs of a run as movie IDs.
+func entriesToCandidates(entries []trec.Entry) ([]methods.Candidate, error) {
+	candidates := make([]methods.Candidate, len(entries))
+	for i, e := range entries {
+		id, err := strconv.Atoi(e.DocID)
+		if err != nil {
+			return nil, fmt.Errorf("doc ID %q is not a movie ID", e.DocID)
+		}
+		candidates[i] = methods.Candidate{DocID: id, Score: e.Score}
+	}
+	return candidates, nil
+}
+
+func init() {
+	HybridCmd.AddCommand(newFuseCmd())
+}
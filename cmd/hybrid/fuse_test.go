@@ -0,0 +1,38 @@
+package hybrid
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseRunSpecs(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    []runSpec
+		wantErr bool
+	}{
+		{spec: "runs/bm25.txt", want: []runSpec{{Path: "runs/bm25.txt", Weight: 1}}},
+		{
+			spec: "runs/bm25.txt=0.5, runs/chunked.txt ,runs/hyde.txt=0",
+			want: []runSpec{
+				{Path: "runs/bm25.txt", Weight: 0.5},
+				{Path: "runs/chunked.txt", Weight: 1},
+				{Path: "runs/hyde.txt", Weight: 0},
+			},
+		},
+		{spec: "", wantErr: true},
+		{spec: " , ", wantErr: true},
+		{spec: "runs/bm25.txt=-1", wantErr: true},
+		{spec: "runs/bm25.txt=heavy", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseRunSpecs(tt.spec)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseRunSpecs(%q) error = %v, want error: %v", tt.spec, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseRunSpecs(%q) = %+v, want %+v", tt.spec, got, tt.want)
+		}
+	}
+}
@@ -12,9 +12,12 @@ import (
 	"strings"
 
 	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/cli"
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/config"
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/embed"
 	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/llms"
 	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/logging"
 	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/methods"
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/utils"
 	"github.com/google/uuid"
 	"github.com/spf13/cobra"
 )
@@ -26,12 +29,14 @@ func newRRFSearchCmd() *cobra.Command {
 	var rerankMethod string
 	var debug bool
 	var evaluate bool
+	var aggregate string
+	var diversify float64
 
 	cmd := &cobra.Command{
-		Use:   "rrfSearch <query> [--limit <int>] [--k <int>] [--enhance <spell|rewrite|expand>] [--rerankMethod <individual|batch|crossEncoder>]",
+		Use:   "rrfSearch <query> [--limit <int>] [--k <int>] [--enhance <spell|rewrite|expand|hyde>] [--rerankMethod <individual|batch|crossEncoder>]",
 		Short: "Reciprocal Rank Fusion search combining both keyword and semantic.",
 		PreRunE: func(cmd *cobra.Command, args []string) error {
-			if err := cli.ValidateFlagEnum(enhance, "enhance", "spell", "rewrite", "expand"); err != nil {
+			if err := cli.ValidateFlagEnum(enhance, "enhance", llms.Enhancements...); err != nil {
 				return err
 			}
 			if err := cli.ValidateFlagEnum(
@@ -43,6 +48,9 @@ func newRRFSearchCmd() *cobra.Command {
 			); err != nil {
 				return err
 			}
+			if err := cli.ValidateFlagEnum(aggregate, "aggregate", methods.Aggregations...); err != nil {
+				return err
+			}
 			return nil
 		},
 		Run: func(cmd *cobra.Command, args []string) {
@@ -52,6 +60,9 @@ func newRRFSearchCmd() *cobra.Command {
 				return
 			}
 			query := args[0]
+			cfg := config.Get()
+			limit = cli.ResolveInt(cmd, "limit", limit, cfg.Search.Limit)
+			k = cli.ResolveInt(cmd, "k", k, cfg.Hybrid.RRFK)
 
 			// Initialize logger
 			logger := logging.New(debug)
@@ -62,10 +73,11 @@ func newRRFSearchCmd() *cobra.Command {
 
 			logging.LogOriginalQuery(logger, execCtx, query)
 
-			hs, err := methods.NewHybridSearch("nomic-embed-text")
+			hs, err := methods.NewHybridSearch()
 			if err != nil {
 				log.Fatalf("❌ Failed to create hybrid search client: %v\n", err)
 			}
+			hs.Css.Aggregation.Method = cli.ResolveString(cmd, "aggregate", aggregate, hs.Css.Aggregation.Method)
 
 			// Pre-process query
 			if enhance != "" {
@@ -74,27 +86,48 @@ func newRRFSearchCmd() *cobra.Command {
 				if err != nil {
 					log.Fatalf("error: %v", err)
 				}
-				fmt.Printf("Enhanced query (%s): '%s' -> '%s'\n", enhance, query, enhancedQuery)
+				if enhance == llms.EnhanceHyDE {
+					// Only the semantic leg searches the passage
+					fmt.Printf("HyDE passage for '%s': '%s'\n", query, enhancedQuery)
+					logging.LogHyDEPassage(logger, execCtx, query, enhancedQuery)
+					hs.SemanticQuery = enhancedQuery
+				} else {
+					fmt.Printf("Enhanced query (%s): '%s' -> '%s'\n", enhance, query, enhancedQuery)
 
-				logging.LogEnhancedQuery(logger, execCtx, logging.EnhancedQueryLog{
-					EnhancementType: enhance,
-					OriginalQuery:   query,
-					EnhancedQuery:   enhancedQuery,
-				})
+					logging.LogEnhancedQuery(logger, execCtx, logging.EnhancedQueryLog{
+						EnhancementType: enhance,
+						OriginalQuery:   query,
+						EnhancedQuery:   enhancedQuery,
+					})
 
-				query = enhancedQuery
+					query = enhancedQuery
+				}
 			}
 
 			// Set search limit
 			searchLimit := limit
 			if rerankMethod != "" {
-				searchLimit = limit * 5
+				searchLimit = limit * cfg.Rerank.CandidateMultiplier
 			}
 
-			results, err := hs.RRFSearch(query, k, searchLimit)
+			// Diversify the candidates (before any re-ranking)
+			diversify = cli.ResolveFloat(cmd, "diversify", diversify, cfg.MMR.Lambda)
+			candidates := searchLimit
+			if diversify > 0 {
+				candidates = methods.DiversifyCandidates(searchLimit)
+			}
+
+			results, err := hs.RRFSearch(query, k, candidates)
 			if err != nil {
 				log.Fatalf("❌ Failed to perform rrf search: %v\n", err)
 			}
+			stats := embed.QueryCacheStats()
+			logging.LogQueryCache(logger, execCtx, stats.Hits, stats.Misses)
+			if diversify > 0 {
+				if results, err = hs.DiversifyRRF(results, diversify, searchLimit); err != nil {
+					log.Fatalf("❌ Failed to diversify results: %v\n", err)
+				}
+			}
 
 			// Log RRF candidates
 			if debug {
@@ -171,10 +204,12 @@ func newRRFSearchCmd() *cobra.Command {
 	}
 	cmd.Flags().IntVar(&limit, "limit", 5, "Limit the amount of results")
 	cmd.Flags().IntVar(&k, "k", 60, "Controls how much more weight we give to higher-ranked results vs lower-ranked ones.")
-	cmd.Flags().StringVar(&enhance, "enhance", "", "Query enhancement method. [choices: spell|rewrite|expand]")
+	cmd.Flags().StringVar(&enhance, "enhance", "", "Query enhancement method; hyde embeds a hypothetical description for the semantic leg only. [choices: spell|rewrite|expand|hyde]")
 	cmd.Flags().StringVar(&rerankMethod, "rerankMethod", "", "Re-ranking method. [choices: individual|batch|crossEncoder]")
 	cmd.Flags().BoolVar(&debug, "debug", false, "Enable debug logging")
 	cmd.Flags().BoolVar(&evaluate, "evaluate", false, "Add LLM evaluation to the results")
+	cmd.Flags().StringVar(&aggregate, "aggregate", methods.AggregateMax, "How chunk scores become a document score (default from aggregation.method). [choices: max|topMean|decaySum|softmax|maxMean]")
+	cmd.Flags().Float64Var(&diversify, "diversify", 0, "Re-rank with Maximal Marginal Relevance: lambda from 1 (relevance only) down to 0 (variety only) (default from mmr.lambda; 0 = off)")
 
 	return cmd
 }
@@ -183,10 +218,7 @@ func init() {
 	rrfSearchCmd := newRRFSearchCmd()
 	rrfSearchCmd.RegisterFlagCompletionFunc(
 		"enhance",
-		cobra.FixedCompletions(
-			[]string{"spell", "rewrite", "expand"},
-			cobra.ShellCompDirectiveNoFileComp,
-		),
+		cobra.FixedCompletions(llms.Enhancements, cobra.ShellCompDirectiveNoFileComp),
 	)
 	rrfSearchCmd.RegisterFlagCompletionFunc(
 		"rerankMethod",
@@ -228,10 +260,7 @@ func printRRFResults(
 		}
 		fmt.Printf("\tRRF Score: %.3f\n", result.RRFScore)
 		fmt.Printf("\tBM25 Rank: %d, Semantic Rank: %d\n", result.KeywordRank, result.SemanticRank)
-		desc := result.Description
-		if len(desc) > 100 {
-			desc = desc[:100]
-		}
+		desc := utils.Truncate(result.Description, 100)
 		fmt.Printf("\t%s...\n\n", desc)
 	}
 }
@@ -5,47 +5,147 @@ Copyright © 2025 NAME HERE <EMAIL ADDRESS>
 package hybrid
 
 import (
+	"context"
 	"fmt"
 	"log"
+	"strconv"
 
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/cli"
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/config"
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/embed"
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/llms"
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/logging"
 	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/methods"
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/utils"
+	"github.com/google/uuid"
 	"github.com/spf13/cobra"
 )
 
 func newWeightedSearchCmd() *cobra.Command {
 	var limit int
-	var alpha float64
+	var alpha string
+	var classifier string
+	var aggregate string
+	var diversify float64
+	var enhance string
+	var debug bool
 
 	cmd := &cobra.Command{
-		Use:   "weightedSearch <query> [--limit <int>] [--alpha <float>]",
+		Use:   "weightedSearch <query> [--limit <int>] [--alpha <float|auto>] [--aggregate <max|topMean|decaySum|softmax|maxMean>] [--enhance <spell|rewrite|expand|hyde>]",
 		Short: "Weighted search combining both keyword and semantic",
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if err := cli.ValidateFlagEnum(aggregate, "aggregate", methods.Aggregations...); err != nil {
+				return err
+			}
+			if alpha != methods.AlphaAuto {
+				if a, err := strconv.ParseFloat(alpha, 64); err != nil || a < 0 || a > 1 {
+					return fmt.Errorf("invalid --alpha: %q (must be a number between 0 and 1, or auto)", alpha)
+				}
+			}
+			if err := cli.ValidateFlagEnum(classifier, "classifier", methods.AlphaClassifiers...); err != nil {
+				return err
+			}
+			return cli.ValidateFlagEnum(enhance, "enhance", llms.Enhancements...)
+		},
 		Run: func(cmd *cobra.Command, args []string) {
 			if len(args) < 1 {
 				fmt.Println("❌ Please provide a query.")
 				return
 			}
 			query := args[0]
+			cfg := config.Get()
+			limit = cli.ResolveInt(cmd, "limit", limit, cfg.Search.Limit)
 
-			hs, err := methods.NewHybridSearch("nomic-embed-text")
+			hs, err := methods.NewHybridSearch()
 			if err != nil {
 				log.Fatalf("❌ Failed to create hybrid search client: %v\n", err)
 			}
+			hs.Css.Aggregation.Method = cli.ResolveString(cmd, "aggregate", aggregate, hs.Css.Aggregation.Method)
 
-			results, err := hs.WeightedSearch(query, alpha, limit)
+			logger := logging.New(debug)
+			execCtx := logging.ExecutionContext{
+				RunID:   uuid.New().String(),
+				QueryID: uuid.New().String(),
+			}
+			logging.LogOriginalQuery(logger, execCtx, query)
+
+			// --alpha wins over the config; auto classifies the query as typed
+			weight := cfg.Hybrid.Alpha
+			auto := cfg.Hybrid.AutoAlpha
+			if cmd.Flags().Changed("alpha") {
+				auto = alpha == methods.AlphaAuto
+				if !auto {
+					weight, _ = strconv.ParseFloat(alpha, 64)
+				}
+			}
+			if auto {
+				classifier = cli.ResolveString(cmd, "classifier", classifier, cfg.Hybrid.AlphaClassifier)
+				choice, err := hs.AutoAlpha(context.Background(), query, classifier)
+				if err != nil {
+					log.Fatalf("❌ Failed to classify query: %v\n", err)
+				}
+				fmt.Printf("Alpha for '%s': %s\n", query, choice)
+				logging.LogAlphaChoice(logger, execCtx, query, choice.Alpha, choice.Class, choice.Classifier, choice.Reason)
+				weight = choice.Alpha
+			}
+
+			if enhance != "" {
+				enhancedQuery, err := llms.PreProcessQuery(context.Background(), query, enhance)
+				if err != nil {
+					log.Fatalf("❌ Failed to enhance query: %v\n", err)
+				}
+				if enhance == llms.EnhanceHyDE {
+					// Only the semantic leg searches the passage
+					fmt.Printf("HyDE passage for '%s': '%s'\n", query, enhancedQuery)
+					logging.LogHyDEPassage(logger, execCtx, query, enhancedQuery)
+					hs.SemanticQuery = enhancedQuery
+				} else {
+					fmt.Printf("Enhanced query (%s): '%s' -> '%s'\n", enhance, query, enhancedQuery)
+					logging.LogEnhancedQuery(logger, execCtx, logging.EnhancedQueryLog{
+						EnhancementType: enhance,
+						OriginalQuery:   query,
+						EnhancedQuery:   enhancedQuery,
+					})
+					query = enhancedQuery
+				}
+			}
+
+			diversify = cli.ResolveFloat(cmd, "diversify", diversify, cfg.MMR.Lambda)
+			searchLimit := limit
+			if diversify > 0 {
+				searchLimit = methods.DiversifyCandidates(limit)
+			}
+
+			results, err := hs.WeightedSearch(query, weight, searchLimit)
 			if err != nil {
 				log.Fatalf("❌ Failed to perform weighted search: %v\n", err)
 			}
+			stats := embed.QueryCacheStats()
+			logging.LogQueryCache(logger, execCtx, stats.Hits, stats.Misses)
+			if diversify > 0 {
+				if results, err = hs.DiversifyWeighted(results, diversify, limit); err != nil {
+					log.Fatalf("❌ Failed to diversify results: %v\n", err)
+				}
+			}
 
 			for i, result := range results {
 				fmt.Printf("%d. %s\n", i+1, result.Title)
 				fmt.Printf("\tHybrid Score: %.3f\n", result.HybridScore)
 				fmt.Printf("\tBM25: %.3f, Semantic: %.3f\n", result.KeywordScore, result.SemanticScore)
-				fmt.Printf("\t%s...\n\n", result.Description[:100])
+				fmt.Printf("\t%s...\n\n", utils.Truncate(result.Description, 100))
 			}
 		},
 	}
 	cmd.Flags().IntVar(&limit, "limit", 5, "Limit the amount of results")
-	cmd.Flags().Float64Var(&alpha, "alpha", 0.5, "Dynamically control the weighting between the two scores")
+	cmd.Flags().StringVar(&alpha, "alpha", "0.5", "Weight of the keyword score, 0 to 1, or auto to pick it by query type (default from hybrid.alpha)")
+	cmd.Flags().StringVar(&classifier, "classifier", methods.ClassifierHeuristic, "How --alpha auto classifies the query (default from hybrid.alpha_classifier). [choices: heuristic|llm]")
+	cmd.Flags().StringVar(&aggregate, "aggregate", methods.AggregateMax, "How chunk scores become a document score (default from aggregation.method). [choices: max|topMean|decaySum|softmax|maxMean]")
+	cmd.Flags().StringVar(&enhance, "enhance", "", "Query enhancement method; hyde embeds a hypothetical description for the semantic leg only. [choices: spell|rewrite|expand|hyde]")
+	cmd.Flags().BoolVar(&debug, "debug", false, "Enable debug logging")
+	cmd.Flags().Float64Var(&diversify, "diversify", 0, "Re-rank with Maximal Marginal Relevance: lambda from 1 (relevance only) down to 0 (variety only) (default from mmr.lambda; 0 = off)")
+
+	cmd.RegisterFlagCompletionFunc("classifier", cobra.FixedCompletions(methods.AlphaClassifiers, cobra.ShellCompDirectiveNoFileComp))
+	cmd.RegisterFlagCompletionFunc("enhance", cobra.FixedCompletions(llms.Enhancements, cobra.ShellCompDirectiveNoFileComp))
 
 	return cmd
 
@@ -0,0 +1,137 @@
+package ingest
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/chunk"
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/cli"
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/collection"
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/fs"
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/index"
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/ingest"
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/methods"
+	"github.com/spf13/cobra"
+)
+
+var (
+	format           string
+	arrayKey         string
+	idField          string
+	titleField       string
+	descriptionField string
+	skipEmbeddings   bool
+	resume           bool
+	strategy         string
+)
+
+var IngestCmd = &cobra.Command{
+	Use:   "ingest <source> --collection <name> [--format <auto|json|jsonl|csv|dir>]",
+	Short: "Ingest documents from JSON, JSONL, CSV or a text directory and build every index for them",
+	Example: `ingest data/movies.json --collection movies
+ingest books.csv --collection books --titleField name --descriptionField summary
+ingest ./notes --collection notes --strategy recursive
+ingest big.jsonl --collection big --resume`,
+	Annotations: map[string]string{collection.AnnotationCreates: "true"},
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if fs.Collection == "" {
+			return fmt.Errorf("--collection is required")
+		}
+		if err := cli.ValidateFlagEnum(strategy, "strategy", chunk.Strategies...); err != nil {
+			return err
+		}
+		return cli.ValidateFlagEnum(format, "format", ingest.Formats...)
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) < 1 {
+			fmt.Println("❌ Please provide a source file or directory.")
+			return
+		}
+		source := args[0]
+
+		docs, err := ingest.Load(source, ingest.Options{
+			Format:   format,
+			ArrayKey: arrayKey,
+			Fields: ingest.FieldMap{
+				ID:          idField,
+				Title:       titleField,
+				Description: descriptionField,
+			},
+		})
+		if err != nil {
+			log.Fatalf("❌ Failed to read documents: %v\n", err)
+		}
+		if len(docs) == 0 {
+			log.Fatalf("❌ No documents found in %s\n", source)
+		}
+		fmt.Printf("📥 Read %d documents from %s\n", len(docs), source)
+
+		// Record the detected format, not "auto"
+		detected, err := ingest.DetectFormat(source, format)
+		if err != nil {
+			log.Fatalf("❌ Failed to read documents: %v\n", err)
+		}
+		if err := collection.Touch(fs.Collection, source, detected); err != nil {
+			log.Fatalf("❌ Failed to register collection: %v\n", err)
+		}
+		if err := fs.SaveMovies(docs); err != nil {
+			log.Fatalf("❌ Failed to save documents: %v\n", err)
+		}
+
+		// Inverted index
+		idx := index.NewInvertedIndex()
+		if err := idx.Build(); err != nil {
+			log.Fatalf("❌ Failed to build index: %v\n", err)
+		}
+		if err := idx.Save(); err != nil {
+			log.Fatalf("❌ Failed to save index: %v\n", err)
+		}
+		fmt.Println("✅ Inverted index built.")
+
+		if skipEmbeddings {
+			fmt.Printf("✅ Collection '%s' ingested (embeddings skipped).\n", fs.Collection)
+			return
+		}
+
+		// Full document embeddings
+		ss, err := methods.NewSemanticSearch()
+		if err != nil {
+			log.Fatalf("❌ Failed to create semantic search client: %v\n", err)
+		}
+		ss.Documents = docs
+		ss.Resume = resume
+		if _, err := ss.BuildEmbeddings(); err != nil {
+			log.Fatalf("❌ Failed to generate embeddings: %v\n", err)
+		}
+
+		// Chunk embeddings
+		css, err := methods.NewChunkedSemanticSearch()
+		if err != nil {
+			log.Fatalf("❌ Failed to create chunked semantic search client: %v\n", err)
+		}
+		css.Documents = docs
+		css.Resume = resume
+		css.Strategy = cli.ResolveString(cmd, "strategy", strategy, css.Strategy)
+		if _, err := css.BuildChunksEmbeddings(); err != nil {
+			log.Fatalf("❌ Failed to generate chunk embeddings: %v\n", err)
+		}
+
+		fmt.Printf("✅ Collection '%s' ingested into %s\n", fs.Collection, fs.CacheDir)
+	},
+}
+
+func init() {
+	IngestCmd.Flags().StringVar(&format, "format", ingest.FormatAuto, "Source format. [choices: auto|json|jsonl|csv|dir]")
+	IngestCmd.Flags().StringVar(&arrayKey, "arrayKey", "", "Key holding the documents array when a JSON source is an object")
+	IngestCmd.Flags().StringVar(&idField, "idField", "id", "Source field/column used as document ID")
+	IngestCmd.Flags().StringVar(&titleField, "titleField", "title", "Source field/column used as document title")
+	IngestCmd.Flags().StringVar(&descriptionField, "descriptionField", "description", "Source field/column used as document description")
+	IngestCmd.Flags().BoolVar(&skipEmbeddings, "skipEmbeddings", false, "Only build the inverted index")
+	IngestCmd.Flags().StringVar(&strategy, "strategy", chunk.StrategySentences, "Chunking strategy for the chunk embeddings (default from chunking.strategy). [choices: sentences|words|tokens|recursive|semantic]")
+	IngestCmd.Flags().BoolVar(&resume, "resume", false, "Continue an interrupted embedding build from its checkpoint instead of starting over")
+
+	IngestCmd.RegisterFlagCompletionFunc(
+		"format",
+		cobra.FixedCompletions(ingest.Formats, cobra.ShellCompDirectiveNoFileComp),
+	)
+}
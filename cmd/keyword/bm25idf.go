@@ -24,7 +24,7 @@ var bm25idfCmd = &cobra.Command{
 
 		// load index
 		idx := index.NewInvertedIndex()
-		if err := idx.Load(); err != nil {
+		if err := idx.LoadOrBuild(); err != nil {
 			log.Fatalf("❌ Failed to load index: %v\n", err)
 		}
 
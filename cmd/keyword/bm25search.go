@@ -8,6 +8,8 @@ import (
 	"log"
 	"time"
 
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/cli"
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/config"
 	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/index"
 	"github.com/spf13/cobra"
 )
@@ -25,10 +27,11 @@ func newBm25SearchCmd() *cobra.Command {
 				return
 			}
 			query := args[0]
+			limit = cli.ResolveInt(cmd, "limit", limit, config.Get().Search.Limit)
 
 			// load index
 			idx := index.NewInvertedIndex()
-			if err := idx.Load(); err != nil {
+			if err := idx.LoadOrBuild(); err != nil {
 				log.Fatalf("❌ Failed to load index: %v\n", err)
 			}
 
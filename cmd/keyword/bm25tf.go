@@ -8,6 +8,8 @@ import (
 	"log"
 	"strconv"
 
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/cli"
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/config"
 	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/index"
 	"github.com/spf13/cobra"
 )
@@ -32,10 +34,13 @@ func NewBm25TFCmd() *cobra.Command {
 			}
 
 			term := args[1]
+			cfg := config.Get()
+			k1 = cli.ResolveFloat(cmd, "k1", k1, cfg.Keyword.K1)
+			b = cli.ResolveFloat(cmd, "b", b, cfg.Keyword.B)
 
 			// load index
 			idx := index.NewInvertedIndex()
-			if err := idx.Load(); err != nil {
+			if err := idx.LoadOrBuild(); err != nil {
 				log.Fatalf("❌ Failed to load index: %v\n", err)
 			}
 
@@ -23,7 +23,7 @@ var idfCmd = &cobra.Command{
 
 		// load index
 		idx := index.NewInvertedIndex()
-		if err := idx.Load(); err != nil {
+		if err := idx.LoadOrBuild(); err != nil {
 			log.Fatalf("❌ Failed to load index: %v\n", err)
 		}
 
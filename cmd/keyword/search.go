@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"log"
 
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/config"
 	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/fs"
 	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/index"
 	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/model"
@@ -28,7 +29,7 @@ var searchCmd = &cobra.Command{
 
 		idx := index.NewInvertedIndex()
 
-		if err := idx.Load(); err != nil {
+		if err := idx.LoadOrBuild(); err != nil {
 			log.Fatalf("❌ Failed to load index: %v\n", err)
 		}
 
@@ -38,12 +39,13 @@ var searchCmd = &cobra.Command{
 		}
 
 		queryTokens := tokenizer.Tokenize(query, stopWords)
+		limit := config.Get().Search.Limit
 
 		results := []model.Movie{}
 		for _, t := range queryTokens {
 			docs := idx.GetDocuments(t)
 			results = append(results, docs...)
-			if len(results) >= 5 {
+			if len(results) >= limit {
 				break
 			}
 		}
@@ -53,8 +55,8 @@ var searchCmd = &cobra.Command{
 			return
 		}
 
-		if len(results) > 5 {
-			results = results[:5]
+		if len(results) > limit {
+			results = results[:limit]
 		}
 
 		for i, movie := range results {
@@ -6,14 +6,20 @@ import (
 	"log"
 	"os"
 
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/cli"
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/config"
 	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/methods"
 	"github.com/spf13/cobra"
 )
 
 func newImageSearchCmd() *cobra.Command {
+	var (
+		useANN   bool
+		efSearch int
+	)
 
 	cmd := &cobra.Command{
-		Use:   "imageSearch <imagePath>",
+		Use:   "imageSearch <imagePath> [--ann [--efSearch <int>]]",
 		Short: "Use RAG to summarize the results with an LLM.",
 		Run: func(cmd *cobra.Command, args []string) {
 
@@ -39,13 +45,21 @@ func newImageSearchCmd() *cobra.Command {
 				log.Fatalf("❌ Failed to load or generate embeddings: %v\n", err)
 			}
 
+			if useANN {
+				params := methods.ANNParams()
+				params.EfSearch = cli.ResolveInt(cmd, "efSearch", efSearch, params.EfSearch)
+				if err := mms.LoadOrBuildANN(params); err != nil {
+					log.Fatalf("❌ Failed to load or build HNSW index: %v\n", err)
+				}
+			}
+
 			// fmt.Println("embeddings len:", len(embeddings))
 			// if len(embeddings) > 0 {
 			// 	fmt.Println("dimensions:", len(embeddings[0]))
 			// }
 
 			ctx := context.Background()
-			limit := 5
+			limit := config.Get().Multimodal.Limit
 			results, err := mms.ImageSearch(ctx, imagePath, limit)
 			if err != nil {
 				log.Fatalf("❌ Failed to perfom image search: %v\n", err)
@@ -57,6 +71,8 @@ func newImageSearchCmd() *cobra.Command {
 				// fmt.Printf("\t%s\n\n", res.Description)
 			}
 		}}
+	cmd.Flags().BoolVar(&useANN, "ann", false, "Search the HNSW index instead of scanning every vector (see `semantic buildANN --target multimodal`)")
+	cmd.Flags().IntVar(&efSearch, "efSearch", 64, "HNSW candidate list size with --ann (higher = better recall, slower)")
 
 	return cmd
 }
@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"log"
 
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/cli"
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/config"
 	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/llms"
 	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/methods"
 	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/utils"
@@ -14,6 +16,7 @@ import (
 func newCitationsCmd() *cobra.Command {
 	var limit int
 	var k int
+	var ctxFlags contextFlags
 
 	cmd := &cobra.Command{
 		Use:   "citations <query>",
@@ -25,8 +28,11 @@ func newCitationsCmd() *cobra.Command {
 				return
 			}
 			query := args[0]
+			cfg := config.Get()
+			limit = cli.ResolveInt(cmd, "limit", limit, cfg.Search.Limit)
+			k = cli.ResolveInt(cmd, "k", k, cfg.RAG.RRFK)
 
-			hs, err := methods.NewHybridSearch("nomic-embed-text")
+			hs, err := methods.NewHybridSearch()
 			if err != nil {
 				log.Fatalf("❌ Failed to create hybrid search client: %v\n", err)
 			}
@@ -35,6 +41,7 @@ func newCitationsCmd() *cobra.Command {
 			if err != nil {
 				log.Fatalf("❌ Failed to perform rrf search: %v\n", err)
 			}
+			ctxFlags.apply(cmd, hs, results)
 
 			ctx := context.Background()
 			resultsStr := utils.ResultsListToStr(results)
@@ -54,6 +61,8 @@ func newCitationsCmd() *cobra.Command {
 	cmd.Flags().IntVar(&limit, "limit", 5, "Limit the amount of results")
 	cmd.Flags().IntVar(&k, "k", 60, "Controls how much more weight we give to higher-ranked results vs lower-ranked ones.")
 
+	ctxFlags.register(cmd)
+
 	return cmd
 }
 
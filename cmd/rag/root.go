@@ -1,9 +1,41 @@
 package rag
 
-import "github.com/spf13/cobra"
+import (
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/cli"
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/config"
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/methods"
+	"github.com/spf13/cobra"
+)
 
 var RAGCmd = &cobra.Command{
 	Use:     "rag",
 	Aliases: []string{"rag"},
 	Short:   "RAG commands",
 }
+
+// contextFlags are the --context/--window retrieval options shared by the
+// rag commands.
+type contextFlags struct {
+	mode   string
+	window int
+}
+
+func (f *contextFlags) register(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&f.mode, "context", methods.ContextChunk, "What the LLM sees of each result (default from rag.context). [choices: chunk|window|parent]")
+	cmd.Flags().IntVar(&f.window, "window", 1, "window context: neighbouring chunks on each side of a match (default from rag.context_window)")
+	cmd.RegisterFlagCompletionFunc(
+		"context",
+		cobra.FixedCompletions(methods.ContextModes, cobra.ShellCompDirectiveNoFileComp),
+	)
+	cmd.PreRunE = func(cmd *cobra.Command, args []string) error {
+		return cli.ValidateFlagEnum(f.mode, "context", methods.ContextModes...)
+	}
+}
+
+// apply expands the passages of results as configured.
+func (f *contextFlags) apply(cmd *cobra.Command, hs *methods.HybridSearch, results []methods.RRFSearchResult) {
+	cfg := config.Get()
+	mode := cli.ResolveString(cmd, "context", f.mode, cfg.RAG.Context)
+	window := cli.ResolveInt(cmd, "window", f.window, cfg.RAG.ContextWindow)
+	hs.ExpandContext(results, mode, window)
+}
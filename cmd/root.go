@@ -22,21 +22,179 @@ THE SOFTWARE.
 package cmd
 
 import (
+	"fmt"
 	"os"
+	"slices"
+	"strings"
 
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/cmd/collections"
+	configcmd "github.com/agustin-carnevale/advanced-search-hoopla-go/cmd/config"
 	"github.com/agustin-carnevale/advanced-search-hoopla-go/cmd/evaluation"
 	"github.com/agustin-carnevale/advanced-search-hoopla-go/cmd/hybrid"
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/cmd/ingest"
 	"github.com/agustin-carnevale/advanced-search-hoopla-go/cmd/keyword"
 	"github.com/agustin-carnevale/advanced-search-hoopla-go/cmd/multimodal"
 	"github.com/agustin-carnevale/advanced-search-hoopla-go/cmd/rag"
 	"github.com/agustin-carnevale/advanced-search-hoopla-go/cmd/semantic"
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/chunk"
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/collection"
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/config"
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/embed"
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/fs"
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/llms"
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/manifest"
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/methods"
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/quant"
 	"github.com/spf13/cobra"
 )
 
+var (
+	configPath     string
+	collectionName string
+	strict         bool
+	offline        bool
+)
+
 // rootCmd represents the base command when called without any subcommands
 var RootCmd = &cobra.Command{
 	Use:   "hoopla",
 	Short: "Advanced search and RAG using keyword, semantic and hybrid techniques",
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		if err := config.Load(configPath, fs.ProjectRoot); err != nil {
+			return err
+		}
+		cfg := config.Get()
+
+		if cmd.Flags().Changed("collection") {
+			cfg.Collection = collectionName
+			config.SetOrigin("collection", "flag --collection")
+		}
+		if offline {
+			cfg.Offline = true
+			config.SetOrigin("offline", "flag --offline")
+		}
+		if cfg.Offline {
+			// Every model-backed step runs locally
+			cfg.Embedding.Provider = embed.ProviderHashing
+			cfg.Multimodal.Provider = embed.ProviderHashing
+			cfg.LLM.Provider = llms.ProviderMock
+			for _, key := range []string{"embedding.provider", "multimodal.provider", "llm.provider"} {
+				config.SetOrigin(key, "offline")
+			}
+		}
+		if strict {
+			cfg.Cache.OnStale = manifest.PolicyFail
+			config.SetOrigin("cache.on_stale", "flag --strict")
+		}
+		if !slices.Contains(manifest.Policies, cfg.Cache.OnStale) {
+			return fmt.Errorf(
+				"invalid cache.on_stale: %q (allowed: %s)",
+				cfg.Cache.OnStale,
+				strings.Join(manifest.Policies, ", "),
+			)
+		}
+		if !slices.Contains(quant.Kinds, cfg.Quantize.Storage) {
+			return fmt.Errorf(
+				"invalid quantization.storage: %q (allowed: %s)",
+				cfg.Quantize.Storage,
+				strings.Join(quant.Kinds, ", "),
+			)
+		}
+		if !slices.Contains(chunk.Strategies, cfg.Chunking.Strategy) {
+			return fmt.Errorf(
+				"invalid chunking.strategy: %q (allowed: %s)",
+				cfg.Chunking.Strategy,
+				strings.Join(chunk.Strategies, ", "),
+			)
+		}
+		if !slices.Contains(methods.Aggregations, cfg.Aggregation.Method) {
+			return fmt.Errorf(
+				"invalid aggregation.method: %q (allowed: %s)",
+				cfg.Aggregation.Method,
+				strings.Join(methods.Aggregations, ", "),
+			)
+		}
+		if cfg.MMR.Lambda < 0 || cfg.MMR.Lambda > 1 {
+			return fmt.Errorf("invalid mmr.lambda: %g (must be between 0 and 1)", cfg.MMR.Lambda)
+		}
+		if _, err := methods.ParseLegSpecs(cfg.Fusion.Retrievers); err != nil {
+			return fmt.Errorf("invalid fusion.retrievers: %w", err)
+		}
+		if !slices.Contains(methods.FusionMethods, cfg.Fusion.Method) {
+			return fmt.Errorf(
+				"invalid fusion.method: %q (allowed: %s)",
+				cfg.Fusion.Method,
+				strings.Join(methods.FusionMethods, ", "),
+			)
+		}
+		if !slices.Contains(methods.Normalizations, cfg.Fusion.Normalization) {
+			return fmt.Errorf(
+				"invalid fusion.normalization: %q (allowed: %s)",
+				cfg.Fusion.Normalization,
+				strings.Join(methods.Normalizations, ", "),
+			)
+		}
+		if err := methods.FuseOptionsFromConfig().Validate(); err != nil {
+			return fmt.Errorf("invalid fusion section: %w", err)
+		}
+		if cfg.Fusion.RBFPersistence <= 0 || cfg.Fusion.RBFPersistence >= 1 {
+			return fmt.Errorf("invalid fusion.rbf_persistence: %g (must be between 0 and 1, exclusive)", cfg.Fusion.RBFPersistence)
+		}
+		if !slices.Contains(methods.AlphaClassifiers, cfg.Hybrid.AlphaClassifier) {
+			return fmt.Errorf(
+				"invalid hybrid.alpha_classifier: %q (allowed: %s)",
+				cfg.Hybrid.AlphaClassifier,
+				strings.Join(methods.AlphaClassifiers, ", "),
+			)
+		}
+		for name, alpha := range map[string]float64{
+			"alpha_exact":      cfg.Hybrid.AlphaExact,
+			"alpha_conceptual": cfg.Hybrid.AlphaConceptual,
+			"alpha_mixed":      cfg.Hybrid.AlphaMixed,
+		} {
+			if alpha < 0 || alpha > 1 {
+				return fmt.Errorf("invalid hybrid.%s: %g (must be between 0 and 1)", name, alpha)
+			}
+		}
+		if cfg.Keyword.RM3OriginalWeight < 0 || cfg.Keyword.RM3OriginalWeight > 1 {
+			return fmt.Errorf("invalid keyword.rm3_original_weight: %g (must be between 0 and 1)", cfg.Keyword.RM3OriginalWeight)
+		}
+		if _, err := methods.ParseFieldWeights(cfg.Search.FieldWeights); err != nil {
+			return fmt.Errorf("invalid search.field_weights: %w", err)
+		}
+		if !slices.Contains(methods.ContextModes, cfg.RAG.Context) {
+			return fmt.Errorf(
+				"invalid rag.context: %q (allowed: %s)",
+				cfg.RAG.Context,
+				strings.Join(methods.ContextModes, ", "),
+			)
+		}
+
+		fs.SetDirs(cfg.Paths.DataDir, cfg.Paths.CacheDir)
+		if cfg.Collection == "" {
+			return nil
+		}
+		_, creates := cmd.Annotations[collection.AnnotationCreates]
+		return collection.Use(cfg.Collection, !creates && !managesCollections(cmd))
+	},
+	PersistentPostRunE: func(cmd *cobra.Command, args []string) error {
+		// Query cache hits only update recency in memory
+		if err := embed.SaveQueryCaches(); err != nil {
+			return fmt.Errorf("failed to save query embedding cache: %w", err)
+		}
+		return nil
+	},
+}
+
+// managesCollections reports whether cmd is in a command tree annotated
+// with collection.AnnotationManages.
+func managesCollections(cmd *cobra.Command) bool {
+	for c := cmd; c != nil; c = c.Parent() {
+		if _, ok := c.Annotations[collection.AnnotationManages]; ok {
+			return true
+		}
+	}
+	return false
 }
 
 func Execute() {
@@ -47,10 +205,18 @@ func Execute() {
 }
 
 func init() {
+	RootCmd.PersistentFlags().StringVar(&configPath, "config", "", "Config file (default: $HOOPLA_CONFIG or ./hoopla.yaml)")
+	RootCmd.PersistentFlags().StringVar(&collectionName, "collection", "", "Named collection to use (default: data/movies.json)")
+	RootCmd.PersistentFlags().BoolVar(&offline, "offline", false, "Use the local hashing embedder and mock LLM (no Ollama/Gemini/Cohere needed)")
+	RootCmd.PersistentFlags().BoolVar(&strict, "strict", false, "Fail instead of warning/rebuilding when a cached artifact is stale")
+
 	RootCmd.AddCommand(keyword.KeywordCmd)
 	RootCmd.AddCommand(semantic.SemanticCmd)
 	RootCmd.AddCommand(hybrid.HybridCmd)
 	RootCmd.AddCommand(evaluation.EvaluationCmd)
 	RootCmd.AddCommand(rag.RAGCmd)
 	RootCmd.AddCommand(multimodal.MultimodalCmd)
+	RootCmd.AddCommand(ingest.IngestCmd)
+	RootCmd.AddCommand(collections.CollectionsCmd)
+	RootCmd.AddCommand(configcmd.ConfigCmd)
 }
@@ -0,0 +1,184 @@
+package semantic
+
+import (
+	"fmt"
+	"log"
+	"math/rand"
+	"time"
+
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/ann"
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/cli"
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/config"
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/fs"
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/methods"
+	"github.com/spf13/cobra"
+)
+
+func newBuildANNCmd() *cobra.Command {
+	var (
+		target         string
+		m              int
+		efConstruction int
+		efSearch       int
+		k              int
+		evalQueries    int
+	)
+
+	cmd := &cobra.Command{
+		Use:   "buildANN [--target <docs|chunks|multimodal>] [--m <int>] [--efConstruction <int>] [--efSearch <int>]",
+		Short: "Build an HNSW index over the cached embeddings and report recall@k against exact search",
+		Example: `semantic buildANN
+semantic buildANN --target chunks --m 32 --efConstruction 400
+semantic buildANN --target chunks --efSearch 128 --k 10`,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			return cli.ValidateFlagEnum(target, "target", methods.ANNTargets...)
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			cfg := config.Get().ANN
+			params := ann.Params{
+				M:              cli.ResolveInt(cmd, "m", m, cfg.M),
+				EfConstruction: cli.ResolveInt(cmd, "efConstruction", efConstruction, cfg.EfConstruction),
+				EfSearch:       cli.ResolveInt(cmd, "efSearch", efSearch, cfg.EfSearch),
+			}
+
+			vectors, err := loadANNVectors(target)
+			if err != nil {
+				log.Fatalf("❌ Failed to load embeddings: %v\n", err)
+			}
+			if len(vectors) == 0 {
+				log.Fatalf("❌ No %s embeddings to index\n", target)
+			}
+
+			start := time.Now()
+			index, err := methods.BuildANN(target, vectors, params)
+			if err != nil {
+				log.Fatalf("❌ Failed to build HNSW index: %v\n", err)
+			}
+			indexPath, _ := methods.ANNPaths(target)
+			fmt.Printf("✅ HNSW index built in %s → %s\n", time.Since(start).Round(time.Millisecond), indexPath)
+
+			report := evaluateANN(index, vectors, k, evalQueries)
+			fmt.Printf("\nRecall@%d over %d sampled queries (efSearch=%d): %.4f\n", k, report.queries, index.EfSearch, report.recall)
+			fmt.Printf("Avg latency: exact %s | ANN %s (speedup %.1fx)\n",
+				report.exact, report.approx, float64(report.exact)/float64(max(report.approx, 1)))
+		},
+	}
+
+	cmd.Flags().StringVar(&target, "target", methods.ANNTargetDocs, "Embeddings to index. [choices: docs|chunks|multimodal]")
+	cmd.Flags().IntVar(&m, "m", 16, "HNSW links per node (higher = better recall, more memory)")
+	cmd.Flags().IntVar(&efConstruction, "efConstruction", 200, "HNSW candidate list size while building")
+	cmd.Flags().IntVar(&efSearch, "efSearch", 64, "HNSW candidate list size while querying")
+	cmd.Flags().IntVar(&k, "k", 10, "k used for the recall@k report")
+	cmd.Flags().IntVar(&evalQueries, "evalQueries", 200, "Number of stored vectors sampled as queries for the recall report (each query's own vector is left out)")
+
+	cmd.RegisterFlagCompletionFunc(
+		"target",
+		cobra.FixedCompletions(methods.ANNTargets, cobra.ShellCompDirectiveNoFileComp),
+	)
+
+	return cmd
+}
+
+// loadANNVectors loads (or builds) the embeddings an index targets.
+func loadANNVectors(target string) ([][]float32, error) {
+	if target == methods.ANNTargetMultimodal {
+		mms, err := methods.NewMultimodalSearch()
+		if err != nil {
+			return nil, err
+		}
+		return mms.LoadOrCreateEmbeddings()
+	}
+
+	moviesDocs, err := fs.LoadMovies()
+	if err != nil {
+		return nil, err
+	}
+
+	if target == methods.ANNTargetChunks {
+		css, err := methods.NewChunkedSemanticSearch()
+		if err != nil {
+			return nil, err
+		}
+		embeddings, err := css.LoadOrCreateChunksEmbeddings(moviesDocs)
+		if err != nil {
+			return nil, err
+		}
+		return embeddings, nil
+	}
+
+	ss, err := methods.NewSemanticSearch()
+	if err != nil {
+		return nil, err
+	}
+	embeddings, err := ss.LoadOrCreateEmbeddings(moviesDocs)
+	if err != nil {
+		return nil, err
+	}
+	return embeddings, nil
+}
+
+type annReport struct {
+	queries int
+	recall  float64
+	exact   time.Duration
+	approx  time.Duration
+}
+
+// evaluateANN uses a fixed sample of the indexed vectors as queries and
+// compares the ANN top-k with brute force. Each query is in the index, so
+// both searches fetch k+1 and drop the query itself: finding it says
+// nothing about recall.
+func evaluateANN(index *ann.HNSW, vectors [][]float32, k int, queries int) annReport {
+	queries = min(queries, len(vectors))
+	rng := rand.New(rand.NewSource(42))
+	sample := rng.Perm(len(vectors))[:queries]
+
+	var report annReport
+	report.queries = queries
+
+	var recallSum float64
+	for _, i := range sample {
+		start := time.Now()
+		exact := withoutSelf(index.ExactSearch(vectors[i], k+1), i, k)
+		report.exact += time.Since(start)
+
+		start = time.Now()
+		approx := withoutSelf(index.Search(vectors[i], k+1), i, k)
+		report.approx += time.Since(start)
+
+		recallSum += ann.Recall(exact, approx)
+	}
+
+	if queries > 0 {
+		report.recall = recallSum / float64(queries)
+		report.exact /= time.Duration(queries)
+		report.approx /= time.Duration(queries)
+	}
+	return report
+}
+
+// withoutSelf drops the query's own vector from results and keeps k.
+func withoutSelf(results []ann.Result, self int, k int) []ann.Result {
+	kept := make([]ann.Result, 0, len(results))
+	for _, r := range results {
+		if r.ID != self {
+			kept = append(kept, r)
+		}
+	}
+	if k < len(kept) {
+		kept = kept[:k]
+	}
+	return kept
+}
+
+// annParams returns the configured HNSW parameters, with --efSearch
+// overriding the query-time candidate list size.
+func annParams(cmd *cobra.Command, efSearch int) ann.Params {
+	params := methods.ANNParams()
+	params.EfSearch = cli.ResolveInt(cmd, "efSearch", efSearch, params.EfSearch)
+	return params
+}
+
+func init() {
+	SemanticCmd.AddCommand(newBuildANNCmd())
+}
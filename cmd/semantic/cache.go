@@ -0,0 +1,110 @@
+package semantic
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/embed"
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/fs"
+	"github.com/spf13/cobra"
+)
+
+var cacheCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "Inspect or prune the embedding caches shared by every collection (documents keyed by model + text hash, queries by model + normalized query)",
+}
+
+func newCacheStatsCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "stats",
+		Short: "Show entries, dimension and size of the document and query embedding caches per model",
+		Run: func(cmd *cobra.Command, args []string) {
+			infos, err := embed.ListCaches()
+			if err != nil {
+				log.Fatalf("❌ Failed to read embedding cache: %v\n", err)
+			}
+			queryInfos, err := embed.ListQueryCaches()
+			if err != nil {
+				log.Fatalf("❌ Failed to read query embedding cache: %v\n", err)
+			}
+			if len(infos) == 0 && len(queryInfos) == 0 {
+				fmt.Printf("Embedding cache is empty (%s)\n", fs.EmbeddingCacheDir)
+				return
+			}
+
+			total := printCacheInfos(infos)
+			if len(queryInfos) > 0 {
+				fmt.Printf("\nQueries:\n")
+				total += printCacheInfos(queryInfos)
+			}
+			fmt.Printf("\nTotal: %d models, %s\n", len(infos), humanBytes(total))
+		},
+	}
+}
+
+// printCacheInfos prints one block per model cache and returns their size.
+func printCacheInfos(infos []embed.CacheInfo) int {
+	total := 0
+	for _, info := range infos {
+		fmt.Printf("%s\n", info.Model)
+		fmt.Printf("   entries: %d | dims: %d | size: %s\n", info.Entries, info.Dimension, humanBytes(int(info.Bytes)))
+		if info.Entries > 0 {
+			fmt.Printf("   last used: %s … %s\n", info.Oldest.Format(time.DateTime), info.Newest.Format(time.DateTime))
+		}
+		fmt.Printf("   %s\n", info.Path)
+		total += int(info.Bytes)
+	}
+	return total
+}
+
+func newCachePruneCmd() *cobra.Command {
+	var (
+		olderThan   time.Duration
+		otherModels bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "prune [--olderThan <duration>] [--otherModels]",
+		Short: "Remove embedding cache entries that haven't been used for a while",
+		Example: `semantic cache prune --olderThan 168h
+semantic cache prune --olderThan 0 --otherModels`,
+		Run: func(cmd *cobra.Command, args []string) {
+			var keep []string
+			if otherModels {
+				keep = currentModels()
+				if len(keep) == 0 {
+					log.Fatalf("❌ Could not resolve the configured embedding models, refusing to drop every cache\n")
+				}
+			}
+
+			removed, err := embed.PruneCaches(olderThan, keep)
+			if err != nil {
+				log.Fatalf("❌ Failed to prune embedding cache: %v\n", err)
+			}
+			fmt.Printf("✅ Removed %d cached embeddings\n", removed)
+		},
+	}
+	cmd.Flags().DurationVar(&olderThan, "olderThan", 30*24*time.Hour, "Drop entries not used for this long (0 = keep all)")
+	cmd.Flags().BoolVar(&otherModels, "otherModels", false, "Also drop the caches of models other than the configured embedding/multimodal ones")
+
+	return cmd
+}
+
+// currentModels names the configured embedders as they appear in the cache.
+func currentModels() []string {
+	var models []string
+	if e, err := embed.FromConfig(); err == nil {
+		models = append(models, e.Info().Name())
+	}
+	if e, err := embed.MultimodalFromConfig(); err == nil {
+		models = append(models, e.Info().Name())
+	}
+	return models
+}
+
+func init() {
+	cacheCmd.AddCommand(newCacheStatsCmd())
+	cacheCmd.AddCommand(newCachePruneCmd())
+	SemanticCmd.AddCommand(cacheCmd)
+}
@@ -4,54 +4,77 @@ Copyright © 2025 NAME HERE <EMAIL ADDRESS>
 package semantic
 
 import (
+	"context"
 	"fmt"
-	"strings"
+	"log"
 
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/chunk"
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/cli"
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/embed"
 	"github.com/spf13/cobra"
 )
 
 func newChunkCmd() *cobra.Command {
-	var chunkSize int
-	var overlap int
+	var (
+		strategy   string
+		chunkSize  int
+		overlap    int
+		percentile float64
+	)
 
 	cmd := &cobra.Command{
-		Use:   "chunk <text> [--chunkSize <int>] [--overlap <int>]",
+		Use:   "chunk <text> [--strategy <words|sentences|tokens|recursive|semantic>] [--chunkSize <int>] [--overlap <int>]",
 		Short: "Split long text into smaller pieces for embedding",
+		Example: `semantic chunk "$(cat notes.md)" --strategy recursive --chunkSize 500 --overlap 50
+semantic chunk "$(cat notes.md)" --strategy semantic --percentile 80`,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			return cli.ValidateFlagEnum(strategy, "strategy", chunk.Strategies...)
+		},
 		Run: func(cmd *cobra.Command, args []string) {
 			if len(args) < 1 {
 				fmt.Println("❌ You need to provide a text to chunk")
 				return
 			}
 			text := args[0]
-			fmt.Printf("Chunking %d characters\n", len(text))
 
-			words := strings.Fields(text)
-			if len(words) == 0 {
-				return
+			opts := chunk.ConfiguredOptions(strategy)
+			opts.Size = cli.ResolveInt(cmd, "chunkSize", chunkSize, opts.Size)
+			opts.Overlap = cli.ResolveInt(cmd, "overlap", overlap, opts.Overlap)
+			opts.Percentile = cli.ResolveFloat(cmd, "percentile", percentile, opts.Percentile)
+			if strategy == chunk.StrategySemantic {
+				embedder, err := embed.FromConfig()
+				if err != nil {
+					log.Fatalf("❌ Failed to create embedder: %v\n", err)
+				}
+				opts.Embedder = embedder
 			}
 
-			var chunks []string
-			start := 0
-			end := min(chunkSize, len(words))
-			c := strings.Join(words[start:end], " ")
-			chunks = append(chunks, c)
-
-			start = min(chunkSize-overlap, len(words))
-			for start+overlap < len(words) {
-				end = min(start+chunkSize, len(words))
-				c = strings.Join(words[start:end], " ")
-				chunks = append(chunks, c)
-				start = end - overlap
+			chunker, err := chunk.New(strategy, opts)
+			if err != nil {
+				log.Fatalf("❌ Invalid chunking options: %v\n", err)
 			}
+			fmt.Printf("Chunking %d characters with %s\n", len(text), chunker.Name())
 
-			for i, chunk := range chunks {
-				fmt.Printf("%d. %s\n", i+1, chunk)
+			chunks, err := chunker.Split(context.Background(), []string{text})
+			if err != nil {
+				log.Fatalf("❌ Failed to chunk text: %v\n", err)
+			}
+
+			for i, c := range chunks[0] {
+				fmt.Printf("%d. %s\n", i+1, c.Text)
 			}
 		},
 	}
 
-	cmd.Flags().IntVar(&chunkSize, "chunkSize", 200, "Specify the chunk size in words [default: 200]")
-	cmd.Flags().IntVar(&overlap, "overlap", 0, "Specify number of words to overlap between chunks [default: 0]")
+	cmd.Flags().StringVar(&strategy, "strategy", chunk.StrategyWords, "Splitter. [choices: words|sentences|tokens|recursive|semantic]")
+	cmd.Flags().IntVar(&chunkSize, "chunkSize", 200, "Chunk size in the strategy's unit: words, sentences, tokens or characters (default from chunking config)")
+	cmd.Flags().IntVar(&overlap, "overlap", 0, "Units shared by consecutive chunks (default from chunking config)")
+	cmd.Flags().Float64Var(&percentile, "percentile", 90, "semantic strategy: adjacent-sentence distance percentile that starts a new chunk")
+
+	cmd.RegisterFlagCompletionFunc(
+		"strategy",
+		cobra.FixedCompletions(chunk.Strategies, cobra.ShellCompDirectiveNoFileComp),
+	)
 
 	return cmd
 }
@@ -7,20 +7,32 @@ import (
 	"fmt"
 	"log"
 
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/chunk"
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/cli"
 	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/fs"
 	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/methods"
 
 	"github.com/spf13/cobra"
 )
 
+var (
+	embedChunksResume   bool
+	embedChunksStrategy string
+)
+
 var embedChunksCmd = &cobra.Command{
 	Use:   "embedChunks",
 	Short: "Verifies chunked embeddings exist if not creates them",
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		return cli.ValidateFlagEnum(embedChunksStrategy, "strategy", chunk.Strategies...)
+	},
 	Run: func(cmd *cobra.Command, args []string) {
-		css, err := methods.NewChunkedSemanticSearch("nomic-embed-text")
+		css, err := methods.NewChunkedSemanticSearch()
 		if err != nil {
 			log.Fatalf("❌ Failed to create chunked semantic search client: %v\n", err)
 		}
+		css.Resume = embedChunksResume
+		css.Strategy = cli.ResolveString(cmd, "strategy", embedChunksStrategy, css.Strategy)
 
 		moviesDocs, err := fs.LoadMovies()
 		if err != nil {
@@ -37,6 +49,7 @@ var embedChunksCmd = &cobra.Command{
 }
 
 func init() {
+	embedChunksCmd.Flags().BoolVar(&embedChunksResume, "resume", false, "Continue an interrupted embedding build from its checkpoint instead of starting over")
+	embedChunksCmd.Flags().StringVar(&embedChunksStrategy, "strategy", chunk.StrategySentences, "Chunking strategy (default from chunking.strategy). [choices: sentences|words|tokens|recursive|semantic]")
 	SemanticCmd.AddCommand(embedChunksCmd)
-
 }
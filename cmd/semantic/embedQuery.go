@@ -21,7 +21,7 @@ var embedQueryCmd = &cobra.Command{
 		}
 		query := args[0]
 
-		ss, err := methods.NewSemanticSearch("nomic-embed-text")
+		ss, err := methods.NewSemanticSearch()
 		if err != nil {
 			log.Fatalf("❌ Failed to create semantic search client: %v\n", err)
 		}
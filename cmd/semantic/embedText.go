@@ -22,7 +22,7 @@ var embedTextCmd = &cobra.Command{
 
 		text := args[0]
 
-		ss, err := methods.NewSemanticSearch("nomic-embed-text")
+		ss, err := methods.NewSemanticSearch()
 		if err != nil {
 			log.Fatalf("❌ Failed to create semantic search client: %v\n", err)
 		}
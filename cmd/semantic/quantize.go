@@ -0,0 +1,194 @@
+package semantic
+
+import (
+	"fmt"
+	"log"
+	"math/rand"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/cli"
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/config"
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/methods"
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/quant"
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/vecstore"
+	"github.com/spf13/cobra"
+)
+
+func newQuantizeCmd() *cobra.Command {
+	var (
+		target      string
+		storage     string
+		subvectors  int
+		rescore     int
+		k           int
+		evalQueries int
+	)
+
+	cmd := &cobra.Command{
+		Use:   "quantize [--target <docs|chunks>] [--storage <int8|pq>] [--subvectors <int>] [--rescore <int>]",
+		Short: "Quantize the cached embeddings (int8 or PQ) and report compression ratio and recall@k",
+		Example: `semantic quantize --storage int8
+semantic quantize --target chunks --storage pq --subvectors 96 --rescore 8`,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if err := cli.ValidateFlagEnum(target, "target", methods.ANNTargetDocs, methods.ANNTargetChunks); err != nil {
+				return err
+			}
+			return cli.ValidateFlagEnum(storage, "storage", quant.KindInt8, quant.KindPQ)
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			cfg := config.Get().Quantize
+			params := methods.QuantizeParams()
+			params.Subvectors = cli.ResolveInt(cmd, "subvectors", subvectors, cfg.PQSubvectors)
+			rescore = cli.ResolveInt(cmd, "rescore", rescore, cfg.Rescore)
+
+			vectors, err := loadANNVectors(target)
+			if err != nil {
+				log.Fatalf("❌ Failed to load embeddings: %v\n", err)
+			}
+			if len(vectors) == 0 {
+				log.Fatalf("❌ No %s embeddings to quantize\n", target)
+			}
+
+			start := time.Now()
+			q, err := methods.BuildQuantized(target, storage, vectors, params)
+			if err != nil {
+				log.Fatalf("❌ Failed to quantize embeddings: %v\n", err)
+			}
+			path, sourcePath := methods.QuantizedPaths(target, storage)
+			fmt.Printf("✅ Quantized in %s → %s\n\n", time.Since(start).Round(time.Millisecond), path)
+
+			dim := len(vectors[0])
+			fullBytes := len(vectors) * dim * 4
+			fmt.Printf("Vectors: %d × %d dims\n", len(vectors), dim)
+			fmt.Printf("%s: %s in memory, %s on disk (%s)\n", quant.KindFloat, humanBytes(fullBytes), fileSize(sourcePath), sourcePath)
+			fmt.Printf("%s: %s in memory, %s on disk\n", storage, humanBytes(q.Bytes()), fileSize(path))
+			fmt.Printf("Compression ratio: %.1fx\n\n", float64(fullBytes)/float64(q.Bytes()))
+
+			report, err := evaluateQuantized(q, vectors, k, rescore, evalQueries)
+			if err != nil {
+				log.Fatalf("❌ Failed to evaluate quantized embeddings: %v\n", err)
+			}
+			fmt.Printf("Recall@%d over %d sampled queries:\n", k, report.queries)
+			fmt.Printf("  quantized only:            %.4f\n", report.recall)
+			if rescore > 0 {
+				fmt.Printf("  rescored (top %d×%d exact): %.4f (%+.4f)\n", k, rescore, report.rescored, report.rescored-report.recall)
+			}
+		},
+	}
+
+	cmd.Flags().StringVar(&target, "target", methods.ANNTargetDocs, "Embeddings to quantize. [choices: docs|chunks]")
+	cmd.Flags().StringVar(&storage, "storage", quant.KindInt8, "Quantization. [choices: int8|pq]")
+	cmd.Flags().IntVar(&subvectors, "subvectors", 0, "PQ subspaces, one byte per vector each (0 = one per 8 dimensions)")
+	cmd.Flags().IntVar(&rescore, "rescore", 4, "Re-rank k*rescore candidates with full-precision vectors (0 = off)")
+	cmd.Flags().IntVar(&k, "k", 10, "k used for the recall@k report")
+	cmd.Flags().IntVar(&evalQueries, "evalQueries", 200, "Number of stored vectors sampled as queries for the recall report (each query's own vector is left out)")
+
+	cmd.RegisterFlagCompletionFunc(
+		"storage",
+		cobra.FixedCompletions([]string{quant.KindInt8, quant.KindPQ}, cobra.ShellCompDirectiveNoFileComp),
+	)
+
+	return cmd
+}
+
+type quantizeReport struct {
+	queries  int
+	recall   float64
+	rescored float64
+}
+
+// evaluateQuantized compares the quantized top-k (with and without
+// rescoring) against exact cosine search, using stored vectors as queries.
+// Each query's own vector is left out of every list, as it would always be
+// found first.
+func evaluateQuantized(q quant.Quantizer, vectors [][]float32, k int, rescore int, queries int) (quantizeReport, error) {
+	queries = min(queries, len(vectors))
+	rng := rand.New(rand.NewSource(42))
+	sample := rng.Perm(len(vectors))[:queries]
+
+	var report quantizeReport
+	report.queries = queries
+
+	for _, i := range sample {
+		query := vectors[i]
+
+		exactScores := make([]float32, len(vectors))
+		for j, v := range vectors {
+			exactScores[j] = float32(methods.CosineSimilarity(query, v))
+		}
+		exact := withoutIndex(vecstore.TopScores(exactScores, k+1), i, k)
+
+		approxScores, err := q.Scores(query)
+		if err != nil {
+			return quantizeReport{}, err
+		}
+		report.recall += overlap(exact, withoutIndex(vecstore.TopScores(approxScores, k+1), i, k))
+
+		if rescore > 0 {
+			candidates := withoutIndex(vecstore.TopScores(approxScores, k*rescore+1), i, k*rescore)
+			sort.Slice(candidates, func(a, b int) bool {
+				return exactScores[candidates[a]] > exactScores[candidates[b]]
+			})
+			report.rescored += overlap(exact, candidates[:min(k, len(candidates))])
+		}
+	}
+
+	if queries > 0 {
+		report.recall /= float64(queries)
+		report.rescored /= float64(queries)
+	}
+	return report, nil
+}
+
+// withoutIndex drops self from ids and keeps n.
+func withoutIndex(ids []int, self int, n int) []int {
+	kept := make([]int, 0, len(ids))
+	for _, id := range ids {
+		if id != self {
+			kept = append(kept, id)
+		}
+	}
+	return kept[:min(n, len(kept))]
+}
+
+func overlap(exact []int, approx []int) float64 {
+	if len(exact) == 0 {
+		return 1
+	}
+	found := make(map[int]struct{}, len(approx))
+	for _, id := range approx {
+		found[id] = struct{}{}
+	}
+	hits := 0
+	for _, id := range exact {
+		if _, ok := found[id]; ok {
+			hits++
+		}
+	}
+	return float64(hits) / float64(len(exact))
+}
+
+func fileSize(path string) string {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "?"
+	}
+	return humanBytes(int(info.Size()))
+}
+
+func humanBytes(n int) string {
+	switch {
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MiB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1f KiB", float64(n)/(1<<10))
+	default:
+		return fmt.Sprintf("%d B", n)
+	}
+}
+
+func init() {
+	SemanticCmd.AddCommand(newQuantizeCmd())
+}
@@ -6,26 +6,46 @@ package semantic
 import (
 	"fmt"
 	"log"
+	"strings"
 
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/cli"
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/config"
 	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/fs"
 	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/methods"
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/quant"
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/utils"
 	"github.com/spf13/cobra"
 )
 
 func newSearchCmd() *cobra.Command {
-	var limit int
+	var (
+		limit     int
+		useANN    bool
+		efSearch  int
+		storage   string
+		rescore   int
+		diversify float64
+		fields    string
+	)
 
 	cmd := &cobra.Command{
-		Use:   "search <query> [--limit <int>]",
+		Use:   "search <query> [--limit <int>] [--ann [--efSearch <int>] | --storage <float32|int8|pq> [--rescore <int>]] [--fields <field=weight,...>]",
 		Short: "Semantic search for query among all documents/movies",
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if _, err := methods.ParseFieldWeights(fields); err != nil {
+				return fmt.Errorf("invalid --fields: %w", err)
+			}
+			return cli.ValidateFlagEnum(storage, "storage", quant.Kinds...)
+		},
 		Run: func(cmd *cobra.Command, args []string) {
 			if len(args) < 1 {
 				fmt.Println("❌ Please provide a query.")
 				return
 			}
 			query := args[0]
+			limit = cli.ResolveInt(cmd, "limit", limit, config.Get().Search.Limit)
 
-			ss, err := methods.NewSemanticSearch("nomic-embed-text")
+			ss, err := methods.NewSemanticSearch()
 			if err != nil {
 				log.Fatalf("❌ Failed to create semantic search client: %v\n", err)
 			}
@@ -35,29 +55,92 @@ func newSearchCmd() *cobra.Command {
 				log.Fatalf("❌ Failed to load movies: %v\n", err)
 			}
 
-			_, err = ss.LoadOrCreateEmbeddings(moviesDocs)
-			if err != nil {
-				log.Fatalf("❌ Failed to load or generate embeddings: %v\n", err)
+			fields = cli.ResolveString(cmd, "fields", fields, config.Get().Search.FieldWeights)
+			weights, _ := methods.ParseFieldWeights(fields)
+
+			// Field search scans every field's vectors at full precision
+			if len(weights) > 0 && (useANN || cmd.Flags().Changed("storage")) {
+				if cmd.Flags().Changed("fields") {
+					log.Fatalf("❌ --fields can't be combined with --ann or --storage\n")
+				}
+				fmt.Println("ℹ️ Ignoring search.field_weights: field search doesn't support --ann or --storage")
+				weights = nil
+			}
+
+			switch {
+			case len(weights) > 0:
+				if err := ss.LoadOrCreateFieldEmbeddings(moviesDocs, weights); err != nil {
+					log.Fatalf("❌ Failed to load or generate field embeddings: %v\n", err)
+				}
+			case useANN:
+				if _, err := ss.LoadOrCreateEmbeddings(moviesDocs); err != nil {
+					log.Fatalf("❌ Failed to load or generate embeddings: %v\n", err)
+				}
+				if err := ss.LoadOrBuildANN(annParams(cmd, efSearch)); err != nil {
+					log.Fatalf("❌ Failed to load or build HNSW index: %v\n", err)
+				}
+			default:
+				if _, err := ss.LoadOrCreateEmbeddings(moviesDocs); err != nil {
+					log.Fatalf("❌ Failed to load or generate embeddings: %v\n", err)
+				}
+				cfg := config.Get().Quantize
+				storage = cli.ResolveString(cmd, "storage", storage, cfg.Storage)
+				rescore = cli.ResolveInt(cmd, "rescore", rescore, cfg.Rescore)
+				if err := ss.UseStorage(storage, rescore); err != nil {
+					log.Fatalf("❌ Failed to load or build quantized embeddings: %v\n", err)
+				}
 			}
 
-			results, err := ss.Search(query, limit)
+			diversify = cli.ResolveFloat(cmd, "diversify", diversify, config.Get().MMR.Lambda)
+			searchLimit := limit
+			if diversify > 0 {
+				searchLimit = methods.DiversifyCandidates(limit)
+			}
+
+			results, err := ss.Search(query, searchLimit)
 			if err != nil {
 				log.Fatalf("❌ Failed to perform semantic search: %v\n", err)
 			}
+			if diversify > 0 {
+				if results, err = ss.DiversifySemantic(results, diversify, limit); err != nil {
+					log.Fatalf("❌ Failed to diversify results: %v\n", err)
+				}
+			}
 
 			for i, result := range results {
 				fmt.Printf("%d. %s (score: %.4f)\n", i+1, result.Title, result.Score)
-				fmt.Printf("   %s ...\n\n", result.Description[:100])
+				if len(result.FieldScores) > 0 {
+					fmt.Printf("   %s\n", fieldScoresToStr(result.FieldScores))
+				}
+				fmt.Printf("   %s ...\n\n", utils.Truncate(result.Description, 100))
 			}
 
 		},
 	}
 	cmd.Flags().IntVar(&limit, "limit", 5, "Limit the amount of results [default: 5]")
+	cmd.Flags().BoolVar(&useANN, "ann", false, "Search the HNSW index instead of scanning every vector (see `semantic buildANN`)")
+	cmd.Flags().IntVar(&efSearch, "efSearch", 64, "HNSW candidate list size with --ann (higher = better recall, slower)")
+	cmd.Flags().StringVar(&storage, "storage", quant.KindFloat, "Embeddings to scan: full precision or a quantized copy (see `semantic quantize`; full-precision vectors are still loaded). [choices: float32|int8|pq]")
+	cmd.Flags().Float64Var(&diversify, "diversify", 0, "Re-rank with Maximal Marginal Relevance: lambda from 1 (relevance only) down to 0 (variety only) (default from mmr.lambda; 0 = off)")
+	cmd.Flags().StringVar(&fields, "fields", "", "Score the weighted mean of per-field similarities, e.g. title=0.3,description=0.7 (fields: title|description|keywords; default from search.field_weights)")
+	cmd.Flags().IntVar(&rescore, "rescore", 4, "With --storage int8|pq, re-rank limit*rescore candidates with full-precision vectors (0 = off)")
 
 	return cmd
 
 }
 
+// fieldScoresToStr lists the similarity of each field, e.g.
+// "title 0.41 | description 0.63".
+func fieldScoresToStr(scores map[string]float64) string {
+	parts := make([]string, 0, len(scores))
+	for _, field := range methods.Fields {
+		if score, ok := scores[field]; ok {
+			parts = append(parts, fmt.Sprintf("%s %.4f", field, score))
+		}
+	}
+	return strings.Join(parts, " | ")
+}
+
 func init() {
 	searchCmd := newSearchCmd()
 	SemanticCmd.AddCommand(searchCmd)
@@ -4,31 +4,61 @@ Copyright © 2025 NAME HERE <EMAIL ADDRESS>
 package semantic
 
 import (
+	"context"
 	"fmt"
 	"log"
 
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/cli"
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/config"
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/embed"
 	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/fs"
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/llms"
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/logging"
 	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/methods"
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/quant"
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/utils"
+	"github.com/google/uuid"
 	"github.com/spf13/cobra"
 )
 
 func newSearchChunkedCmd() *cobra.Command {
-	var limit int
+	var (
+		limit     int
+		useANN    bool
+		efSearch  int
+		storage   string
+		rescore   int
+		aggregate string
+		diversify float64
+		enhance   string
+		debug     bool
+	)
 
 	cmd := &cobra.Command{
-		Use:   "searchChunked <query> [--limit <int>]",
+		Use:   "searchChunked <query> [--limit <int>] [--ann [--efSearch <int>] | --storage <float32|int8|pq> [--rescore <int>]] [--aggregate <max|topMean|decaySum|softmax|maxMean>] [--enhance <spell|rewrite|expand|hyde>]",
 		Short: "Chunked semantic search for query among all documents/movies",
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if err := cli.ValidateFlagEnum(aggregate, "aggregate", methods.Aggregations...); err != nil {
+				return err
+			}
+			if err := cli.ValidateFlagEnum(enhance, "enhance", llms.Enhancements...); err != nil {
+				return err
+			}
+			return cli.ValidateFlagEnum(storage, "storage", quant.Kinds...)
+		},
 		Run: func(cmd *cobra.Command, args []string) {
 			if len(args) < 1 {
 				fmt.Println("❌ Please provide a query.")
 				return
 			}
 			query := args[0]
+			limit = cli.ResolveInt(cmd, "limit", limit, config.Get().Search.Limit)
 
-			css, err := methods.NewChunkedSemanticSearch("nomic-embed-text")
+			css, err := methods.NewChunkedSemanticSearch()
 			if err != nil {
 				log.Fatalf("❌ Failed to create semantic search client: %v\n", err)
 			}
+			css.Aggregation.Method = cli.ResolveString(cmd, "aggregate", aggregate, css.Aggregation.Method)
 
 			moviesDocs, err := fs.LoadMovies()
 			if err != nil {
@@ -40,19 +70,89 @@ func newSearchChunkedCmd() *cobra.Command {
 				log.Fatalf("❌ Failed to load or generate embeddings: %v\n", err)
 			}
 
-			results, err := css.SearchChunked(query, limit)
+			if useANN {
+				if err := css.LoadOrBuildChunksANN(annParams(cmd, efSearch)); err != nil {
+					log.Fatalf("❌ Failed to load or build HNSW index: %v\n", err)
+				}
+			} else {
+				cfg := config.Get().Quantize
+				storage = cli.ResolveString(cmd, "storage", storage, cfg.Storage)
+				rescore = cli.ResolveInt(cmd, "rescore", rescore, cfg.Rescore)
+				if err := css.UseChunksStorage(storage, rescore); err != nil {
+					log.Fatalf("❌ Failed to load or build quantized embeddings: %v\n", err)
+				}
+			}
+
+			logger := logging.New(debug)
+			execCtx := logging.ExecutionContext{
+				RunID:   uuid.New().String(),
+				QueryID: uuid.New().String(),
+			}
+			logging.LogOriginalQuery(logger, execCtx, query)
+
+			if enhance != "" {
+				enhancedQuery, err := llms.PreProcessQuery(context.Background(), query, enhance)
+				if err != nil {
+					log.Fatalf("❌ Failed to enhance query: %v\n", err)
+				}
+				if enhance == llms.EnhanceHyDE {
+					fmt.Printf("HyDE passage for '%s': '%s'\n", query, enhancedQuery)
+					logging.LogHyDEPassage(logger, execCtx, query, enhancedQuery)
+				} else {
+					fmt.Printf("Enhanced query (%s): '%s' -> '%s'\n", enhance, query, enhancedQuery)
+					logging.LogEnhancedQuery(logger, execCtx, logging.EnhancedQueryLog{
+						EnhancementType: enhance,
+						OriginalQuery:   query,
+						EnhancedQuery:   enhancedQuery,
+					})
+				}
+				// There is no keyword leg, so the passage replaces the query
+				query = enhancedQuery
+			}
+
+			diversify = cli.ResolveFloat(cmd, "diversify", diversify, config.Get().MMR.Lambda)
+			searchLimit := limit
+			if diversify > 0 {
+				searchLimit = methods.DiversifyCandidates(limit)
+			}
+
+			results, err := css.SearchChunked(query, searchLimit)
 			if err != nil {
 				log.Fatalf("❌ Failed to perform semantic search: %v\n", err)
 			}
+			stats := embed.QueryCacheStats()
+			logging.LogQueryCache(logger, execCtx, stats.Hits, stats.Misses)
+			if diversify > 0 {
+				if results, err = css.DiversifySemantic(results, diversify, limit); err != nil {
+					log.Fatalf("❌ Failed to diversify results: %v\n", err)
+				}
+			}
 
 			for i, result := range results {
 				fmt.Printf("%d. %s (score: %.4f)\n", i+1, result.Title, result.Score)
-				fmt.Printf("   %s ...\n\n", result.Description[:100])
+				if len(result.Passages) == 0 {
+					fmt.Printf("   %s ...\n\n", utils.Truncate(result.Description, 100))
+					continue
+				}
+				for _, p := range result.Passages {
+					fmt.Printf("   [%d-%d] (%.4f) %s\n", p.Start, p.End, p.Score, utils.PassagesToStr([]methods.Passage{p}))
+				}
+				fmt.Println()
 			}
 
 		},
 	}
 	cmd.Flags().IntVar(&limit, "limit", 5, "Limit the amount of results [default: 5]")
+	cmd.Flags().BoolVar(&useANN, "ann", false, "Search the HNSW index instead of scanning every vector (see `semantic buildANN`)")
+	cmd.Flags().IntVar(&efSearch, "efSearch", 64, "HNSW candidate list size with --ann (higher = better recall, slower)")
+	cmd.Flags().StringVar(&storage, "storage", quant.KindFloat, "Embeddings to scan: full precision or a quantized copy (see `semantic quantize`). [choices: float32|int8|pq]")
+	cmd.Flags().StringVar(&aggregate, "aggregate", methods.AggregateMax, "How chunk scores become a document score (default from aggregation.method). [choices: max|topMean|decaySum|softmax|maxMean]")
+	cmd.Flags().IntVar(&rescore, "rescore", 4, "With --storage int8|pq, re-rank limit*rescore candidates with full-precision vectors (0 = off)")
+	cmd.Flags().StringVar(&enhance, "enhance", "", "Query enhancement method; hyde searches with a hypothetical movie description. [choices: spell|rewrite|expand|hyde]")
+	cmd.Flags().BoolVar(&debug, "debug", false, "Enable debug logging")
+	cmd.Flags().Float64Var(&diversify, "diversify", 0, "Re-rank with Maximal Marginal Relevance: lambda from 1 (relevance only) down to 0 (variety only) (default from mmr.lambda; 0 = off)")
+
+	cmd.RegisterFlagCompletionFunc("enhance", cobra.FixedCompletions(llms.Enhancements, cobra.ShellCompDirectiveNoFileComp))
 
 	return cmd
 
@@ -6,6 +6,8 @@ package semantic
 import (
 	"fmt"
 
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/cli"
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/config"
 	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/methods"
 	"github.com/spf13/cobra"
 )
@@ -23,6 +25,9 @@ func newSemanticChunkCmd() *cobra.Command {
 				return
 			}
 			text := args[0]
+			cfg := config.Get()
+			maxChunkSize = cli.ResolveInt(cmd, "maxChunkSize", maxChunkSize, cfg.Chunking.MaxChunkSize)
+			overlap = cli.ResolveInt(cmd, "overlap", overlap, cfg.Chunking.Overlap)
 			fmt.Printf("Chunking %d characters\n", len(text))
 
 			chunks := methods.SemanticChunk(text, maxChunkSize, overlap)
@@ -34,7 +39,7 @@ func newSemanticChunkCmd() *cobra.Command {
 	}
 
 	cmd.Flags().IntVar(&maxChunkSize, "maxChunkSize", 4, "Specify the chunk size in sentences [default: 4]")
-	cmd.Flags().IntVar(&overlap, "overlap", 0, "Specify number of sentences to overlap between chunks [default: 0]")
+	cmd.Flags().IntVar(&overlap, "overlap", 1, "Specify number of sentences to overlap between chunks [default: 1]")
 
 	return cmd
 }
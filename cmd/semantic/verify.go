@@ -14,7 +14,7 @@ var verifyCmd = &cobra.Command{
 	Use:   "verify",
 	Short: "Verify that the semantic model is correctly loaded and working",
 	Run: func(cmd *cobra.Command, args []string) {
-		ss, err := methods.NewSemanticSearch("nomic-embed-text")
+		ss, err := methods.NewSemanticSearch()
 		if err != nil {
 			log.Fatalf("❌ Failed to create semantic search client: %v\n", err)
 		}
@@ -12,14 +12,17 @@ import (
 	"github.com/spf13/cobra"
 )
 
+var verifyResume bool
+
 var verifyEmbeddingsCmd = &cobra.Command{
 	Use:   "verifyEmbeddings",
 	Short: "Verifies embeddings exist if not creates them",
 	Run: func(cmd *cobra.Command, args []string) {
-		ss, err := methods.NewSemanticSearch("nomic-embed-text")
+		ss, err := methods.NewSemanticSearch()
 		if err != nil {
 			log.Fatalf("❌ Failed to create semantic search client: %v\n", err)
 		}
+		ss.Resume = verifyResume
 
 		moviesDocs, err := fs.LoadMovies()
 		if err != nil {
@@ -40,5 +43,6 @@ var verifyEmbeddingsCmd = &cobra.Command{
 }
 
 func init() {
+	verifyEmbeddingsCmd.Flags().BoolVar(&verifyResume, "resume", false, "Continue an interrupted embedding build from its checkpoint instead of starting over")
 	SemanticCmd.AddCommand(verifyEmbeddingsCmd)
 }
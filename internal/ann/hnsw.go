@@ -0,0 +1,356 @@
+package ann
+
+import (
+	"container/heap"
+	"encoding/gob"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"os"
+	"sort"
+
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/fs"
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/vecstore"
+)
+
+// Params controls the HNSW graph:
+//   - M: links per node on upper layers (2*M on layer 0). Higher = better recall, more memory.
+//   - EfConstruction: candidate list size while inserting. Higher = better graph, slower build.
+//   - EfSearch: candidate list size while querying. Higher = better recall, slower search.
+type Params struct {
+	M              int
+	EfConstruction int
+	EfSearch       int
+	Seed           int64
+}
+
+func (p Params) String() string {
+	return fmt.Sprintf("hnsw(M=%d,efConstruction=%d)", p.M, p.EfConstruction)
+}
+
+// HNSW is a Hierarchical Navigable Small World graph over cosine similarity.
+// Vectors are stored normalized, so distance is 1 - dot product.
+type HNSW struct {
+	Params
+	Dim      int
+	Vectors  [][]float32
+	Links    [][][]int32 // node -> layer -> neighbour ids
+	Entry    int32
+	MaxLevel int
+
+	rng *rand.Rand
+}
+
+type Result struct {
+	ID    int
+	Score float32 // cosine similarity
+}
+
+func New(params Params) *HNSW {
+	if params.M < 2 {
+		params.M = 2
+	}
+	if params.EfConstruction < params.M {
+		params.EfConstruction = params.M
+	}
+	if params.EfSearch <= 0 {
+		params.EfSearch = 64
+	}
+
+	return &HNSW{
+		Params: params,
+		Entry:  -1,
+		rng:    rand.New(rand.NewSource(params.Seed)),
+	}
+}
+
+// Build inserts every vector in order, so node ids match the input positions.
+// onAdd (optional) is called after each insertion, e.g. to move a progress bar.
+func Build(vectors [][]float32, params Params, onAdd func()) *HNSW {
+	h := New(params)
+	for _, v := range vectors {
+		h.Add(v)
+		if onAdd != nil {
+			onAdd()
+		}
+	}
+	return h
+}
+
+func (h *HNSW) Len() int {
+	return len(h.Vectors)
+}
+
+func (h *HNSW) levelMult() float64 {
+	return 1 / math.Log(float64(h.M))
+}
+
+func (h *HNSW) randomLevel() int {
+	return int(math.Floor(-math.Log(1-h.rng.Float64()) * h.levelMult()))
+}
+
+func (h *HNSW) maxLinks(level int) int {
+	if level == 0 {
+		return 2 * h.M
+	}
+	return h.M
+}
+
+func (h *HNSW) distance(q []float32, id int32) float32 {
+	return 1 - vecstore.Dot(q, h.Vectors[id])
+}
+
+// Add inserts a vector and returns its id.
+func (h *HNSW) Add(vec []float32) int {
+	if h.Dim == 0 {
+		h.Dim = len(vec)
+	}
+
+	q := vecstore.Normalize(vec)
+	id := int32(len(h.Vectors))
+	level := h.randomLevel()
+
+	h.Vectors = append(h.Vectors, q)
+	h.Links = append(h.Links, make([][]int32, level+1))
+
+	if h.Entry < 0 {
+		h.Entry = id
+		h.MaxLevel = level
+		return int(id)
+	}
+
+	ep := h.Entry
+	for l := h.MaxLevel; l > level; l-- {
+		ep = h.greedy(q, ep, l)
+	}
+
+	for l := min(level, h.MaxLevel); l >= 0; l-- {
+		candidates := h.searchLayer(q, ep, h.EfConstruction, l)
+		neighbours := h.selectNeighbours(candidates, h.M)
+
+		h.Links[id][l] = make([]int32, 0, len(neighbours))
+		for _, n := range neighbours {
+			h.Links[id][l] = append(h.Links[id][l], n.id)
+			h.connect(n.id, id, n.dist, l)
+		}
+
+		ep = candidates[0].id
+	}
+
+	if level > h.MaxLevel {
+		h.MaxLevel = level
+		h.Entry = id
+	}
+
+	return int(id)
+}
+
+// connect adds a back link from node to newID, pruning node's links with
+// the neighbour heuristic when it exceeds the layer's capacity.
+func (h *HNSW) connect(node int32, newID int32, dist float32, level int) {
+	links := append(h.Links[node][level], newID)
+	if len(links) <= h.maxLinks(level) {
+		h.Links[node][level] = links
+		return
+	}
+
+	candidates := make([]candidate, 0, len(links))
+	for _, l := range links {
+		candidates = append(candidates, candidate{id: l, dist: h.distance(h.Vectors[node], l)})
+	}
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].dist < candidates[j].dist })
+
+	kept := h.selectNeighbours(candidates, h.maxLinks(level))
+	pruned := make([]int32, len(kept))
+	for i, c := range kept {
+		pruned[i] = c.id
+	}
+	h.Links[node][level] = pruned
+}
+
+// greedy walks one layer towards q, always moving to the closest neighbour.
+func (h *HNSW) greedy(q []float32, ep int32, level int) int32 {
+	best := ep
+	bestDist := h.distance(q, ep)
+
+	for changed := true; changed; {
+		changed = false
+		for _, n := range h.Links[best][level] {
+			if d := h.distance(q, n); d < bestDist {
+				best, bestDist = n, d
+				changed = true
+			}
+		}
+	}
+
+	return best
+}
+
+// searchLayer is the beam search of the HNSW paper: it returns up to ef
+// nodes closest to q on the given layer, sorted by ascending distance.
+func (h *HNSW) searchLayer(q []float32, ep int32, ef int, level int) []candidate {
+	visited := map[int32]struct{}{ep: {}}
+	d := h.distance(q, ep)
+
+	// found keeps the best ef by negated distance, so the closest rank best.
+	toVisit := &minHeap{{id: ep, dist: d}}
+	found := vecstore.NewTopK(ef)
+	found.Push(vecstore.Hit{ID: int(ep), Score: -d})
+
+	for toVisit.Len() > 0 {
+		c := heap.Pop(toVisit).(candidate)
+		if found.Full() && -c.dist < found.Worst().Score {
+			break
+		}
+
+		if level >= len(h.Links[c.id]) {
+			continue
+		}
+		for _, n := range h.Links[c.id][level] {
+			if _, seen := visited[n]; seen {
+				continue
+			}
+			visited[n] = struct{}{}
+
+			nd := h.distance(q, n)
+			if found.Push(vecstore.Hit{ID: int(n), Score: -nd}) {
+				heap.Push(toVisit, candidate{id: n, dist: nd})
+			}
+		}
+	}
+
+	hits := found.Hits()
+	results := make([]candidate, len(hits))
+	for i, hit := range hits {
+		results[i] = candidate{id: int32(hit.ID), dist: -hit.Score}
+	}
+	return results
+}
+
+// selectNeighbours keeps a candidate only if it's closer to the new node than
+// to every neighbour kept so far (diversifies links across clusters), then
+// tops up with the closest discarded ones.
+func (h *HNSW) selectNeighbours(candidates []candidate, m int) []candidate {
+	if len(candidates) <= m {
+		return candidates
+	}
+
+	kept := make([]candidate, 0, m)
+	discarded := make([]candidate, 0)
+	for _, c := range candidates {
+		if len(kept) >= m {
+			break
+		}
+		good := true
+		for _, k := range kept {
+			if 1-vecstore.Dot(h.Vectors[c.id], h.Vectors[k.id]) < c.dist {
+				good = false
+				break
+			}
+		}
+		if good {
+			kept = append(kept, c)
+		} else {
+			discarded = append(discarded, c)
+		}
+	}
+
+	for _, c := range discarded {
+		if len(kept) >= m {
+			break
+		}
+		kept = append(kept, c)
+	}
+
+	return kept
+}
+
+// Search returns the (approximately) k most similar vectors to query.
+func (h *HNSW) Search(query []float32, k int) []Result {
+	if h.Entry < 0 || k <= 0 {
+		return []Result{}
+	}
+
+	q := vecstore.Normalize(query)
+	ep := h.Entry
+	for l := h.MaxLevel; l > 0; l-- {
+		ep = h.greedy(q, ep, l)
+	}
+
+	candidates := h.searchLayer(q, ep, max(h.EfSearch, k), 0)
+	if len(candidates) > k {
+		candidates = candidates[:k]
+	}
+
+	results := make([]Result, len(candidates))
+	for i, c := range candidates {
+		results[i] = Result{ID: int(c.id), Score: 1 - c.dist}
+	}
+	return results
+}
+
+// ExactSearch brute-forces the k most similar vectors. Used as ground truth
+// when measuring recall.
+func (h *HNSW) ExactSearch(query []float32, k int) []Result {
+	q := vecstore.Normalize(query)
+
+	top := vecstore.NewTopK(k)
+	for i, v := range h.Vectors {
+		top.Push(vecstore.Hit{ID: i, Score: vecstore.Dot(q, v)})
+	}
+
+	hits := top.Hits()
+	results := make([]Result, len(hits))
+	for i, hit := range hits {
+		results[i] = Result{ID: hit.ID, Score: hit.Score}
+	}
+	return results
+}
+
+func (h *HNSW) Save(path string) error {
+	err := fs.WriteAtomic(path, func(w io.Writer) error {
+		return gob.NewEncoder(w).Encode(h)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write ANN index: %w", err)
+	}
+	return nil
+}
+
+func Load(path string) (*HNSW, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open ANN index file: %w", err)
+	}
+	defer f.Close()
+
+	h := &HNSW{}
+	if err := gob.NewDecoder(f).Decode(h); err != nil {
+		return nil, fmt.Errorf("failed to decode ANN index: %w", err)
+	}
+	h.rng = rand.New(rand.NewSource(h.Seed + int64(len(h.Vectors))))
+
+	return h, nil
+}
+
+// Recall is the fraction of the exact top-k ids also returned by the
+// approximate search.
+func Recall(exact []Result, approx []Result) float64 {
+	if len(exact) == 0 {
+		return 1
+	}
+
+	found := make(map[int]struct{}, len(approx))
+	for _, r := range approx {
+		found[r.ID] = struct{}{}
+	}
+
+	hits := 0
+	for _, r := range exact {
+		if _, ok := found[r.ID]; ok {
+			hits++
+		}
+	}
+	return float64(hits) / float64(len(exact))
+}
@@ -0,0 +1,125 @@
+package ann
+
+import (
+	"math/rand"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func randomVectors(n int, dim int, seed int64) [][]float32 {
+	rng := rand.New(rand.NewSource(seed))
+	vectors := make([][]float32, n)
+	for i := range vectors {
+		vectors[i] = make([]float32, dim)
+		for j := range vectors[i] {
+			vectors[i][j] = float32(rng.NormFloat64())
+		}
+	}
+	return vectors
+}
+
+func TestHNSWRecall(t *testing.T) {
+	tests := []struct {
+		name      string
+		n, dim, k int
+		params    Params
+		minRecall float64
+	}{
+		{"small graph", 200, 16, 10, Params{M: 8, EfConstruction: 64, EfSearch: 64, Seed: 1}, 0.95},
+		{"default-like", 1000, 32, 10, Params{M: 16, EfConstruction: 200, EfSearch: 64, Seed: 7}, 0.9},
+		{"k above efSearch", 300, 16, 50, Params{M: 8, EfConstruction: 64, EfSearch: 16, Seed: 3}, 0.9},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := Build(randomVectors(tt.n, tt.dim, tt.params.Seed), tt.params, nil)
+			queries := randomVectors(50, tt.dim, tt.params.Seed+100)
+
+			var total float64
+			for _, q := range queries {
+				exact := h.ExactSearch(q, tt.k)
+				approx := h.Search(q, tt.k)
+				if len(approx) != tt.k {
+					t.Fatalf("Search returned %d results, want %d", len(approx), tt.k)
+				}
+				for i := 1; i < len(approx); i++ {
+					if approx[i].Score > approx[i-1].Score {
+						t.Fatalf("results not sorted best first: %v", approx)
+					}
+				}
+				total += Recall(exact, approx)
+			}
+
+			if recall := total / float64(len(queries)); recall < tt.minRecall {
+				t.Errorf("recall@%d = %.3f, want >= %.2f", tt.k, recall, tt.minRecall)
+			}
+		})
+	}
+}
+
+func TestExactSearch(t *testing.T) {
+	h := Build([][]float32{{1, 0}, {0, 1}, {1, 1}, {-1, 0}}, Params{M: 4, Seed: 1}, nil)
+
+	got := h.ExactSearch([]float32{2, 0}, 3)
+	want := []int{0, 2, 1}
+	if len(got) != len(want) {
+		t.Fatalf("got %d results, want %d", len(got), len(want))
+	}
+	for i, id := range want {
+		if got[i].ID != id {
+			t.Errorf("result %d = %d, want %d (%v)", i, got[i].ID, id, got)
+		}
+	}
+	if got[0].Score < 0.999 {
+		t.Errorf("self similarity = %f, want 1", got[0].Score)
+	}
+}
+
+func TestSearchEmpty(t *testing.T) {
+	if got := New(Params{}).Search([]float32{1, 0}, 5); len(got) != 0 {
+		t.Errorf("empty index returned %v", got)
+	}
+}
+
+func TestSaveLoad(t *testing.T) {
+	h := Build(randomVectors(100, 8, 5), Params{M: 4, EfConstruction: 32, EfSearch: 32, Seed: 5}, nil)
+	path := filepath.Join(t.TempDir(), "index.hnsw")
+	if err := h.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	query := randomVectors(1, 8, 6)[0]
+	if got, want := loaded.Search(query, 10), h.Search(query, 10); !reflect.DeepEqual(got, want) {
+		t.Errorf("search after load = %v, want %v", got, want)
+	}
+	if matches, _ := filepath.Glob(path + ".tmp-*"); len(matches) != 0 {
+		t.Errorf("temp files left behind: %v", matches)
+	}
+}
+
+func TestRecall(t *testing.T) {
+	exact := []Result{{ID: 1}, {ID: 2}, {ID: 3}, {ID: 4}}
+	tests := []struct {
+		name   string
+		exact  []Result
+		approx []Result
+		want   float64
+	}{
+		{"all found", exact, []Result{{ID: 4}, {ID: 3}, {ID: 2}, {ID: 1}}, 1},
+		{"half found", exact, []Result{{ID: 1}, {ID: 3}, {ID: 9}}, 0.5},
+		{"none found", exact, []Result{{ID: 8}}, 0},
+		{"nothing to find", nil, nil, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Recall(tt.exact, tt.approx); got != tt.want {
+				t.Errorf("Recall = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,197 @@
+package checkpoint
+
+import (
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/fs"
+)
+
+const DefaultEvery = 1000
+
+// Checkpoint keeps the finished vectors of an in-progress embedding build
+// on disk, as numbered segment files in a directory next to the artifact,
+// so an interrupted build only has to embed what's missing.
+type Checkpoint struct {
+	Dir   string
+	Key   string // identifies the inputs (documents, model, chunking)
+	Total int
+
+	every    int
+	mu       sync.Mutex
+	pending  segment
+	segments int
+	saved    int
+}
+
+type meta struct {
+	Key   string `json:"key"`
+	Total int    `json:"total"`
+}
+
+type segment struct {
+	Indices []int
+	Vectors [][]float32
+}
+
+// Open starts a checkpoint for a build of total items. With resume it
+// returns the vectors saved by an earlier run of the same build (keyed by
+// item position); a checkpoint for different inputs is discarded. Without
+// resume any earlier checkpoint is discarded. Pending vectors are written
+// every `every` items.
+func Open(dir string, key string, total int, every int, resume bool) (*Checkpoint, map[int][]float32, error) {
+	if every <= 0 {
+		every = DefaultEvery
+	}
+	c := &Checkpoint{Dir: dir, Key: key, Total: total, every: every}
+	done := make(map[int][]float32)
+
+	if resume {
+		var err error
+		done, err = c.load()
+		if err != nil {
+			return nil, nil, err
+		}
+	} else if _, err := os.Stat(dir); err == nil {
+		fmt.Printf("🗑️ Discarding the checkpoint of an earlier interrupted build (use --resume to continue it)\n")
+	}
+
+	if len(done) == 0 {
+		if err := c.reset(); err != nil {
+			return nil, nil, err
+		}
+	}
+	c.saved = len(done)
+
+	return c, done, nil
+}
+
+func (c *Checkpoint) metaPath() string {
+	return filepath.Join(c.Dir, "checkpoint.json")
+}
+
+func (c *Checkpoint) reset() error {
+	if err := os.RemoveAll(c.Dir); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(c.Dir, os.ModePerm); err != nil {
+		return fmt.Errorf("failed to create checkpoint dir: %w", err)
+	}
+
+	raw, err := json.MarshalIndent(meta{Key: c.Key, Total: c.Total}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(c.metaPath(), raw, 0o644)
+}
+
+func (c *Checkpoint) load() (map[int][]float32, error) {
+	done := make(map[int][]float32)
+
+	raw, err := os.ReadFile(c.metaPath())
+	if os.IsNotExist(err) {
+		fmt.Println("No checkpoint found, starting from scratch.")
+		return done, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var m meta
+	if err := json.Unmarshal(raw, &m); err != nil || m.Key != c.Key || m.Total != c.Total {
+		fmt.Println("⚠️ Checkpoint was made for different documents or settings, starting from scratch.")
+		return done, nil
+	}
+
+	files, err := filepath.Glob(filepath.Join(c.Dir, "segment-*.gob"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+
+	for _, path := range files {
+		seg, err := readSegment(path)
+		if err != nil {
+			// Segments are written atomically, so this is real corruption
+			return nil, fmt.Errorf("corrupt checkpoint segment %s: %w", path, err)
+		}
+		for i, idx := range seg.Indices {
+			done[idx] = seg.Vectors[i]
+		}
+	}
+	c.segments = len(files)
+
+	fmt.Printf("♻️ Resuming from checkpoint: %d/%d already embedded\n", len(done), c.Total)
+	return done, nil
+}
+
+func readSegment(path string) (segment, error) {
+	var seg segment
+
+	f, err := os.Open(path)
+	if err != nil {
+		return seg, err
+	}
+	defer f.Close()
+
+	err = gob.NewDecoder(f).Decode(&seg)
+	return seg, err
+}
+
+// Add records finished vectors (indices are item positions) and writes a
+// segment once enough are pending. Safe for concurrent use.
+func (c *Checkpoint) Add(indices []int, vectors [][]float32) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.pending.Indices = append(c.pending.Indices, indices...)
+	c.pending.Vectors = append(c.pending.Vectors, vectors...)
+	if len(c.pending.Indices) < c.every {
+		return nil
+	}
+	return c.flush()
+}
+
+// Flush writes whatever is pending, e.g. before exiting on an error.
+func (c *Checkpoint) Flush() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.flush()
+}
+
+func (c *Checkpoint) flush() error {
+	if len(c.pending.Indices) == 0 {
+		return nil
+	}
+
+	path := filepath.Join(c.Dir, fmt.Sprintf("segment-%06d.gob", c.segments))
+	err := fs.WriteAtomic(path, func(w io.Writer) error {
+		return gob.NewEncoder(w).Encode(c.pending)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write checkpoint: %w", err)
+	}
+
+	c.segments++
+	c.saved += len(c.pending.Indices)
+	c.pending = segment{}
+	return nil
+}
+
+// Saved is the number of items safely on disk.
+func (c *Checkpoint) Saved() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.saved
+}
+
+// Remove deletes the checkpoint once the final artifact is in place.
+func (c *Checkpoint) Remove() error {
+	return os.RemoveAll(c.Dir)
+}
@@ -0,0 +1,116 @@
+package checkpoint
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func vec(i int) []float32 { return []float32{float32(i), float32(i) / 2} }
+
+// interruptedBuild adds items 0..added-1 in batches of two, every 4, and
+// returns without flushing or removing, like a crashed build.
+func interruptedBuild(t *testing.T, dir string, key string, added int) {
+	t.Helper()
+	c, done, err := Open(dir, key, 10, 4, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(done) != 0 {
+		t.Fatalf("fresh build has %d done items", len(done))
+	}
+	for i := 0; i < added; i += 2 {
+		if err := c.Add([]int{i, i + 1}, [][]float32{vec(i), vec(i + 1)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestResume(t *testing.T) {
+	tests := []struct {
+		name     string
+		key      string
+		resume   bool
+		wantDone int
+	}{
+		// 6 added, but only the first 4 reached a segment
+		{name: "resume skips saved items", key: "docs-v1", resume: true, wantDone: 4},
+		{name: "other inputs start over", key: "docs-v2", resume: true, wantDone: 0},
+		{name: "no resume discards", key: "docs-v1", resume: false, wantDone: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := filepath.Join(t.TempDir(), "ckpt")
+			interruptedBuild(t, dir, "docs-v1", 6)
+
+			c, done, err := Open(dir, tt.key, 10, 4, tt.resume)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(done) != tt.wantDone || c.Saved() != tt.wantDone {
+				t.Fatalf("done = %d, saved = %d, want %d", len(done), c.Saved(), tt.wantDone)
+			}
+			for i := 0; i < tt.wantDone; i++ {
+				if !reflect.DeepEqual(done[i], vec(i)) {
+					t.Errorf("item %d = %v, want %v", i, done[i], vec(i))
+				}
+			}
+		})
+	}
+}
+
+func TestResumeAcrossRuns(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "ckpt")
+	interruptedBuild(t, dir, "docs", 4)
+
+	// Second run embeds only what's missing, then flushes on error
+	c, done, err := Open(dir, "docs", 10, 4, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var missing []int
+	for i := 0; i < 10; i++ {
+		if _, ok := done[i]; !ok {
+			missing = append(missing, i)
+		}
+	}
+	if want := []int{4, 5, 6, 7, 8, 9}; !reflect.DeepEqual(missing, want) {
+		t.Fatalf("missing = %v, want %v", missing, want)
+	}
+	if err := c.Add([]int{4}, [][]float32{vec(4)}); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if c.Saved() != 5 {
+		t.Errorf("saved = %d, want 5", c.Saved())
+	}
+
+	_, done, err = Open(dir, "docs", 10, 4, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(done) != 5 || !reflect.DeepEqual(done[4], vec(4)) {
+		t.Errorf("third run resumes %d items (item 4 = %v), want 5", len(done), done[4])
+	}
+
+	if err := c.Remove(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Errorf("checkpoint dir still exists after Remove: %v", err)
+	}
+}
+
+func TestResumeCorruptSegment(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "ckpt")
+	interruptedBuild(t, dir, "docs", 4)
+	if err := os.WriteFile(filepath.Join(dir, "segment-000000.gob"), []byte("not gob"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := Open(dir, "docs", 10, 4, true); err == nil {
+		t.Error("expected an error for a corrupt segment")
+	}
+}
@@ -0,0 +1,169 @@
+package chunk
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/config"
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/embed"
+)
+
+const (
+	StrategySentences = "sentences" // fixed number of sentences per chunk
+	StrategyWords     = "words"     // fixed word windows
+	StrategyTokens    = "tokens"    // fixed token windows
+	StrategyRecursive = "recursive" // paragraphs, then lines, sentences, words, up to a character budget
+	StrategySemantic  = "semantic"  // sentences grouped until the embedding distance spikes
+)
+
+var Strategies = []string{StrategySentences, StrategyWords, StrategyTokens, StrategyRecursive, StrategySemantic}
+
+// Version is part of every chunking fingerprint. Bump it whenever a
+// splitter changes so chunk embeddings built with the old one are rebuilt.
+const Version = 2
+
+// Chunk is a piece of a source text; Start/End are byte offsets into it.
+type Chunk struct {
+	Text  string
+	Start int
+	End   int
+}
+
+// Chunker splits texts into chunks for embedding.
+type Chunker interface {
+	// Split returns the chunks of every text, in order. Texts are split
+	// together so chunkers that need the embedder can batch requests.
+	Split(ctx context.Context, texts []string) ([][]Chunk, error)
+	// Name describes the strategy and its parameters (used in manifests).
+	Name() string
+}
+
+// Options sizes a chunker. Size and Overlap are counted in the strategy's
+// unit: sentences, words, tokens or characters (recursive). For the
+// semantic strategy Size caps the sentences per chunk.
+type Options struct {
+	Size       int
+	Overlap    int
+	Percentile float64        // semantic: distance percentile that starts a new chunk
+	Embedder   embed.Embedder // semantic only
+}
+
+func New(strategy string, opts Options) (Chunker, error) {
+	if opts.Size <= 0 {
+		return nil, fmt.Errorf("chunk size must be positive (got %d)", opts.Size)
+	}
+	opts.Overlap = min(max(opts.Overlap, 0), opts.Size-1)
+
+	switch strategy {
+	case StrategySentences:
+		return &Sentences{opts}, nil
+	case StrategyWords:
+		return &Windows{opts: opts, unit: "words", pattern: wordPattern}, nil
+	case StrategyTokens:
+		return &Windows{opts: opts, unit: "tokens", pattern: tokenPattern}, nil
+	case StrategyRecursive:
+		return &Recursive{opts: opts, Separators: DefaultSeparators}, nil
+	case StrategySemantic:
+		if opts.Embedder == nil {
+			return nil, fmt.Errorf("the %s strategy needs an embedder", StrategySemantic)
+		}
+		return &Semantic{opts}, nil
+	default:
+		return nil, fmt.Errorf("unknown chunking strategy %q", strategy)
+	}
+}
+
+// ConfiguredOptions reads the sizes of a strategy from the chunking section.
+func ConfiguredOptions(strategy string) Options {
+	cfg := config.Get().Chunking
+	switch strategy {
+	case StrategyWords:
+		return Options{Size: cfg.WordChunkSize, Overlap: cfg.WordOverlap}
+	case StrategyTokens:
+		return Options{Size: cfg.TokenChunkSize, Overlap: cfg.TokenOverlap}
+	case StrategyRecursive:
+		return Options{Size: cfg.CharChunkSize, Overlap: cfg.CharOverlap}
+	case StrategySemantic:
+		return Options{Size: cfg.BreakpointMaxSentences, Percentile: cfg.BreakpointPercentile}
+	default:
+		return Options{Size: cfg.MaxChunkSize, Overlap: cfg.Overlap}
+	}
+}
+
+// FromConfig builds the chunker for strategy sized from the config.
+func FromConfig(strategy string, embedder embed.Embedder) (Chunker, error) {
+	opts := ConfiguredOptions(strategy)
+	opts.Embedder = embedder
+	return New(strategy, opts)
+}
+
+// Fingerprint identifies a chunker's output in cache manifests.
+func Fingerprint(c Chunker) string {
+	return fmt.Sprintf("v%d:%s", Version, c.Name())
+}
+
+// Texts returns the text of every chunk.
+func Texts(chunks []Chunk) []string {
+	texts := make([]string, len(chunks))
+	for i, c := range chunks {
+		texts[i] = c.Text
+	}
+	return texts
+}
+
+// windows returns [start, end) ranges of size items, each one sharing
+// overlap items with the previous one.
+func windows(n int, size int, overlap int) [][2]int {
+	var out [][2]int
+	if n == 0 {
+		return out
+	}
+
+	for start := 0; ; start += size - overlap {
+		end := min(start+size, n)
+		out = append(out, [2]int{start, end})
+		if end >= n {
+			return out
+		}
+	}
+}
+
+// fromSpans turns ranges over spans (byte offsets) into chunks of text.
+func fromSpans(text string, spans [][]int, ranges [][2]int) []Chunk {
+	chunks := make([]Chunk, 0, len(ranges))
+	for _, r := range ranges {
+		start, end := spans[r[0]][0], spans[r[1]-1][1]
+		chunks = append(chunks, Chunk{Text: text[start:end], Start: start, End: end})
+	}
+	return chunks
+}
+
+// sentenceEnd matches sentence-final punctuation (with any closing quotes
+// or brackets) followed by whitespace.
+var sentenceEnd = regexp.MustCompile(`[.!?]+["'”’)\]]*\s+`)
+
+// SplitSentences returns the byte spans of the sentences in text, with
+// their punctuation and without surrounding whitespace.
+func SplitSentences(text string) [][]int {
+	var spans [][]int
+
+	add := func(start, end int) {
+		if c, ok := trimmed(text, start, end); ok {
+			spans = append(spans, []int{c.Start, c.End})
+		}
+	}
+
+	start := 0
+	for _, m := range sentenceEnd.FindAllStringIndex(text, -1) {
+		add(start, m[1])
+		start = m[1]
+	}
+	add(start, len(text))
+
+	return spans
+}
+
+func isSpace(b byte) bool {
+	return b == ' ' || b == '\n' || b == '\t' || b == '\r'
+}
@@ -0,0 +1,228 @@
+package chunk
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/embed"
+)
+
+func split(t *testing.T, strategy string, opts Options, text string) []Chunk {
+	t.Helper()
+	c, err := New(strategy, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := c.Split(context.Background(), []string{text})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, chunk := range out[0] {
+		if text[chunk.Start:chunk.End] != chunk.Text {
+			t.Fatalf("chunk %q doesn't match its offsets [%d:%d]", chunk.Text, chunk.Start, chunk.End)
+		}
+	}
+	return out[0]
+}
+
+func TestWindows(t *testing.T) {
+	tests := []struct {
+		n, size, overlap int
+		want             [][2]int
+	}{
+		{0, 3, 0, nil},
+		{2, 3, 0, [][2]int{{0, 2}}},
+		{6, 3, 0, [][2]int{{0, 3}, {3, 6}}},
+		{5, 3, 1, [][2]int{{0, 3}, {2, 5}}},
+		{6, 3, 1, [][2]int{{0, 3}, {2, 5}, {4, 6}}},
+	}
+	for _, tt := range tests {
+		if got := windows(tt.n, tt.size, tt.overlap); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("windows(%d, %d, %d) = %v, want %v", tt.n, tt.size, tt.overlap, got, tt.want)
+		}
+	}
+}
+
+func TestSplitSentences(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"", nil},
+		{"   ", nil},
+		{"One sentence", []string{"One sentence"}},
+		{"First. Second! Third?", []string{"First.", "Second!", "Third?"}},
+		{`He said "go." Then left.`, []string{`He said "go."`, "Then left."}},
+		{"Wait... what?\n\nNext paragraph.", []string{"Wait...", "what?", "Next paragraph."}},
+		{"Version 1.5 is out.", []string{"Version 1.5 is out."}},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, span := range SplitSentences(tt.text) {
+			got = append(got, tt.text[span[0]:span[1]])
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("SplitSentences(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestFixedStrategies(t *testing.T) {
+	const text = "One two. Three four. Five six. Seven."
+	tests := []struct {
+		strategy string
+		opts     Options
+		want     []string
+	}{
+		{StrategySentences, Options{Size: 2}, []string{"One two. Three four.", "Five six. Seven."}},
+		{StrategySentences, Options{Size: 2, Overlap: 1}, []string{"One two. Three four.", "Three four. Five six.", "Five six. Seven."}},
+		{StrategyWords, Options{Size: 3}, []string{"One two. Three", "four. Five six.", "Seven."}},
+		{StrategyWords, Options{Size: 4, Overlap: 2}, []string{"One two. Three four.", "Three four. Five six.", "Five six. Seven."}},
+		{StrategyTokens, Options{Size: 4}, []string{"One two. Three", "four. Five six", ". Seven."}},
+		// Overlap is clamped below the size, so windows always advance
+		{StrategyWords, Options{Size: 3, Overlap: 5}, []string{"One two. Three", "two. Three four.", "Three four. Five", "four. Five six.", "Five six. Seven."}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.strategy, func(t *testing.T) {
+			got := Texts(split(t, tt.strategy, tt.opts, text))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("%+v: got %q, want %q", tt.opts, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRecursive(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		opts Options
+		want []string
+	}{
+		{
+			name: "fits",
+			text: "short text",
+			opts: Options{Size: 50},
+			want: []string{"short text"},
+		},
+		{
+			name: "paragraphs first",
+			text: "First paragraph here.\n\nSecond paragraph here.",
+			opts: Options{Size: 30},
+			want: []string{"First paragraph here.", "Second paragraph here."},
+		},
+		{
+			name: "words when a sentence is too long",
+			text: "alpha beta gamma delta",
+			opts: Options{Size: 11},
+			want: []string{"alpha beta", "gamma delta"},
+		},
+		{
+			name: "overlap repeats trailing pieces",
+			text: "aa bb cc dd ee",
+			opts: Options{Size: 8, Overlap: 3},
+			want: []string{"aa bb", "bb cc", "cc dd ee"},
+		},
+		{
+			name: "no chunk of only overlap",
+			text: "aaaa bbbb cccccc dddddd ee",
+			opts: Options{Size: 10, Overlap: 5},
+			want: []string{"aaaa bbbb", "cccccc", "dddddd ee"},
+		},
+		{
+			name: "hard cut on rune boundaries",
+			text: "ééééé",
+			opts: Options{Size: 3},
+			want: []string{"é", "é", "é", "é", "é"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chunks := split(t, StrategyRecursive, tt.opts, tt.text)
+			for _, c := range chunks {
+				if len(c.Text) > tt.opts.Size {
+					t.Errorf("chunk %q is over the %d byte budget", c.Text, tt.opts.Size)
+				}
+				if !utf8.ValidString(c.Text) {
+					t.Errorf("chunk %q isn't valid UTF-8", c.Text)
+				}
+			}
+			if got := Texts(chunks); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// topicEmbedder embeds a sentence on one axis per topic word it contains.
+type topicEmbedder struct{}
+
+func (topicEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		v := []float32{0.01, 0.01, 0.01}
+		for axis, topic := range []string{"cat", "rocket", "soup"} {
+			if strings.Contains(text, topic) {
+				v[axis] = 1
+			}
+		}
+		vectors[i] = v
+	}
+	return vectors, nil
+}
+
+func (topicEmbedder) Info() embed.Info {
+	return embed.Info{Provider: "test", Model: "topics", Dimension: 3, BatchSize: 2}
+}
+
+func TestSemantic(t *testing.T) {
+	const text = "The cat sleeps. A cat purrs. The rocket launches. The rocket lands. Hot soup."
+	tests := []struct {
+		name string
+		opts Options
+		want []string
+	}{
+		{
+			name: "breaks at topic shifts",
+			opts: Options{Size: 10, Percentile: 50},
+			want: []string{"The cat sleeps. A cat purrs.", "The rocket launches. The rocket lands.", "Hot soup."},
+		},
+		{
+			name: "size caps sentences per chunk",
+			opts: Options{Size: 1, Percentile: 50},
+			want: []string{"The cat sleeps.", "A cat purrs.", "The rocket launches.", "The rocket lands.", "Hot soup."},
+		},
+		{
+			name: "no breakpoint above the top percentile",
+			opts: Options{Size: 10, Percentile: 100},
+			want: []string{text},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.opts.Embedder = topicEmbedder{}
+			if got := Texts(split(t, StrategySemantic, tt.opts, text)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewErrors(t *testing.T) {
+	tests := []struct {
+		strategy string
+		opts     Options
+	}{
+		{StrategyWords, Options{Size: 0}},
+		{"paragraphs", Options{Size: 5}},
+		{StrategySemantic, Options{Size: 5}},
+	}
+	for _, tt := range tests {
+		if _, err := New(tt.strategy, tt.opts); err == nil {
+			t.Errorf("New(%q, %+v): expected an error", tt.strategy, tt.opts)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package chunk
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// DefaultSeparators go from coarse to fine; "" means a hard cut.
+var DefaultSeparators = []string{"\n\n", "\n", ". ", " ", ""}
+
+// Recursive splits on the coarsest separator that yields pieces within the
+// size budget (in UTF-8 bytes), falling back to finer ones for pieces that
+// are still too long, then packs consecutive pieces into chunks.
+type Recursive struct {
+	opts       Options
+	Separators []string
+}
+
+func (r *Recursive) Name() string {
+	return fmt.Sprintf("recursive(chars=%d,overlap=%d)", r.opts.Size, r.opts.Overlap)
+}
+
+func (r *Recursive) Split(ctx context.Context, texts []string) ([][]Chunk, error) {
+	out := make([][]Chunk, len(texts))
+	for i, text := range texts {
+		out[i] = r.merge(text, r.pieces(text, 0, len(text), 0))
+	}
+	return out, nil
+}
+
+// pieces cuts text[start:end] into spans no longer than the budget. A
+// separator stays attached to the piece before it, so spans are contiguous.
+func (r *Recursive) pieces(text string, start int, end int, level int) [][]int {
+	if end-start <= r.opts.Size {
+		return [][]int{{start, end}}
+	}
+	if level >= len(r.Separators) || r.Separators[level] == "" {
+		return r.hardCut(text, start, end)
+	}
+
+	sep := r.Separators[level]
+	var out [][]int
+	s := start
+	for {
+		idx := strings.Index(text[s:end], sep)
+		if idx < 0 {
+			break
+		}
+		cut := s + idx + len(sep)
+		out = append(out, r.pieces(text, s, cut, level+1)...)
+		s = cut
+	}
+	if s < end {
+		out = append(out, r.pieces(text, s, end, level+1)...)
+	}
+	return out
+}
+
+// hardCut splits on rune boundaries when no separator is left.
+func (r *Recursive) hardCut(text string, start int, end int) [][]int {
+	var out [][]int
+	for s := start; s < end; {
+		e := min(s+r.opts.Size, end)
+		for e < end && e > s && !utf8.RuneStart(text[e]) {
+			e--
+		}
+		if e == s {
+			// Budget smaller than one rune: take the whole rune
+			_, size := utf8.DecodeRuneInString(text[s:end])
+			e = s + size
+		}
+		out = append(out, []int{s, e})
+		s = e
+	}
+	return out
+}
+
+// merge packs consecutive pieces into chunks of at most the budget; each
+// chunk after the first starts with the trailing pieces of the previous one
+// that fit in the overlap and still leave room for the next piece (so no
+// chunk is only overlap).
+func (r *Recursive) merge(text string, pieces [][]int) []Chunk {
+	var chunks []Chunk
+
+	for i := 0; i < len(pieces); {
+		j := i + 1
+		for j < len(pieces) && pieces[j][1]-pieces[i][0] <= r.opts.Size {
+			j++
+		}
+		if c, ok := trimmed(text, pieces[i][0], pieces[j-1][1]); ok {
+			chunks = append(chunks, c)
+		}
+		if j >= len(pieces) {
+			break
+		}
+
+		k := j
+		for k-1 > i && pieces[j-1][1]-pieces[k-1][0] <= r.opts.Overlap && pieces[j][1]-pieces[k-1][0] <= r.opts.Size {
+			k--
+		}
+		i = k
+	}
+
+	return chunks
+}
+
+// trimmed is text[start:end] without surrounding whitespace.
+func trimmed(text string, start int, end int) (Chunk, bool) {
+	for start < end && isSpace(text[start]) {
+		start++
+	}
+	for end > start && isSpace(text[end-1]) {
+		end--
+	}
+	return Chunk{Text: text[start:end], Start: start, End: end}, end > start
+}
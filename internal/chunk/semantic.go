@@ -0,0 +1,91 @@
+package chunk
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/config"
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/embed"
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/vecstore"
+)
+
+// Semantic embeds every sentence and starts a new chunk where the cosine
+// distance between adjacent sentences is above the given percentile of all
+// adjacent distances (a topic shift), or when a chunk reaches Size sentences.
+type Semantic struct {
+	opts Options
+}
+
+func (s *Semantic) Name() string {
+	return fmt.Sprintf("semantic(percentile=%g,max=%d,model=%s)", s.opts.Percentile, s.opts.Size, s.opts.Embedder.Info().Name())
+}
+
+func (s *Semantic) Split(ctx context.Context, texts []string) ([][]Chunk, error) {
+	spans := make([][][]int, len(texts))
+	var sentences []string
+	for i, text := range texts {
+		spans[i] = SplitSentences(text)
+		for _, span := range spans[i] {
+			sentences = append(sentences, text[span[0]:span[1]])
+		}
+	}
+
+	cfg := config.Get().Embedding
+	vectors, _, err := embed.Parallel(ctx, s.opts.Embedder, sentences, cfg.Workers, cfg.MaxRetries, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to embed sentences: %w", err)
+	}
+	for i := range vectors {
+		vectors[i] = vecstore.Normalize(vectors[i])
+	}
+
+	// distances[i][j] is between sentence j and j+1 of text i
+	distances := make([][]float64, len(texts))
+	var all []float64
+	next := 0
+	for i := range texts {
+		for j := range spans[i] {
+			if j > 0 {
+				d := 1 - float64(vecstore.Dot(vectors[next+j-1], vectors[next+j]))
+				distances[i] = append(distances[i], d)
+				all = append(all, d)
+			}
+		}
+		next += len(spans[i])
+	}
+	threshold := percentile(all, s.opts.Percentile)
+
+	out := make([][]Chunk, len(texts))
+	for i, text := range texts {
+		var ranges [][2]int
+		start := 0
+		for j := 1; j < len(spans[i]); j++ {
+			if distances[i][j-1] > threshold || j-start >= s.opts.Size {
+				ranges = append(ranges, [2]int{start, j})
+				start = j
+			}
+		}
+		if len(spans[i]) > 0 {
+			ranges = append(ranges, [2]int{start, len(spans[i])})
+		}
+		out[i] = fromSpans(text, spans[i], ranges)
+	}
+
+	return out, nil
+}
+
+// percentile is the nearest-rank p-th percentile (+Inf for no values, so
+// nothing counts as a breakpoint).
+func percentile(values []float64, p float64) float64 {
+	if len(values) == 0 {
+		return math.Inf(1)
+	}
+
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+
+	rank := int(math.Ceil(p/100*float64(len(sorted)))) - 1
+	return sorted[min(max(rank, 0), len(sorted)-1)]
+}
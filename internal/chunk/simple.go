@@ -0,0 +1,52 @@
+package chunk
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+)
+
+var (
+	wordPattern = regexp.MustCompile(`\S+`)
+	// Words and single punctuation marks, close to how subword tokenizers
+	// count (a token budget is usually a bit under the model's limit).
+	tokenPattern = regexp.MustCompile(`[\p{L}\p{N}_]+|[^\p{L}\p{N}_\s]`)
+)
+
+// Sentences groups a fixed number of sentences per chunk.
+type Sentences struct {
+	opts Options
+}
+
+func (s *Sentences) Name() string {
+	return fmt.Sprintf("sentences(max=%d,overlap=%d)", s.opts.Size, s.opts.Overlap)
+}
+
+func (s *Sentences) Split(ctx context.Context, texts []string) ([][]Chunk, error) {
+	out := make([][]Chunk, len(texts))
+	for i, text := range texts {
+		spans := SplitSentences(text)
+		out[i] = fromSpans(text, spans, windows(len(spans), s.opts.Size, s.opts.Overlap))
+	}
+	return out, nil
+}
+
+// Windows cuts fixed windows of words or tokens.
+type Windows struct {
+	opts    Options
+	unit    string
+	pattern *regexp.Regexp
+}
+
+func (w *Windows) Name() string {
+	return fmt.Sprintf("%s(size=%d,overlap=%d)", w.unit, w.opts.Size, w.opts.Overlap)
+}
+
+func (w *Windows) Split(ctx context.Context, texts []string) ([][]Chunk, error) {
+	out := make([][]Chunk, len(texts))
+	for i, text := range texts {
+		spans := w.pattern.FindAllStringIndex(text, -1)
+		out[i] = fromSpans(text, spans, windows(len(spans), w.opts.Size, w.opts.Overlap))
+	}
+	return out, nil
+}
@@ -0,0 +1,28 @@
+package cli
+
+import "github.com/spf13/cobra"
+
+// The Resolve helpers implement the last layer of the config precedence:
+// a flag explicitly set on the command line wins, otherwise the configured
+// value (file/env/default) is used.
+
+func ResolveInt(cmd *cobra.Command, flagName string, value int, configured int) int {
+	if cmd.Flags().Changed(flagName) {
+		return value
+	}
+	return configured
+}
+
+func ResolveFloat(cmd *cobra.Command, flagName string, value float64, configured float64) float64 {
+	if cmd.Flags().Changed(flagName) {
+		return value
+	}
+	return configured
+}
+
+func ResolveString(cmd *cobra.Command, flagName string, value string, configured string) string {
+	if cmd.Flags().Changed(flagName) {
+		return value
+	}
+	return configured
+}
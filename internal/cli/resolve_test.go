@@ -0,0 +1,65 @@
+package cli
+
+import (
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestResolve(t *testing.T) {
+	tests := []struct {
+		name      string
+		args      []string
+		wantInt   int
+		wantFloat float64
+		wantStr   string
+	}{
+		{name: "configured when no flag is set", wantInt: 40, wantFloat: 0.7, wantStr: "minmax"},
+		{name: "flags win", args: []string{"--k=5", "--alpha=0.2", "--norm=zscore"}, wantInt: 5, wantFloat: 0.2, wantStr: "zscore"},
+		// A flag set to its default value still counts as set
+		{name: "explicit default", args: []string{"--k=60"}, wantInt: 60, wantFloat: 0.7, wantStr: "minmax"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				k     int
+				alpha float64
+				norm  string
+			)
+			cmd := &cobra.Command{Use: "test"}
+			cmd.Flags().IntVar(&k, "k", 60, "")
+			cmd.Flags().Float64Var(&alpha, "alpha", 0.5, "")
+			cmd.Flags().StringVar(&norm, "norm", "dbsf", "")
+			if err := cmd.ParseFlags(tt.args); err != nil {
+				t.Fatal(err)
+			}
+
+			if got := ResolveInt(cmd, "k", k, 40); got != tt.wantInt {
+				t.Errorf("ResolveInt = %d, want %d", got, tt.wantInt)
+			}
+			if got := ResolveFloat(cmd, "alpha", alpha, 0.7); got != tt.wantFloat {
+				t.Errorf("ResolveFloat = %v, want %v", got, tt.wantFloat)
+			}
+			if got := ResolveString(cmd, "norm", norm, "minmax"); got != tt.wantStr {
+				t.Errorf("ResolveString = %q, want %q", got, tt.wantStr)
+			}
+		})
+	}
+}
+
+func TestValidateFlagEnum(t *testing.T) {
+	tests := []struct {
+		value   string
+		wantErr bool
+	}{
+		{"", false},
+		{"rrf", false},
+		{"RRF", true},
+		{"borda", true},
+	}
+	for _, tt := range tests {
+		if err := ValidateFlagEnum(tt.value, "method", "rrf", "rbf"); (err != nil) != tt.wantErr {
+			t.Errorf("ValidateFlagEnum(%q) = %v, want error: %v", tt.value, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,235 @@
+package collection
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/fs"
+)
+
+// AnnotationCreates marks commands allowed to run against a collection that
+// doesn't exist yet (they create it).
+const AnnotationCreates = "hoopla/creates-collection"
+
+// AnnotationManages marks a command tree that manages collections itself, so
+// the active collection doesn't have to exist for any command in it.
+const AnnotationManages = "hoopla/manages-collections"
+
+const infoFile = "collection.json"
+
+var validName = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_-]*$`)
+
+type Info struct {
+	Name      string    `json:"name"`
+	Source    string    `json:"source,omitempty"`
+	Format    string    `json:"format,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Summary is what `collections list` prints for every collection.
+type Summary struct {
+	Info
+	Documents int
+	Artifacts []string
+}
+
+func ValidateName(name string) error {
+	if !validName.MatchString(name) {
+		return fmt.Errorf("invalid collection name %q (use letters, digits, '-' and '_')", name)
+	}
+	return nil
+}
+
+func Exists(name string) bool {
+	_, err := os.Stat(filepath.Join(fs.CollectionDir(name), infoFile))
+	return err == nil
+}
+
+// Use selects the named collection for the rest of the process.
+func Use(name string, mustExist bool) error {
+	if err := ValidateName(name); err != nil {
+		return err
+	}
+	if mustExist && !Exists(name) {
+		return fmt.Errorf(
+			"collection %q does not exist (create it with `hoopla collections create` or `hoopla ingest`)",
+			name,
+		)
+	}
+
+	fs.UseCollection(name)
+	return nil
+}
+
+// Create makes the collection directory and its info file. A golden
+// dataset can be copied in so `evaluation` works against the collection.
+func Create(name string, goldenPath string) (*Info, error) {
+	if err := ValidateName(name); err != nil {
+		return nil, err
+	}
+	if Exists(name) {
+		return nil, fmt.Errorf("collection %q already exists", name)
+	}
+
+	dir := fs.CollectionDir(name)
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return nil, fmt.Errorf("failed to create collection dir: %w", err)
+	}
+
+	if goldenPath != "" {
+		if err := copyFile(goldenPath, filepath.Join(dir, "golden_dataset.json")); err != nil {
+			return nil, fmt.Errorf("failed to copy golden dataset: %w", err)
+		}
+	}
+
+	now := time.Now().UTC()
+	info := &Info{
+		Name:      name,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := saveInfo(info); err != nil {
+		return nil, err
+	}
+
+	return info, nil
+}
+
+// Touch records the latest ingested source, creating the collection on the fly.
+func Touch(name string, source string, format string) error {
+	info, err := Load(name)
+	if err != nil {
+		if info, err = Create(name, ""); err != nil {
+			return err
+		}
+	}
+
+	if abs, err := filepath.Abs(source); err == nil {
+		source = abs
+	}
+	info.Source = source
+	info.Format = format
+	info.UpdatedAt = time.Now().UTC()
+
+	return saveInfo(info)
+}
+
+func Load(name string) (*Info, error) {
+	raw, err := os.ReadFile(filepath.Join(fs.CollectionDir(name), infoFile))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read collection %q: %w", name, err)
+	}
+
+	var info Info
+	if err := json.Unmarshal(raw, &info); err != nil {
+		return nil, fmt.Errorf("failed parsing collection %q: %w", name, err)
+	}
+
+	return &info, nil
+}
+
+func Delete(name string) error {
+	if err := ValidateName(name); err != nil {
+		return err
+	}
+	if !Exists(name) {
+		return fmt.Errorf("collection %q does not exist", name)
+	}
+
+	return os.RemoveAll(fs.CollectionDir(name))
+}
+
+// List returns every collection under the cache dir, sorted by name.
+func List() ([]Summary, error) {
+	entries, err := os.ReadDir(fs.CollectionsDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []Summary{}, nil
+		}
+		return nil, err
+	}
+
+	summaries := make([]Summary, 0)
+	for _, entry := range entries {
+		if !entry.IsDir() || !Exists(entry.Name()) {
+			continue
+		}
+
+		info, err := Load(entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		summaries = append(summaries, summarize(*info))
+	}
+
+	sort.Slice(summaries, func(i, j int) bool {
+		return summaries[i].Name < summaries[j].Name
+	})
+
+	return summaries, nil
+}
+
+func summarize(info Info) Summary {
+	dir := fs.CollectionDir(info.Name)
+	summary := Summary{Info: info, Artifacts: []string{}}
+
+	if raw, err := os.ReadFile(filepath.Join(dir, "documents.json")); err == nil {
+		var data struct {
+			Movies []json.RawMessage `json:"movies"`
+		}
+		if json.Unmarshal(raw, &data) == nil {
+			summary.Documents = len(data.Movies)
+		}
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return summary
+	}
+	for _, entry := range entries {
+		if entry.IsDir() || entry.Name() == infoFile || entry.Name() == "documents.json" {
+			continue
+		}
+		summary.Artifacts = append(summary.Artifacts, entry.Name())
+	}
+
+	return summary
+}
+
+func saveInfo(info *Info) error {
+	raw, err := json.MarshalIndent(info, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	path := filepath.Join(fs.CollectionDir(info.Name), infoFile)
+	if err := os.WriteFile(path, raw, 0o644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+
+	return nil
+}
+
+func copyFile(src string, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	_, err = io.Copy(out, in)
+	return err
+}
@@ -0,0 +1,518 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// Precedence (lowest to highest):
+//  1. built-in defaults (Defaults)
+//  2. config file: --config, $HOOPLA_CONFIG or <project root>/hoopla.yaml
+//  3. environment variables: HOOPLA_<SECTION>_<KEY>, e.g. HOOPLA_HYBRID_RRF_K=40
+//  4. command-line flags (resolved per command through cli.Resolve*)
+
+const (
+	FileName  = "hoopla.yaml"
+	EnvPrefix = "HOOPLA_"
+	EnvConfig = "HOOPLA_CONFIG"
+)
+
+type Config struct {
+	Collection  string            `yaml:"collection"`
+	Offline     bool              `yaml:"offline"` // hash embedder + mock LLM, no network
+	Paths       PathsConfig       `yaml:"paths"`
+	Search      SearchConfig      `yaml:"search"`
+	Keyword     KeywordConfig     `yaml:"keyword"`
+	Embedding   EmbeddingConfig   `yaml:"embedding"`
+	Chunking    ChunkingConfig    `yaml:"chunking"`
+	Hybrid      HybridConfig      `yaml:"hybrid"`
+	Rerank      RerankConfig      `yaml:"rerank"`
+	RAG         RAGConfig         `yaml:"rag"`
+	LLM         LLMConfig         `yaml:"llm"`
+	Multimodal  MultimodalConfig  `yaml:"multimodal"`
+	ANN         ANNConfig         `yaml:"ann"`
+	Quantize    QuantizeConfig    `yaml:"quantization"`
+	Aggregation AggregationConfig `yaml:"aggregation"`
+	MMR         MMRConfig         `yaml:"mmr"`
+	Fusion      FusionConfig      `yaml:"fusion"`
+	Cache       CacheConfig       `yaml:"cache"`
+}
+
+type PathsConfig struct {
+	DataDir  string `yaml:"data_dir"`
+	CacheDir string `yaml:"cache_dir"`
+}
+
+type SearchConfig struct {
+	Limit    int `yaml:"limit"`
+	Passages int `yaml:"passages"` // best-matching chunks kept per chunked/hybrid result
+	Workers  int `yaml:"workers"`  // goroutines for exact vector scans; 0 = all CPUs
+	// Semantic search embeds these fields separately and scores the
+	// weighted mean of their similarities, e.g. "title=0.3,description=0.7"
+	// (fields: title, description, keywords); empty = one "title:
+	// description" embedding
+	FieldWeights string `yaml:"field_weights"`
+}
+
+type KeywordConfig struct {
+	K1      float64 `yaml:"k1"`
+	B       float64 `yaml:"b"`
+	Workers int     `yaml:"workers"` // 0 = one per CPU
+	// RM3 pseudo-relevance feedback (the rm3 retriever): expansion terms
+	// are taken from the top documents of a first BM25 pass
+	RM3FeedbackDocs   int     `yaml:"rm3_feedback_docs"`
+	RM3FeedbackTerms  int     `yaml:"rm3_feedback_terms"`
+	RM3OriginalWeight float64 `yaml:"rm3_original_weight"` // weight of the query terms vs the expansion, 0..1
+}
+
+type EmbeddingConfig struct {
+	Provider  string `yaml:"provider"` // ollama | gemini | openai (any OpenAI-compatible server)
+	Model     string `yaml:"model"`
+	BaseURL   string `yaml:"base_url"`    // empty = $OLLAMA_HOST / https://api.openai.com/v1
+	APIKeyEnv string `yaml:"api_key_env"` // env var holding the API key; empty = GEMINI_API_KEY / OPENAI_API_KEY
+	BatchSize int    `yaml:"batch_size"`  // texts per request; 0 = provider default
+	Dimension int    `yaml:"dimension"`   // hash provider only; 0 = 384
+	// Upper bound on concurrent requests (0 = one per CPU); the actual
+	// concurrency adapts to the server's latency and 429/5xx responses
+	Workers    int `yaml:"workers"`
+	MaxRetries int `yaml:"max_retries"` // per batch, with jittered exponential backoff
+	// Finished vectors are checkpointed to disk every N texts so an
+	// interrupted build can continue with --resume
+	CheckpointEvery int `yaml:"checkpoint_every"`
+	// Reuse vectors of texts embedded before (any collection), keyed by
+	// model and text hash; see `semantic cache`
+	Cache bool `yaml:"cache"`
+	// Search queries embedded before are reused from an LRU cache of this
+	// many entries per model, shared by every command; 0 = off
+	QueryCache int `yaml:"query_cache"`
+}
+
+type ChunkingConfig struct {
+	// Splitter used for the chunk embeddings: sentences | words | tokens | recursive | semantic
+	Strategy      string `yaml:"strategy"`
+	MaxChunkSize  int    `yaml:"max_chunk_size"` // sentences per chunk
+	Overlap       int    `yaml:"overlap"`        // sentences shared by consecutive chunks
+	WordChunkSize int    `yaml:"word_chunk_size"`
+	WordOverlap   int    `yaml:"word_overlap"`
+
+	TokenChunkSize int `yaml:"token_chunk_size"`
+	TokenOverlap   int `yaml:"token_overlap"`
+	CharChunkSize  int `yaml:"char_chunk_size"` // recursive strategy, in characters (UTF-8 bytes)
+	CharOverlap    int `yaml:"char_overlap"`
+
+	// semantic strategy: a new chunk starts where the distance between
+	// adjacent sentences is above this percentile, or after max sentences
+	BreakpointPercentile   float64 `yaml:"breakpoint_percentile"`
+	BreakpointMaxSentences int     `yaml:"breakpoint_max_sentences"`
+
+	// Text embedded for each chunk: {title}, {chunk} and {context} (the
+	// situating sentence) are replaced. Passages still show the bare chunk.
+	ContextTemplate string `yaml:"context_template"`
+	// Ask the LLM for one sentence situating each chunk in its document
+	// (cached by content hash); prepended when the template has no {context}
+	Situate bool `yaml:"situate"`
+}
+
+type HybridConfig struct {
+	RRFK  int     `yaml:"rrf_k"`
+	Alpha float64 `yaml:"alpha"`
+	// Pick alpha per query (as --alpha auto) when --alpha isn't given
+	AutoAlpha bool `yaml:"auto_alpha"`
+	// --alpha auto: heuristic | llm
+	AlphaClassifier string `yaml:"alpha_classifier"`
+	// --alpha auto: alpha of each query class
+	AlphaExact      float64 `yaml:"alpha_exact"`
+	AlphaConceptual float64 `yaml:"alpha_conceptual"`
+	AlphaMixed      float64 `yaml:"alpha_mixed"`
+	// Each leg retrieves limit*CandidateMultiplier candidates before fusion
+	CandidateMultiplier int `yaml:"candidate_multiplier"`
+}
+
+type RerankConfig struct {
+	CohereModel string `yaml:"cohere_model"`
+	// Rerankers see limit*CandidateMultiplier fused results
+	CandidateMultiplier int `yaml:"candidate_multiplier"`
+}
+
+type RAGConfig struct {
+	RRFK          int    `yaml:"rrf_k"`
+	QuestionRRFK  int    `yaml:"question_rrf_k"`
+	Context       string `yaml:"context"`        // chunk | window | parent
+	ContextWindow int    `yaml:"context_window"` // neighbours on each side of a matching chunk (window)
+}
+
+type LLMConfig struct {
+	Provider string `yaml:"provider"` // gemini | mock
+	Model    string `yaml:"model"`
+	// YAML list of {match: <regexp>, response: <text>} canned responses for the mock provider
+	MockScript string `yaml:"mock_script"`
+}
+
+// ANNConfig holds the HNSW parameters used by `semantic buildANN` and --ann
+type ANNConfig struct {
+	M              int `yaml:"m"`               // links per node
+	EfConstruction int `yaml:"ef_construction"` // candidate list size while building
+	EfSearch       int `yaml:"ef_search"`       // candidate list size while querying
+}
+
+// QuantizeConfig selects how document/chunk embeddings are scored at search time
+type QuantizeConfig struct {
+	Storage string `yaml:"storage"` // float64 | int8 | pq
+	// Quantized searches re-rank limit*Rescore candidates with the
+	// full-precision vectors; 0 keeps the approximate scores
+	Rescore      int `yaml:"rescore"`
+	PQSubvectors int `yaml:"pq_subvectors"` // 0 = one per 8 dimensions
+	PQIterations int `yaml:"pq_iterations"`
+}
+
+// AggregationConfig controls how chunk scores become a document score
+type AggregationConfig struct {
+	Method      string  `yaml:"method"`      // max | topMean | decaySum | softmax | maxMean
+	TopN        int     `yaml:"top_n"`       // topMean
+	Decay       float64 `yaml:"decay"`       // decaySum
+	Temperature float64 `yaml:"temperature"` // softmax
+	Blend       float64 `yaml:"blend"`       // maxMean: weight of the max
+}
+
+type MMRConfig struct {
+	// Maximal Marginal Relevance trade-off when --diversify isn't given:
+	// 1 = relevance only, lower = more variety; 0 = off
+	Lambda float64 `yaml:"lambda"`
+	// Candidates re-ranked per result kept
+	CandidateMultiplier int `yaml:"candidate_multiplier"`
+}
+
+type FusionConfig struct {
+	// Retrievers merged by `hybrid fuse`, as name=weight[@depth]: e.g.
+	// "bm25=1,chunked=1,rm3=0.5@100" (bm25 | rm3 | semantic | chunked |
+	// hyde). depth is the candidates retrieved; 0 or none =
+	// limit*hybrid.candidate_multiplier
+	Retrievers string `yaml:"retrievers"`
+	// How rankings are merged: rrf | rbf | combsum | combmnz
+	Method string `yaml:"method"`
+	K      int    `yaml:"k"` // rrf constant
+	// rbf: chance of looking past each rank (higher = deeper ranks count more)
+	RBFPersistence float64 `yaml:"rbf_persistence"`
+	// combsum/combmnz score normalization per retriever: minmax | zscore | dbsf
+	Normalization string `yaml:"normalization"`
+}
+
+type CacheConfig struct {
+	// What to do when an artifact's manifest doesn't match the current
+	// documents/settings: warn, rebuild or fail (--strict)
+	OnStale string `yaml:"on_stale"`
+}
+
+type MultimodalConfig struct {
+	Provider       string `yaml:"provider"`
+	EmbeddingModel string `yaml:"embedding_model"`
+	BaseURL        string `yaml:"base_url"`
+	APIKeyEnv      string `yaml:"api_key_env"`
+	BatchSize      int    `yaml:"batch_size"`
+	Limit          int    `yaml:"limit"`
+}
+
+func Defaults() Config {
+	return Config{
+		Paths: PathsConfig{
+			DataDir:  "data",
+			CacheDir: "cache",
+		},
+		Search: SearchConfig{
+			Limit:    5,
+			Passages: 2,
+		},
+		Keyword: KeywordConfig{
+			K1:                1.5,
+			B:                 0.75,
+			RM3FeedbackDocs:   10,
+			RM3FeedbackTerms:  10,
+			RM3OriginalWeight: 0.5,
+		},
+		Embedding: EmbeddingConfig{
+			Provider:        "ollama",
+			Model:           "nomic-embed-text",
+			MaxRetries:      4,
+			CheckpointEvery: 1000,
+			Cache:           true,
+			QueryCache:      1000,
+		},
+		Chunking: ChunkingConfig{
+			Strategy:               "sentences",
+			MaxChunkSize:           4,
+			Overlap:                1,
+			WordChunkSize:          200,
+			TokenChunkSize:         256,
+			TokenOverlap:           32,
+			CharChunkSize:          1000,
+			CharOverlap:            100,
+			BreakpointPercentile:   90,
+			BreakpointMaxSentences: 8,
+			ContextTemplate:        "{chunk}",
+		},
+		Hybrid: HybridConfig{
+			RRFK:                60,
+			Alpha:               0.5,
+			AlphaClassifier:     "heuristic",
+			AlphaExact:          0.8,
+			AlphaConceptual:     0.2,
+			AlphaMixed:          0.5,
+			CandidateMultiplier: 500,
+		},
+		Rerank: RerankConfig{
+			CohereModel:         "rerank-english-v3.0",
+			CandidateMultiplier: 5,
+		},
+		RAG: RAGConfig{
+			RRFK:          60,
+			QuestionRRFK:  10,
+			Context:       "chunk",
+			ContextWindow: 1,
+		},
+		LLM: LLMConfig{
+			Provider: "gemini",
+			Model:    "gemini-3-flash-preview",
+		},
+		Multimodal: MultimodalConfig{
+			Provider:       "gemini",
+			EmbeddingModel: "models/embedding-001",
+			BatchSize:      100,
+			Limit:          5,
+		},
+		ANN: ANNConfig{
+			M:              16,
+			EfConstruction: 200,
+			EfSearch:       64,
+		},
+		Quantize: QuantizeConfig{
+			Storage:      "float32",
+			Rescore:      4,
+			PQIterations: 10,
+		},
+		Aggregation: AggregationConfig{
+			Method:      "max",
+			TopN:        3,
+			Decay:       0.5,
+			Temperature: 0.05,
+			Blend:       0.7,
+		},
+		MMR: MMRConfig{
+			CandidateMultiplier: 4,
+		},
+		Fusion: FusionConfig{
+			Retrievers:     "bm25=1,chunked=1",
+			Method:         "rrf",
+			K:              60,
+			RBFPersistence: 0.8,
+			Normalization:  "minmax",
+		},
+		Cache: CacheConfig{
+			OnStale: "rebuild",
+		},
+	}
+}
+
+var (
+	current = Defaults()
+	file    string
+	origins = map[string]string{}
+)
+
+// Get returns the resolved configuration (defaults until Load is called).
+func Get() *Config {
+	return &current
+}
+
+// File returns the config file that was loaded, if any.
+func File() string {
+	return file
+}
+
+// Origin tells where a dotted key (e.g. "hybrid.rrf_k") got its value from.
+func Origin(key string) string {
+	if o, ok := origins[key]; ok {
+		return o
+	}
+	return "default"
+}
+
+// SetOrigin records a value that was overridden after Load (global flags).
+func SetOrigin(key string, origin string) {
+	origins[key] = origin
+}
+
+// Load resolves defaults, the config file and environment variables.
+// An explicit path must exist; the implicit project hoopla.yaml is optional.
+func Load(path string, projectRoot string) error {
+	cfg := Defaults()
+	origins = map[string]string{}
+	file = ""
+
+	explicit := path != ""
+	if !explicit {
+		path = os.Getenv(EnvConfig)
+		explicit = path != ""
+	}
+	if !explicit {
+		path = filepath.Join(projectRoot, FileName)
+	}
+
+	raw, err := os.ReadFile(path)
+	switch {
+	case err == nil:
+		// Unknown keys are errors, so a misspelled key doesn't silently
+		// fall back to its default
+		dec := yaml.NewDecoder(bytes.NewReader(raw))
+		dec.KnownFields(true)
+		if err := dec.Decode(&cfg); err != nil && err != io.EOF {
+			return fmt.Errorf("failed parsing %s: %w", path, err)
+		}
+		var keys map[string]any
+		if err := yaml.Unmarshal(raw, &keys); err == nil {
+			for _, k := range flattenKeys("", keys) {
+				origins[k] = "file"
+			}
+		}
+		file = path
+	case explicit || !os.IsNotExist(err):
+		return fmt.Errorf("failed to read config %s: %w", path, err)
+	}
+
+	if err := applyEnv(reflect.ValueOf(&cfg).Elem(), ""); err != nil {
+		return err
+	}
+
+	current = cfg
+	return nil
+}
+
+func flattenKeys(prefix string, m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for k, v := range m {
+		key := k
+		if prefix != "" {
+			key = prefix + "." + k
+		}
+		if nested, ok := v.(map[string]any); ok {
+			keys = append(keys, flattenKeys(key, nested)...)
+			continue
+		}
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// applyEnv walks the config struct and overrides every leaf that has a
+// matching HOOPLA_* variable. Names come from the yaml tags.
+func applyEnv(v reflect.Value, prefix string) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := strings.Split(field.Tag.Get("yaml"), ",")[0]
+		if tag == "" || tag == "-" {
+			continue
+		}
+		key := tag
+		if prefix != "" {
+			key = prefix + "." + tag
+		}
+
+		fv := v.Field(i)
+		if fv.Kind() == reflect.Struct {
+			if err := applyEnv(fv, key); err != nil {
+				return err
+			}
+			continue
+		}
+
+		name := EnvName(key)
+		raw, ok := os.LookupEnv(name)
+		if !ok {
+			continue
+		}
+		if err := setValue(fv, raw); err != nil {
+			return fmt.Errorf("invalid value for %s: %w", name, err)
+		}
+		origins[key] = "env " + name
+	}
+	return nil
+}
+
+// EnvName maps a dotted key to its environment variable.
+func EnvName(key string) string {
+	return EnvPrefix + strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+}
+
+func setValue(fv reflect.Value, raw string) error {
+	switch fv.Kind() {
+	case reflect.String:
+		fv.SetString(raw)
+	case reflect.Int:
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			return err
+		}
+		fv.SetInt(int64(n))
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return err
+		}
+		fv.SetFloat(f)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return err
+		}
+		fv.SetBool(b)
+	default:
+		return fmt.Errorf("unsupported kind %s", fv.Kind())
+	}
+	return nil
+}
+
+// Keys lists every dotted key of the configuration, sorted.
+func Keys() []string {
+	var keys []string
+	var walk func(t reflect.Type, prefix string)
+	walk = func(t reflect.Type, prefix string) {
+		for i := 0; i < t.NumField(); i++ {
+			tag := strings.Split(t.Field(i).Tag.Get("yaml"), ",")[0]
+			if tag == "" || tag == "-" {
+				continue
+			}
+			key := tag
+			if prefix != "" {
+				key = prefix + "." + tag
+			}
+			if t.Field(i).Type.Kind() == reflect.Struct {
+				walk(t.Field(i).Type, key)
+				continue
+			}
+			keys = append(keys, key)
+		}
+	}
+	walk(reflect.TypeOf(Config{}), "")
+	sort.Strings(keys)
+	return keys
+}
+
+// Marshal renders the resolved configuration as YAML.
+func Marshal() ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(current); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
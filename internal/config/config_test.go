@@ -0,0 +1,119 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// useConfig writes content as a config file and restores the defaults
+// after the test.
+func useConfig(t *testing.T, content string) string {
+	t.Helper()
+	t.Cleanup(func() {
+		current = Defaults()
+		origins = map[string]string{}
+		file = ""
+	})
+	path := filepath.Join(t.TempDir(), FileName)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	path := useConfig(t, "search:\n  limit: 7\nhybrid:\n  rrf_k: 40\n  alpha: 0.8\n")
+	t.Setenv(EnvConfig, "")
+	t.Setenv("HOOPLA_HYBRID_RRF_K", "25")
+	t.Setenv("HOOPLA_KEYWORD_K1", "1.2")
+
+	if err := Load(path, ""); err != nil {
+		t.Fatal(err)
+	}
+	cfg := Get()
+	defaults := Defaults()
+
+	tests := []struct {
+		key        string
+		got, want  any
+		wantOrigin string
+	}{
+		{"search.limit", cfg.Search.Limit, 7, "file"},
+		{"hybrid.alpha", cfg.Hybrid.Alpha, 0.8, "file"},
+		{"hybrid.rrf_k", cfg.Hybrid.RRFK, 25, "env HOOPLA_HYBRID_RRF_K"},
+		{"keyword.k1", cfg.Keyword.K1, 1.2, "env HOOPLA_KEYWORD_K1"},
+		{"keyword.b", cfg.Keyword.B, defaults.Keyword.B, "default"},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s = %v, want %v", tt.key, tt.got, tt.want)
+		}
+		if origin := Origin(tt.key); origin != tt.wantOrigin {
+			t.Errorf("origin of %s = %q, want %q", tt.key, origin, tt.wantOrigin)
+		}
+	}
+	if File() != path {
+		t.Errorf("File() = %q, want %q", File(), path)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		env     map[string]string
+		wantErr string
+	}{
+		{name: "unknown key", content: "search:\n  limt: 3\n", wantErr: "limt"},
+		{name: "wrong type", content: "search:\n  limit: many\n", wantErr: "failed parsing"},
+		{name: "invalid env value", content: "", env: map[string]string{"HOOPLA_SEARCH_LIMIT": "ten"}, wantErr: "HOOPLA_SEARCH_LIMIT"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := useConfig(t, tt.content)
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			err := Load(path, "")
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Load error = %v, want one mentioning %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadMissingFile(t *testing.T) {
+	useConfig(t, "")
+	t.Setenv(EnvConfig, "")
+	dir := t.TempDir()
+
+	// The project hoopla.yaml is optional...
+	if err := Load("", dir); err != nil {
+		t.Errorf("implicit config: %v", err)
+	}
+	if Get().Search.Limit != Defaults().Search.Limit {
+		t.Errorf("search.limit = %d, want the default", Get().Search.Limit)
+	}
+	// ...an explicit one, from the flag or $HOOPLA_CONFIG, isn't
+	if err := Load(filepath.Join(dir, "missing.yaml"), dir); err == nil {
+		t.Error("explicit missing config: expected an error")
+	}
+	t.Setenv(EnvConfig, filepath.Join(dir, "missing.yaml"))
+	if err := Load("", dir); err == nil {
+		t.Error("missing $HOOPLA_CONFIG: expected an error")
+	}
+}
+
+func TestEnvName(t *testing.T) {
+	tests := map[string]string{
+		"collection":   "HOOPLA_COLLECTION",
+		"hybrid.rrf_k": "HOOPLA_HYBRID_RRF_K",
+	}
+	for key, want := range tests {
+		if got := EnvName(key); got != want {
+			t.Errorf("EnvName(%q) = %q, want %q", key, got, want)
+		}
+	}
+}
@@ -0,0 +1,271 @@
+package embed
+
+import (
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/fs"
+)
+
+var unsafeChars = regexp.MustCompile(`[^a-zA-Z0-9._-]+`)
+
+// Cache is a persistent content-addressed store of embeddings for one
+// model: vectors are keyed by a hash of the embedded text, so documents
+// and chunks that didn't change are never sent to the embedder again.
+type Cache struct {
+	Path  string
+	Model string
+	// Capacity bounds the entries kept on Save, dropping the least recently
+	// used first; 0 = unbounded
+	Capacity int
+
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+	dirty   bool
+}
+
+type cacheEntry struct {
+	Vector   []float32
+	LastUsed int64 // unix seconds, used by PruneCaches
+}
+
+type cacheFile struct {
+	Model   string
+	Entries map[string]cacheEntry
+}
+
+// CacheInfo is what `semantic cache stats` prints for every model.
+type CacheInfo struct {
+	Model     string
+	Path      string
+	Entries   int
+	Dimension int
+	Bytes     int64
+	Oldest    time.Time
+	Newest    time.Time
+}
+
+// TextHash is the cache key of a text.
+func TextHash(text string) string {
+	sum := sha256.Sum256([]byte(text))
+	return hex.EncodeToString(sum[:])
+}
+
+// CachePath is the cache file for model (an Info.Name()).
+func CachePath(model string) string {
+	return cachePath(fs.EmbeddingCacheDir, model)
+}
+
+func cachePath(dir string, model string) string {
+	sum := sha256.Sum256([]byte(model))
+	safe := strings.Trim(unsafeChars.ReplaceAllString(model, "_"), "_")
+	return filepath.Join(dir, fmt.Sprintf("%s-%s.gob", safe, hex.EncodeToString(sum[:])[:8]))
+}
+
+// OpenCache loads the cache for model; a missing file is an empty cache.
+func OpenCache(model string) (*Cache, error) {
+	return openCacheFile(CachePath(model), model)
+}
+
+func openCacheFile(path string, model string) (*Cache, error) {
+	c := &Cache{Path: path, Model: model, entries: make(map[string]cacheEntry)}
+
+	data, err := readCacheFile(c.Path)
+	if os.IsNotExist(err) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read embedding cache %s: %w", c.Path, err)
+	}
+	if data.Entries != nil {
+		c.entries = data.Entries
+	}
+
+	return c, nil
+}
+
+func readCacheFile(path string) (cacheFile, error) {
+	var data cacheFile
+
+	f, err := os.Open(path)
+	if err != nil {
+		return data, err
+	}
+	defer f.Close()
+
+	err = gob.NewDecoder(f).Decode(&data)
+	return data, err
+}
+
+// Get returns the cached vector for text and marks it as used. Vectors of
+// another dimension than dim (0 = any) are misses: the model changed behind
+// the same name, and Put will replace them.
+func (c *Cache) Get(text string, dim int) ([]float32, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := TextHash(text)
+	entry, ok := c.entries[key]
+	if !ok || (dim != 0 && len(entry.Vector) != dim) {
+		return nil, false
+	}
+	entry.LastUsed = time.Now().Unix()
+	c.entries[key] = entry
+	c.dirty = true
+
+	return entry.Vector, true
+}
+
+// Put stores the vector of text. Safe for concurrent use.
+func (c *Cache) Put(text string, vector []float32) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries[TextHash(text)] = cacheEntry{Vector: vector, LastUsed: time.Now().Unix()}
+	c.dirty = true
+}
+
+func (c *Cache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries)
+}
+
+// Save writes the cache if anything changed since it was opened.
+func (c *Cache) Save() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.dirty {
+		return nil
+	}
+	c.evict()
+	if err := os.MkdirAll(filepath.Dir(c.Path), os.ModePerm); err != nil {
+		return fmt.Errorf("failed to create embedding cache dir: %w", err)
+	}
+
+	err := fs.WriteAtomic(c.Path, func(w io.Writer) error {
+		return gob.NewEncoder(w).Encode(cacheFile{Model: c.Model, Entries: c.entries})
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write embedding cache: %w", err)
+	}
+
+	c.dirty = false
+	return nil
+}
+
+// evict drops the least recently used entries over Capacity.
+func (c *Cache) evict() {
+	if c.Capacity <= 0 || len(c.entries) <= c.Capacity {
+		return
+	}
+	keys := make([]string, 0, len(c.entries))
+	for key := range c.entries {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return c.entries[keys[i]].LastUsed < c.entries[keys[j]].LastUsed
+	})
+	for _, key := range keys[:len(keys)-c.Capacity] {
+		delete(c.entries, key)
+	}
+}
+
+// ListCaches describes every model cache on disk, sorted by model.
+func ListCaches() ([]CacheInfo, error) {
+	return listCaches(fs.EmbeddingCacheDir)
+}
+
+func listCaches(dir string) ([]CacheInfo, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.gob"))
+	if err != nil {
+		return nil, err
+	}
+
+	infos := make([]CacheInfo, 0, len(files))
+	for _, path := range files {
+		data, err := readCacheFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read embedding cache %s: %w", path, err)
+		}
+
+		info := CacheInfo{Model: data.Model, Path: path, Entries: len(data.Entries)}
+		if stat, err := os.Stat(path); err == nil {
+			info.Bytes = stat.Size()
+		}
+		for _, entry := range data.Entries {
+			info.Dimension = max(info.Dimension, len(entry.Vector))
+			used := time.Unix(entry.LastUsed, 0)
+			if info.Oldest.IsZero() || used.Before(info.Oldest) {
+				info.Oldest = used
+			}
+			if used.After(info.Newest) {
+				info.Newest = used
+			}
+		}
+		infos = append(infos, info)
+	}
+
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Model < infos[j].Model })
+	return infos, nil
+}
+
+// PruneCaches drops entries not used for olderThan (0 keeps them all) and,
+// unless keep is empty, the whole cache of every model not in keep, from
+// both the document and the query caches. It returns the number of entries
+// removed.
+func PruneCaches(olderThan time.Duration, keep []string) (int, error) {
+	infos, err := ListCaches()
+	if err != nil {
+		return 0, err
+	}
+	queryInfos, err := ListQueryCaches()
+	if err != nil {
+		return 0, err
+	}
+	infos = append(infos, queryInfos...)
+
+	removed := 0
+	cutoff := time.Now().Add(-olderThan).Unix()
+	for _, info := range infos {
+		if len(keep) > 0 && !slices.Contains(keep, info.Model) {
+			if err := os.Remove(info.Path); err != nil {
+				return removed, err
+			}
+			removed += info.Entries
+			continue
+		}
+		if olderThan <= 0 {
+			continue
+		}
+
+		c, err := openCacheFile(info.Path, info.Model)
+		if err != nil {
+			return removed, err
+		}
+		for key, entry := range c.entries {
+			if entry.LastUsed < cutoff {
+				delete(c.entries, key)
+				c.dirty = true
+				removed++
+			}
+		}
+		if err := c.Save(); err != nil {
+			return removed, err
+		}
+	}
+
+	return removed, nil
+}
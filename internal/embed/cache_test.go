@@ -0,0 +1,251 @@
+package embed
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/fs"
+)
+
+// useCacheDirs points the embedding and query caches at a temporary dir
+// for the duration of the test.
+func useCacheDirs(t *testing.T) {
+	t.Helper()
+	embeddings, queries := fs.EmbeddingCacheDir, fs.QueryCacheDir
+	fs.EmbeddingCacheDir = t.TempDir()
+	fs.QueryCacheDir = t.TempDir()
+	t.Cleanup(func() {
+		fs.EmbeddingCacheDir, fs.QueryCacheDir = embeddings, queries
+	})
+}
+
+// age marks the entry of text as last used ago.
+func age(c *Cache, text string, ago time.Duration) {
+	key := TextHash(text)
+	entry := c.entries[key]
+	entry.LastUsed = time.Now().Add(-ago).Unix()
+	c.entries[key] = entry
+	c.dirty = true
+}
+
+func TestCacheGet(t *testing.T) {
+	useCacheDirs(t)
+
+	c, err := OpenCache("test-model")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Put("hello", []float32{1, 2, 3})
+
+	tests := []struct {
+		name string
+		text string
+		dim  int
+		hit  bool
+	}{
+		{name: "hit", text: "hello", dim: 3, hit: true},
+		{name: "any dimension", text: "hello", dim: 0, hit: true},
+		{name: "other dimension", text: "hello", dim: 4, hit: false},
+		{name: "unknown text", text: "goodbye", dim: 3, hit: false},
+		{name: "text is not normalized", text: "Hello", dim: 3, hit: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vector, ok := c.Get(tt.text, tt.dim)
+			if ok != tt.hit {
+				t.Fatalf("Get(%q, %d) hit = %v, want %v", tt.text, tt.dim, ok, tt.hit)
+			}
+			if ok && len(vector) != 3 {
+				t.Errorf("Get(%q, %d) = %v", tt.text, tt.dim, vector)
+			}
+		})
+	}
+}
+
+func TestCacheSave(t *testing.T) {
+	useCacheDirs(t)
+
+	c, err := OpenCache("test-model")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(c.Path); !os.IsNotExist(err) {
+		t.Fatalf("Save of an unchanged cache wrote %s", c.Path)
+	}
+
+	c.Put("a", []float32{1, 0})
+	c.Put("b", []float32{0, 1})
+	if err := c.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	reopened, err := OpenCache("test-model")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reopened.Len() != 2 {
+		t.Fatalf("reopened cache has %d entries, want 2", reopened.Len())
+	}
+	if vector, ok := reopened.Get("b", 2); !ok || vector[1] != 1 {
+		t.Errorf("Get(b) = %v, %v after reopening", vector, ok)
+	}
+
+	other, err := OpenCache("other-model")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other.Len() != 0 {
+		t.Errorf("other model sees %d entries, want 0", other.Len())
+	}
+}
+
+func TestCacheCapacity(t *testing.T) {
+	tests := []struct {
+		name     string
+		capacity int
+		want     []string
+	}{
+		{name: "unbounded", capacity: 0, want: []string{"old", "mid", "new"}},
+		{name: "under capacity", capacity: 5, want: []string{"old", "mid", "new"}},
+		{name: "drops least recently used", capacity: 2, want: []string{"mid", "new"}},
+		{name: "keeps newest", capacity: 1, want: []string{"new"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useCacheDirs(t)
+
+			c, err := OpenCache("test-model")
+			if err != nil {
+				t.Fatal(err)
+			}
+			c.Capacity = tt.capacity
+			for _, text := range []string{"old", "mid", "new"} {
+				c.Put(text, []float32{1})
+			}
+			age(c, "old", 3*time.Hour)
+			age(c, "mid", 2*time.Hour)
+			age(c, "new", time.Hour)
+			if err := c.Save(); err != nil {
+				t.Fatal(err)
+			}
+
+			reopened, err := OpenCache("test-model")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if reopened.Len() != len(tt.want) {
+				t.Errorf("kept %d entries, want %d", reopened.Len(), len(tt.want))
+			}
+			for _, text := range tt.want {
+				if _, ok := reopened.Get(text, 0); !ok {
+					t.Errorf("%q was evicted", text)
+				}
+			}
+		})
+	}
+}
+
+func TestListCaches(t *testing.T) {
+	useCacheDirs(t)
+
+	infos, err := ListCaches()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(infos) != 0 {
+		t.Fatalf("empty dir lists %d caches", len(infos))
+	}
+
+	for model, dim := range map[string]int{"b-model": 3, "a-model": 2} {
+		c, err := OpenCache(model)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c.Put("x", make([]float32, dim))
+		c.Put("y", make([]float32, dim))
+		age(c, "x", time.Hour)
+		if err := c.Save(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	infos, err = ListCaches()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(infos) != 2 || infos[0].Model != "a-model" || infos[1].Model != "b-model" {
+		t.Fatalf("ListCaches = %+v, want a-model and b-model", infos)
+	}
+	for i, want := range []int{2, 3} {
+		info := infos[i]
+		if info.Entries != 2 || info.Dimension != want || info.Bytes == 0 {
+			t.Errorf("%s: entries %d dimension %d bytes %d", info.Model, info.Entries, info.Dimension, info.Bytes)
+		}
+		if !info.Oldest.Before(info.Newest) {
+			t.Errorf("%s: oldest %s not before newest %s", info.Model, info.Oldest, info.Newest)
+		}
+	}
+}
+
+func TestPruneCaches(t *testing.T) {
+	tests := []struct {
+		name        string
+		olderThan   time.Duration
+		keep        []string
+		wantRemoved int
+		wantLeft    map[string]int
+	}{
+		{name: "nothing", wantRemoved: 0, wantLeft: map[string]int{"kept": 3, "dropped": 3}},
+		{name: "older than", olderThan: 90 * time.Minute, wantRemoved: 2, wantLeft: map[string]int{"kept": 2, "dropped": 2}},
+		{name: "other models", keep: []string{"kept"}, wantRemoved: 3, wantLeft: map[string]int{"kept": 3}},
+		{name: "both", olderThan: 30 * time.Minute, keep: []string{"kept"}, wantRemoved: 5, wantLeft: map[string]int{"kept": 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useCacheDirs(t)
+			for _, model := range []string{"kept", "dropped"} {
+				c, err := OpenCache(model)
+				if err != nil {
+					t.Fatal(err)
+				}
+				c.Put("fresh", []float32{1})
+				c.Put("hour", []float32{1})
+				c.Put("day", []float32{1})
+				age(c, "hour", time.Hour)
+				age(c, "day", 24*time.Hour)
+				if err := c.Save(); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			removed, err := PruneCaches(tt.olderThan, tt.keep)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if removed != tt.wantRemoved {
+				t.Errorf("removed %d entries, want %d", removed, tt.wantRemoved)
+			}
+
+			infos, err := ListCaches()
+			if err != nil {
+				t.Fatal(err)
+			}
+			left := make(map[string]int)
+			for _, info := range infos {
+				left[info.Model] = info.Entries
+			}
+			if len(left) != len(tt.wantLeft) {
+				t.Errorf("left %v, want %v", left, tt.wantLeft)
+			}
+			for model, want := range tt.wantLeft {
+				if left[model] != want {
+					t.Errorf("%s has %d entries, want %d", model, left[model], want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,120 @@
+package embed
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync/atomic"
+
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/config"
+)
+
+const (
+	ProviderOllama = "ollama"
+	ProviderGemini = "gemini"
+	ProviderOpenAI = "openai" // any OpenAI-compatible /embeddings endpoint
+	// ProviderHashing needs no model or network (used by --offline)
+	ProviderHashing = "hash"
+)
+
+var Providers = []string{ProviderOllama, ProviderGemini, ProviderOpenAI, ProviderHashing}
+
+// Embedder turns texts into vectors. Implementations must return one
+// vector per input text, in order.
+type Embedder interface {
+	Embed(ctx context.Context, texts []string) ([][]float32, error)
+	Info() Info
+}
+
+// Info describes the model behind an embedder.
+type Info struct {
+	Provider string
+	Model    string
+	// Dimension is 0 until the first successful Embed call.
+	Dimension int
+	// BatchSize is the largest number of texts sent per request.
+	BatchSize int
+}
+
+// Name identifies the model in cache manifests, e.g. "ollama:nomic-embed-text".
+func (i Info) Name() string {
+	return i.Provider + ":" + i.Model
+}
+
+type Options struct {
+	Provider  string
+	Model     string
+	BaseURL   string
+	APIKeyEnv string
+	BatchSize int // 0 = provider default
+	Dimension int // only used by the hashing embedder; 0 = default
+}
+
+func New(opts Options) (Embedder, error) {
+	switch opts.Provider {
+	case ProviderOllama:
+		return NewOllama(opts)
+	case ProviderGemini:
+		return NewGemini(opts)
+	case ProviderOpenAI:
+		return NewOpenAI(opts)
+	case ProviderHashing:
+		return NewHashing(opts)
+	default:
+		return nil, fmt.Errorf("unknown embedding provider %q (allowed: %s)", opts.Provider, strings.Join(Providers, ", "))
+	}
+}
+
+// FromConfig returns the text embedder configured in the embedding section.
+func FromConfig() (Embedder, error) {
+	cfg := config.Get().Embedding
+	return New(Options{
+		Provider:  cfg.Provider,
+		Model:     cfg.Model,
+		BaseURL:   cfg.BaseURL,
+		APIKeyEnv: cfg.APIKeyEnv,
+		BatchSize: cfg.BatchSize,
+		Dimension: cfg.Dimension,
+	})
+}
+
+// MultimodalFromConfig returns the embedder configured in the multimodal
+// section (used for image search).
+func MultimodalFromConfig() (Embedder, error) {
+	cfg := config.Get().Multimodal
+	return New(Options{
+		Provider:  cfg.Provider,
+		Model:     cfg.EmbeddingModel,
+		BaseURL:   cfg.BaseURL,
+		APIKeyEnv: cfg.APIKeyEnv,
+		BatchSize: cfg.BatchSize,
+		Dimension: config.Get().Embedding.Dimension,
+	})
+}
+
+// One embeds a single text.
+func One(ctx context.Context, e Embedder, text string) ([]float32, error) {
+	vectors, err := e.Embed(ctx, []string{text})
+	if err != nil {
+		return nil, err
+	}
+	if len(vectors) != 1 {
+		return nil, fmt.Errorf("expected 1 embedding, got %d", len(vectors))
+	}
+	return vectors[0], nil
+}
+
+// dimension records the vector size seen on the first call.
+type dimension struct {
+	n atomic.Int64
+}
+
+func (d *dimension) observe(vectors [][]float32) {
+	if len(vectors) > 0 {
+		d.n.CompareAndSwap(0, int64(len(vectors[0])))
+	}
+}
+
+func (d *dimension) get() int {
+	return int(d.n.Load())
+}
@@ -0,0 +1,75 @@
+package embed
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"google.golang.org/genai"
+)
+
+const defaultGeminiBatchSize = 100
+
+type Gemini struct {
+	model     string
+	batchSize int
+	client    *genai.Client
+	dim       dimension
+}
+
+func NewGemini(opts Options) (*Gemini, error) {
+	keyEnv := opts.APIKeyEnv
+	if keyEnv == "" {
+		keyEnv = "GEMINI_API_KEY"
+	}
+	apiKey := os.Getenv(keyEnv)
+	if apiKey == "" {
+		return nil, fmt.Errorf("%s not set", keyEnv)
+	}
+
+	client, err := genai.NewClient(context.Background(), &genai.ClientConfig{
+		APIKey:  apiKey,
+		Backend: genai.BackendGeminiAPI,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create client: %w", err)
+	}
+
+	batchSize := opts.BatchSize
+	if batchSize <= 0 {
+		batchSize = defaultGeminiBatchSize
+	}
+
+	return &Gemini{model: opts.Model, batchSize: batchSize, client: client}, nil
+}
+
+func (g *Gemini) Info() Info {
+	return Info{Provider: ProviderGemini, Model: g.model, Dimension: g.dim.get(), BatchSize: g.batchSize}
+}
+
+func (g *Gemini) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	contents := make([]*genai.Content, len(texts))
+	for i, text := range texts {
+		contents[i] = &genai.Content{Parts: []*genai.Part{{Text: text}}}
+	}
+
+	resp, err := g.client.Models.EmbedContent(ctx, g.model, contents, nil)
+	if err != nil {
+		return nil, err
+	}
+	if resp == nil || len(resp.Embeddings) != len(texts) {
+		got := 0
+		if resp != nil {
+			got = len(resp.Embeddings)
+		}
+		return nil, fmt.Errorf("expected %d embeddings, got %d", len(texts), got)
+	}
+
+	vectors := make([][]float32, len(resp.Embeddings))
+	for i, emb := range resp.Embeddings {
+		vectors[i] = emb.Values
+	}
+
+	g.dim.observe(vectors)
+	return vectors, nil
+}
@@ -0,0 +1,101 @@
+package embed
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"sync"
+
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/fs"
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/tokenizer"
+)
+
+const (
+	defaultHashingDimension = 384
+	hashingNGram            = 3
+	// Character n-grams weigh less than whole tokens; they mostly help
+	// with typos and morphology the stemmer misses.
+	hashingNGramWeight = 0.5
+)
+
+// Hashing is a deterministic, model-free embedder: analyzed tokens and
+// their character n-grams are hashed into a fixed number of buckets
+// (the "hashing trick"), with a hash-derived sign to cancel collisions
+// out on average. Vectors are L2-normalized.
+type Hashing struct {
+	dimension int
+
+	stopWordsOnce sync.Once
+	stopWords     map[string]struct{}
+}
+
+func NewHashing(opts Options) (*Hashing, error) {
+	dimension := opts.Dimension
+	if dimension <= 0 {
+		dimension = defaultHashingDimension
+	}
+	return &Hashing{dimension: dimension}, nil
+}
+
+func (h *Hashing) Info() Info {
+	return Info{
+		Provider:  ProviderHashing,
+		Model:     fmt.Sprintf("feature-hash(dim=%d,ngram=%d,%s)", h.dimension, hashingNGram, tokenizer.Analyzer),
+		Dimension: h.dimension,
+		BatchSize: 256,
+	}
+}
+
+func (h *Hashing) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	h.stopWordsOnce.Do(func() {
+		// Without a stop word list every token is kept
+		h.stopWords, _ = fs.LoadStopWords()
+	})
+
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		vectors[i] = h.embed(text)
+	}
+	return vectors, nil
+}
+
+func (h *Hashing) embed(text string) []float32 {
+	vector := make([]float32, h.dimension)
+
+	for _, token := range tokenizer.Tokenize(text, h.stopWords) {
+		h.add(vector, "t:"+token, 1)
+
+		padded := []rune("#" + token + "#")
+		for i := 0; i+hashingNGram <= len(padded); i++ {
+			h.add(vector, "g:"+string(padded[i:i+hashingNGram]), hashingNGramWeight)
+		}
+	}
+
+	var norm float64
+	for _, x := range vector {
+		norm += float64(x) * float64(x)
+	}
+	if norm > 0 {
+		inv := float32(1 / math.Sqrt(norm))
+		for i := range vector {
+			vector[i] *= inv
+		}
+	}
+	return vector
+}
+
+func (h *Hashing) add(vector []float32, feature string, weight float32) {
+	hasher := fnv.New64a()
+	hasher.Write([]byte(feature))
+	sum := hasher.Sum64()
+
+	bucket := sum % uint64(h.dimension)
+	if sum>>63 == 1 {
+		weight = -weight
+	}
+	vector[bucket] += weight
+}
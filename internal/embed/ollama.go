@@ -0,0 +1,101 @@
+package embed
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+
+	ollama "github.com/ollama/ollama/api"
+)
+
+const defaultOllamaBatchSize = 32
+
+// Ollama embeds batches through /api/embed, falling back to one text per
+// /api/embeddings request on servers that predate the batch endpoint.
+type Ollama struct {
+	model     string
+	batchSize int
+	client    *ollama.Client
+	dim       dimension
+	legacy    atomic.Bool
+}
+
+func NewOllama(opts Options) (*Ollama, error) {
+	var client *ollama.Client
+	if opts.BaseURL != "" {
+		base, err := url.Parse(opts.BaseURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ollama base_url: %w", err)
+		}
+		client = ollama.NewClient(base, http.DefaultClient)
+	} else {
+		var err error
+		client, err = ollama.ClientFromEnvironment() // $OLLAMA_HOST
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	batchSize := opts.BatchSize
+	if batchSize <= 0 {
+		batchSize = defaultOllamaBatchSize
+	}
+
+	return &Ollama{model: opts.Model, batchSize: batchSize, client: client}, nil
+}
+
+func (o *Ollama) Info() Info {
+	batchSize := o.batchSize
+	if o.legacy.Load() {
+		batchSize = 1
+	}
+	return Info{Provider: ProviderOllama, Model: o.model, Dimension: o.dim.get(), BatchSize: batchSize}
+}
+
+func (o *Ollama) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	if !o.legacy.Load() {
+		resp, err := o.client.Embed(ctx, &ollama.EmbedRequest{
+			Model: o.model,
+			Input: texts,
+		})
+		var statusErr ollama.StatusError
+		if errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusNotFound &&
+			!strings.Contains(statusErr.ErrorMessage, "model") {
+			// No /api/embed route (a missing model also 404s, but says so)
+			o.legacy.Store(true)
+		} else if err != nil {
+			return nil, err
+		} else {
+			o.dim.observe(resp.Embeddings)
+			return resp.Embeddings, nil
+		}
+	}
+
+	return o.embedLegacy(ctx, texts)
+}
+
+func (o *Ollama) embedLegacy(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, 0, len(texts))
+	for _, text := range texts {
+		resp, err := o.client.Embeddings(ctx, &ollama.EmbeddingRequest{
+			Model:  o.model,
+			Prompt: text,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		vector := make([]float32, len(resp.Embedding))
+		for i, x := range resp.Embedding {
+			vector[i] = float32(x)
+		}
+		vectors = append(vectors, vector)
+	}
+
+	o.dim.observe(vectors)
+	return vectors, nil
+}
@@ -0,0 +1,137 @@
+package embed
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	defaultOpenAIBaseURL   = "https://api.openai.com/v1"
+	defaultOpenAIBatchSize = 100
+)
+
+// OpenAI talks to any server implementing the OpenAI /embeddings API
+// (OpenAI, vLLM, LM Studio, llama.cpp server, ...).
+type OpenAI struct {
+	model     string
+	baseURL   string
+	apiKey    string
+	batchSize int
+	client    *http.Client
+	dim       dimension
+}
+
+func NewOpenAI(opts Options) (*OpenAI, error) {
+	baseURL := opts.BaseURL
+	if baseURL == "" {
+		baseURL = defaultOpenAIBaseURL
+	}
+	keyEnv := opts.APIKeyEnv
+	if keyEnv == "" {
+		keyEnv = "OPENAI_API_KEY"
+	}
+	batchSize := opts.BatchSize
+	if batchSize <= 0 {
+		batchSize = defaultOpenAIBatchSize
+	}
+
+	// Local servers usually don't need a key, so a missing one isn't an error
+	return &OpenAI{
+		model:     opts.Model,
+		baseURL:   strings.TrimSuffix(baseURL, "/"),
+		apiKey:    os.Getenv(keyEnv),
+		batchSize: batchSize,
+		client:    http.DefaultClient,
+	}, nil
+}
+
+func (o *OpenAI) Info() Info {
+	return Info{Provider: ProviderOpenAI, Model: o.model, Dimension: o.dim.get(), BatchSize: o.batchSize}
+}
+
+type openAIRequest struct {
+	Model string   `json:"model"`
+	Input []string `json:"input"`
+}
+
+type openAIResponse struct {
+	Data []struct {
+		Index     int       `json:"index"`
+		Embedding []float32 `json:"embedding"`
+	} `json:"data"`
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+func (o *OpenAI) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	body, err := json.Marshal(openAIRequest{Model: o.model, Input: texts})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, o.baseURL+"/embeddings", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if o.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+o.apiKey)
+	}
+
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	raw, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var parsed openAIResponse
+	parseErr := json.Unmarshal(raw, &parsed)
+	if resp.StatusCode != http.StatusOK {
+		message := truncate(string(raw), 200)
+		if parseErr == nil && parsed.Error != nil {
+			message = parsed.Error.Message
+		}
+		return nil, &HTTPError{StatusCode: resp.StatusCode, Message: message}
+	}
+	if parseErr != nil {
+		return nil, fmt.Errorf("unexpected response: %s", truncate(string(raw), 200))
+	}
+	if len(parsed.Data) != len(texts) {
+		return nil, fmt.Errorf("expected %d embeddings, got %d", len(texts), len(parsed.Data))
+	}
+
+	vectors := make([][]float32, len(texts))
+	for _, d := range parsed.Data {
+		if d.Index < 0 || d.Index >= len(texts) {
+			return nil, fmt.Errorf("embedding index %d out of range", d.Index)
+		}
+		vectors[d.Index] = d.Embedding
+	}
+
+	o.dim.observe(vectors)
+	return vectors, nil
+}
+
+// truncate cuts s to at most n bytes, backing up to a rune boundary.
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n] + "…"
+}
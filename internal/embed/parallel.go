@@ -0,0 +1,203 @@
+package embed
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// A batch slower than latencyBackoffFactor times the fastest batch seen
+// (per text) counts as a sign of saturation.
+const latencyBackoffFactor = 3.0
+
+// decreaseCooldown keeps one burst of slow/failed batches from halving the
+// limit several times over.
+const decreaseCooldown = time.Second
+
+// Stats summarizes a Parallel run.
+type Stats struct {
+	Texts            int
+	Batches          int
+	Retries          int
+	Elapsed          time.Duration
+	PeakConcurrency  int
+	FinalConcurrency int
+}
+
+func (s Stats) DocsPerSecond() float64 {
+	if s.Elapsed <= 0 {
+		return 0
+	}
+	return float64(s.Texts) / s.Elapsed.Seconds()
+}
+
+func (s Stats) String() string {
+	return fmt.Sprintf("%d texts in %s (%.1f docs/sec) | %d batches, %d retries | concurrency peak %d, final %d",
+		s.Texts, s.Elapsed.Round(time.Millisecond), s.DocsPerSecond(),
+		s.Batches, s.Retries, s.PeakConcurrency, s.FinalConcurrency)
+}
+
+// Parallel embeds texts in batches of the embedder's BatchSize. Up to
+// maxWorkers (0 = one per CPU) batches are in flight; the actual limit
+// adapts (AIMD): it grows by one after a round of healthy batches and
+// halves on 429/5xx or when latency spikes. Failed batches are retried
+// with jittered backoff up to maxRetries times; only then does the build
+// fail. onDone is called with every finished batch (from is the position
+// of its first text); calls may be concurrent.
+func Parallel(ctx context.Context, e Embedder, texts []string, maxWorkers int, maxRetries int, onDone func(from int, vectors [][]float32)) ([][]float32, Stats, error) {
+	if maxWorkers <= 0 {
+		maxWorkers = runtime.NumCPU()
+	}
+	batchSize := max(e.Info().BatchSize, 1)
+	start := time.Now()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	limiter := newLimiter(maxWorkers)
+	embeddings := make([][]float32, len(texts))
+	var (
+		firstErr atomic.Value
+		retries  atomic.Int64
+		batches  int
+		wg       sync.WaitGroup
+	)
+
+	for from := 0; from < len(texts) && ctx.Err() == nil; from += batchSize {
+		to := min(from+batchSize, len(texts))
+		if err := limiter.acquire(ctx); err != nil {
+			break
+		}
+		batches++
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer limiter.release()
+
+			for attempt := 0; ; attempt++ {
+				began := time.Now()
+				vectors, err := e.Embed(ctx, texts[from:to])
+				if err == nil && len(vectors) != to-from {
+					err = fmt.Errorf("expected %d embeddings, got %d", to-from, len(vectors))
+				}
+				if err == nil {
+					limiter.succeeded(time.Since(began) / time.Duration(to-from))
+					copy(embeddings[from:to], vectors)
+					if onDone != nil {
+						onDone(from, vectors)
+					}
+					return
+				}
+
+				if Overloaded(err) {
+					limiter.overloaded()
+				}
+				if attempt >= maxRetries || !Retryable(err) || ctx.Err() != nil {
+					firstErr.CompareAndSwap(nil, fmt.Errorf("embedding error on docs %d-%d: %w", from, to-1, err))
+					cancel()
+					return
+				}
+				retries.Add(1)
+				if sleep(ctx, backoff(attempt)) != nil {
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	stats := Stats{
+		Texts:            len(texts),
+		Batches:          batches,
+		Retries:          int(retries.Load()),
+		Elapsed:          time.Since(start),
+		PeakConcurrency:  limiter.peak,
+		FinalConcurrency: limiter.limit,
+	}
+
+	if err, ok := firstErr.Load().(error); ok {
+		return nil, stats, err
+	}
+	return embeddings, stats, ctx.Err()
+}
+
+// limiter is a semaphore whose size changes at runtime.
+type limiter struct {
+	mu        sync.Mutex
+	cond      *sync.Cond
+	max       int
+	limit     int
+	peak      int
+	inFlight  int
+	successes int           // healthy batches since the last change
+	fastest   time.Duration // best per-text latency seen
+	lastCut   time.Time
+}
+
+func newLimiter(maxWorkers int) *limiter {
+	// Start at half capacity and probe upwards
+	l := &limiter{max: maxWorkers, limit: max(1, maxWorkers/2)}
+	l.peak = l.limit
+	l.cond = sync.NewCond(&l.mu)
+	return l
+}
+
+func (l *limiter) acquire(ctx context.Context) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for l.inFlight >= l.limit {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		l.cond.Wait()
+	}
+	l.inFlight++
+	return nil
+}
+
+func (l *limiter) release() {
+	l.mu.Lock()
+	l.inFlight--
+	l.mu.Unlock()
+	l.cond.Broadcast()
+}
+
+func (l *limiter) succeeded(perText time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.fastest == 0 || perText < l.fastest {
+		l.fastest = perText
+	}
+	if float64(perText) > latencyBackoffFactor*float64(l.fastest) {
+		l.decrease()
+		return
+	}
+
+	l.successes++
+	if l.successes >= l.limit && l.limit < l.max {
+		l.limit++
+		l.peak = max(l.peak, l.limit)
+		l.successes = 0
+		l.cond.Broadcast()
+	}
+}
+
+func (l *limiter) overloaded() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.decrease()
+}
+
+func (l *limiter) decrease() {
+	l.successes = 0
+	if time.Since(l.lastCut) < decreaseCooldown {
+		return
+	}
+	l.limit = max(1, l.limit/2)
+	l.lastCut = time.Now()
+}
@@ -0,0 +1,125 @@
+package embed
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/config"
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/fs"
+)
+
+// QueryStats counts the query cache lookups of this process.
+type QueryStats struct {
+	Hits   int
+	Misses int
+}
+
+var queryCaches = struct {
+	mu      sync.Mutex
+	byModel map[string]*Cache
+	stats   QueryStats
+}{byModel: make(map[string]*Cache)}
+
+// NormalizeQuery is the query cache key: queries that differ only in case
+// or spacing share a vector.
+func NormalizeQuery(query string) string {
+	return strings.ToLower(strings.Join(strings.Fields(query), " "))
+}
+
+// OpenQueryCache loads the query cache for model, keeping at most capacity
+// entries (least recently used are dropped first).
+func OpenQueryCache(model string, capacity int) (*Cache, error) {
+	c, err := openCacheFile(cachePath(fs.QueryCacheDir, model), model)
+	if err != nil {
+		return nil, err
+	}
+	c.Capacity = capacity
+	return c, nil
+}
+
+// ListQueryCaches describes every query cache on disk, sorted by model.
+func ListQueryCaches() ([]CacheInfo, error) {
+	return listCaches(fs.QueryCacheDir)
+}
+
+// Query embeds a search query through the persistent query cache shared by
+// every command (embedding.query_cache entries per model; 0 = off), so a
+// query repeated across runs is embedded once. Cached vectors of another
+// dimension than dim (0 = any) are misses. New vectors are saved right away;
+// the recency of hits by SaveQueryCaches.
+func Query(ctx context.Context, e Embedder, query string, dim int) ([]float32, error) {
+	capacity := config.Get().Embedding.QueryCache
+	if capacity <= 0 {
+		return One(ctx, e, query)
+	}
+	if d := e.Info().Dimension; d != 0 {
+		dim = d
+	}
+
+	cache, err := queryCache(e.Info().Name(), capacity)
+	if err != nil {
+		return nil, err
+	}
+
+	key := NormalizeQuery(query)
+	vector, hit := cache.Get(key, dim)
+	queryCaches.mu.Lock()
+	if hit {
+		queryCaches.stats.Hits++
+	} else {
+		queryCaches.stats.Misses++
+	}
+	queryCaches.mu.Unlock()
+	if hit {
+		return vector, nil
+	}
+
+	// Not holding queryCaches.mu, so other queries embed meanwhile
+	vector, err = One(ctx, e, query)
+	if err != nil {
+		return nil, err
+	}
+	cache.Put(key, vector)
+	if err := cache.Save(); err != nil {
+		return nil, err
+	}
+	return vector, nil
+}
+
+// queryCache returns the open query cache of model, opening it on first use.
+func queryCache(model string, capacity int) (*Cache, error) {
+	queryCaches.mu.Lock()
+	defer queryCaches.mu.Unlock()
+
+	cache, ok := queryCaches.byModel[model]
+	if !ok {
+		var err error
+		if cache, err = OpenQueryCache(model, capacity); err != nil {
+			return nil, err
+		}
+		queryCaches.byModel[model] = cache
+	}
+	return cache, nil
+}
+
+// SaveQueryCaches writes the query caches changed since they were opened,
+// so hits refresh the recency eviction goes by.
+func SaveQueryCaches() error {
+	queryCaches.mu.Lock()
+	defer queryCaches.mu.Unlock()
+
+	for _, cache := range queryCaches.byModel {
+		if err := cache.Save(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// QueryCacheStats returns the hits and misses of the query cache so far.
+func QueryCacheStats() QueryStats {
+	queryCaches.mu.Lock()
+	defer queryCaches.mu.Unlock()
+	return queryCaches.stats
+}
@@ -0,0 +1,153 @@
+package embed
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/config"
+)
+
+// useQueryCache runs the test with a fresh process-wide query cache of
+// capacity entries under a temporary dir.
+func useQueryCache(t *testing.T, capacity int) {
+	t.Helper()
+	useCacheDirs(t)
+	resetQueryCaches()
+	previous := config.Get().Embedding.QueryCache
+	config.Get().Embedding.QueryCache = capacity
+	t.Cleanup(func() {
+		config.Get().Embedding.QueryCache = previous
+		resetQueryCaches()
+	})
+}
+
+// resetQueryCaches forgets the open query caches, as a new process would.
+func resetQueryCaches() {
+	queryCaches.mu.Lock()
+	defer queryCaches.mu.Unlock()
+	queryCaches.byModel = make(map[string]*Cache)
+	queryCaches.stats = QueryStats{}
+}
+
+func TestNormalizeQuery(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{query: "space movie", want: "space movie"},
+		{query: "  Space   MOVIE\t", want: "space movie"},
+		{query: "space\nmovie", want: "space movie"},
+		{query: "", want: ""},
+	}
+	for _, tt := range tests {
+		if got := NormalizeQuery(tt.query); got != tt.want {
+			t.Errorf("NormalizeQuery(%q) = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestQuery(t *testing.T) {
+	tests := []struct {
+		name       string
+		capacity   int
+		queries    []string
+		wantCalls  int32
+		wantHits   int
+		wantMisses int
+	}{
+		{name: "off", capacity: 0, queries: []string{"bear", "bear"}, wantCalls: 2},
+		{name: "repeated", capacity: 10, queries: []string{"bear", "bear", "bear"}, wantCalls: 1, wantHits: 2, wantMisses: 1},
+		{name: "normalized", capacity: 10, queries: []string{"bear movie", " Bear  MOVIE "}, wantCalls: 1, wantHits: 1, wantMisses: 1},
+		{name: "distinct", capacity: 10, queries: []string{"bear", "shark"}, wantCalls: 2, wantMisses: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useQueryCache(t, tt.capacity)
+			e := &flakyEmbedder{}
+			for _, query := range tt.queries {
+				vector, err := Query(context.Background(), e, query, 0)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(vector) != 1 {
+					t.Fatalf("Query(%q) = %v", query, vector)
+				}
+			}
+			if got := e.calls.Load(); got != tt.wantCalls {
+				t.Errorf("embedder called %d times, want %d", got, tt.wantCalls)
+			}
+			stats := QueryCacheStats()
+			if stats.Hits != tt.wantHits || stats.Misses != tt.wantMisses {
+				t.Errorf("stats = %+v, want %d hits and %d misses", stats, tt.wantHits, tt.wantMisses)
+			}
+		})
+	}
+}
+
+func TestQueryCachePersistence(t *testing.T) {
+	useQueryCache(t, 2)
+	e := &flakyEmbedder{}
+	query := func(text string) {
+		t.Helper()
+		if _, err := Query(context.Background(), e, text, 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	query("first")
+	query("second")
+	cache, err := queryCache(e.Info().Name(), 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	age(cache, NormalizeQuery("first"), 2*time.Hour)
+	age(cache, NormalizeQuery("second"), time.Hour)
+	query("first") // a hit makes "first" the most recently used
+	query("third") // saving evicts "second", the least recently used
+	if err := SaveQueryCaches(); err != nil {
+		t.Fatal(err)
+	}
+
+	// A new process reads the cache back from disk
+	resetQueryCaches()
+	e.calls.Store(0)
+	tests := []struct {
+		query string
+		hit   bool
+	}{
+		{query: "first", hit: true},
+		{query: "third", hit: true},
+		{query: "second", hit: false},
+	}
+	for _, tt := range tests {
+		calls := e.calls.Load()
+		query(tt.query)
+		if hit := e.calls.Load() == calls; hit != tt.hit {
+			t.Errorf("%q: hit = %v, want %v", tt.query, hit, tt.hit)
+		}
+	}
+}
+
+func TestPruneQueryCaches(t *testing.T) {
+	useQueryCache(t, 10)
+	e := &flakyEmbedder{}
+	if _, err := Query(context.Background(), e, "bear", 0); err != nil {
+		t.Fatal(err)
+	}
+
+	removed, err := PruneCaches(0, []string{"another-model"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if removed != 1 {
+		t.Errorf("removed %d entries, want 1", removed)
+	}
+	infos, err := ListQueryCaches()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(infos) != 0 {
+		t.Errorf("ListQueryCaches = %+v after pruning its model", infos)
+	}
+}
@@ -0,0 +1,160 @@
+package embed
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	ollama "github.com/ollama/ollama/api"
+	"google.golang.org/genai"
+)
+
+func TestRetryable(t *testing.T) {
+	tests := []struct {
+		name           string
+		err            error
+		wantRetry      bool
+		wantOverloaded bool
+	}{
+		{name: "nil", err: nil},
+		{name: "canceled", err: fmt.Errorf("embed: %w", context.Canceled)},
+		{name: "deadline", err: fmt.Errorf("embed: %w", context.DeadlineExceeded), wantRetry: true},
+		{name: "429", err: &HTTPError{StatusCode: http.StatusTooManyRequests}, wantRetry: true, wantOverloaded: true},
+		{name: "503 wrapped", err: fmt.Errorf("batch: %w", &HTTPError{StatusCode: 503}), wantRetry: true, wantOverloaded: true},
+		{name: "400", err: &HTTPError{StatusCode: http.StatusBadRequest}},
+		{name: "401", err: &HTTPError{StatusCode: http.StatusUnauthorized}},
+		{name: "ollama 404 unknown model", err: ollama.StatusError{StatusCode: 404, ErrorMessage: "model not found"}},
+		{name: "ollama 500", err: ollama.StatusError{StatusCode: 500}, wantRetry: true, wantOverloaded: true},
+		{name: "gemini 429", err: genai.APIError{Code: 429}, wantRetry: true, wantOverloaded: true},
+		{name: "gemini 403", err: genai.APIError{Code: 403}},
+		{name: "connection refused", err: &net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}, wantRetry: true},
+		{name: "connection reset", err: fmt.Errorf("read: %w", syscall.ECONNRESET), wantRetry: true},
+		{name: "truncated body", err: fmt.Errorf("decode: %w", io.ErrUnexpectedEOF), wantRetry: true},
+		{name: "other", err: errors.New("invalid input")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Retryable(tt.err); got != tt.wantRetry {
+				t.Errorf("Retryable = %v, want %v", got, tt.wantRetry)
+			}
+			if tt.err != nil {
+				if got := Overloaded(tt.err); got != tt.wantOverloaded {
+					t.Errorf("Overloaded = %v, want %v", got, tt.wantOverloaded)
+				}
+			}
+		})
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	for attempt := 0; attempt < 10; attempt++ {
+		ceiling := min(retryBaseDelay<<attempt, retryMaxDelay)
+		if d := backoff(attempt); d < 0 || d > ceiling {
+			t.Errorf("backoff(%d) = %s, want within [0, %s]", attempt, d, ceiling)
+		}
+	}
+}
+
+func TestLimiter(t *testing.T) {
+	l := newLimiter(8)
+	if l.limit != 4 {
+		t.Fatalf("starting limit = %d, want half of 8", l.limit)
+	}
+
+	// Additive increase: one more slot after a limit's worth of healthy batches
+	for i := 0; i < 4; i++ {
+		l.succeeded(time.Millisecond)
+	}
+	if l.limit != 5 || l.peak != 5 {
+		t.Errorf("after 4 successes limit = %d, peak = %d, want 5, 5", l.limit, l.peak)
+	}
+
+	// Multiplicative decrease, at most once per cooldown
+	l.overloaded()
+	if l.limit != 2 {
+		t.Errorf("after overload limit = %d, want 2", l.limit)
+	}
+	l.overloaded()
+	if l.limit != 2 {
+		t.Errorf("second overload within the cooldown: limit = %d, want 2", l.limit)
+	}
+
+	// Latency well above the fastest seen counts as overload
+	l.lastCut = time.Time{}
+	l.succeeded(time.Duration(latencyBackoffFactor*float64(time.Millisecond)) + time.Millisecond)
+	if l.limit != 1 {
+		t.Errorf("after a slow batch limit = %d, want 1", l.limit)
+	}
+	l.lastCut = time.Time{}
+	l.overloaded()
+	if l.limit != 1 {
+		t.Errorf("limit = %d, never below 1", l.limit)
+	}
+}
+
+// flakyEmbedder fails the first failures calls with err.
+type flakyEmbedder struct {
+	failures int32
+	err      error
+	calls    atomic.Int32
+}
+
+func (f *flakyEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	if f.calls.Add(1) <= f.failures {
+		return nil, f.err
+	}
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		vectors[i] = []float32{float32(len(text))}
+	}
+	return vectors, nil
+}
+
+func (f *flakyEmbedder) Info() Info {
+	return Info{Provider: "test", Model: "flaky", Dimension: 1, BatchSize: 2}
+}
+
+func TestParallelRetries(t *testing.T) {
+	texts := []string{"a", "bb", "ccc"}
+	tests := []struct {
+		name      string
+		embedder  *flakyEmbedder
+		retries   int
+		wantErr   bool
+		wantCalls int32
+	}{
+		{name: "no errors", embedder: &flakyEmbedder{}, retries: 2, wantCalls: 2},
+		{name: "retried", embedder: &flakyEmbedder{failures: 1, err: &HTTPError{StatusCode: 503}}, retries: 2, wantCalls: 3},
+		{name: "not retryable", embedder: &flakyEmbedder{failures: 1, err: &HTTPError{StatusCode: 400}}, retries: 2, wantErr: true},
+		{name: "out of retries", embedder: &flakyEmbedder{failures: 10, err: &HTTPError{StatusCode: 503}}, retries: 0, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vectors, stats, err := Parallel(context.Background(), tt.embedder, texts, 1, tt.retries, nil)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, want error: %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			for i, v := range vectors {
+				if v[0] != float32(len(texts[i])) {
+					t.Errorf("vector %d = %v, want the one of %q", i, v, texts[i])
+				}
+			}
+			if calls := tt.embedder.calls.Load(); calls != tt.wantCalls {
+				t.Errorf("calls = %d, want %d", calls, tt.wantCalls)
+			}
+			if stats.Batches != 2 || stats.Retries != int(tt.embedder.failures) {
+				t.Errorf("stats = %+v, want 2 batches and %d retries", stats, tt.embedder.failures)
+			}
+		})
+	}
+}
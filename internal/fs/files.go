@@ -3,6 +3,7 @@ package fs
 import (
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
@@ -20,15 +21,130 @@ var (
 	DataPath          = filepath.Join(ProjectRoot, "data", "movies.json")
 	StopWordsPath     = filepath.Join(ProjectRoot, "data", "stopwords.txt")
 	GoldenDatasetPath = filepath.Join(ProjectRoot, "data", "golden_dataset.json")
-	CacheDir          = filepath.Join(ProjectRoot, "cache")
+	CollectionsDir    = filepath.Join(ProjectRoot, "cache")
+	CacheDir          = CollectionsDir
 	IndexPath         = filepath.Join(CacheDir, "index.gob")
 	EmbeddingsPath    = filepath.Join(CacheDir, "movie_embeddings.gob")
 
 	ChunksEmbeddingsPath     = filepath.Join(CacheDir, "chunks_embeddings.gob")
 	ChunksMetadataPath       = filepath.Join(CacheDir, "chunks_metadata.json")
 	MultimodalEmbeddingsPath = filepath.Join(CacheDir, "multimodal_embeddings.gob")
+
+	EmbeddingsANNPath = filepath.Join(CacheDir, "movie_embeddings.hnsw")
+	ChunksANNPath     = filepath.Join(CacheDir, "chunks_embeddings.hnsw")
+	MultimodalANNPath = filepath.Join(CacheDir, "multimodal_embeddings.hnsw")
+
+	// EmbeddingCacheDir is shared by every collection (entries are keyed by
+	// content); the leading underscore keeps it out of the collection names.
+	EmbeddingCacheDir = filepath.Join(CollectionsDir, "_embeddings")
+	// SituateCacheDir holds the LLM situating sentences of chunks, also
+	// keyed by content.
+	SituateCacheDir = filepath.Join(CollectionsDir, "_situate")
+	// EnrichCacheDir holds the LLM-generated document fields
+	EnrichCacheDir = filepath.Join(CollectionsDir, "_enrich")
+	// QueryCacheDir holds the embeddings of search queries, per model
+	QueryCacheDir = filepath.Join(CollectionsDir, "_queries")
+
+	// Collection is the active named collection, empty for the default
+	// data/movies.json dataset cached directly under cache/.
+	Collection         string
+	CollectionInfoPath string
 )
 
+// SetDirs relocates the data and cache directories (relative paths are
+// resolved against the project root) and resets every derived path.
+func SetDirs(dataDir string, cacheDir string) {
+	if !filepath.IsAbs(dataDir) {
+		dataDir = filepath.Join(ProjectRoot, dataDir)
+	}
+	if !filepath.IsAbs(cacheDir) {
+		cacheDir = filepath.Join(ProjectRoot, cacheDir)
+	}
+
+	DataPath = filepath.Join(dataDir, "movies.json")
+	StopWordsPath = filepath.Join(dataDir, "stopwords.txt")
+	GoldenDatasetPath = filepath.Join(dataDir, "golden_dataset.json")
+	CollectionsDir = cacheDir
+	CacheDir = CollectionsDir
+	EmbeddingCacheDir = filepath.Join(CollectionsDir, "_embeddings")
+	SituateCacheDir = filepath.Join(CollectionsDir, "_situate")
+	EnrichCacheDir = filepath.Join(CollectionsDir, "_enrich")
+	QueryCacheDir = filepath.Join(CollectionsDir, "_queries")
+	setCachePaths()
+}
+
+// UseCollection points the data source, golden dataset and every cache
+// artifact at cache/<name>/, so collections never overwrite each other.
+func UseCollection(name string) {
+	Collection = name
+	CacheDir = CollectionDir(name)
+	DataPath = filepath.Join(CacheDir, "documents.json")
+	GoldenDatasetPath = filepath.Join(CacheDir, "golden_dataset.json")
+	CollectionInfoPath = filepath.Join(CacheDir, "collection.json")
+	setCachePaths()
+}
+
+// setCachePaths derives every cache artifact path from CacheDir.
+func setCachePaths() {
+	IndexPath = filepath.Join(CacheDir, "index.gob")
+	EmbeddingsPath = filepath.Join(CacheDir, "movie_embeddings.gob")
+	ChunksEmbeddingsPath = filepath.Join(CacheDir, "chunks_embeddings.gob")
+	ChunksMetadataPath = filepath.Join(CacheDir, "chunks_metadata.json")
+	MultimodalEmbeddingsPath = filepath.Join(CacheDir, "multimodal_embeddings.gob")
+	EmbeddingsANNPath = filepath.Join(CacheDir, "movie_embeddings.hnsw")
+	ChunksANNPath = filepath.Join(CacheDir, "chunks_embeddings.hnsw")
+	MultimodalANNPath = filepath.Join(CacheDir, "multimodal_embeddings.hnsw")
+}
+
+// QuantizedPath is where the quantized copy of an embeddings file is
+// stored, e.g. movie_embeddings.int8.gob.
+func QuantizedPath(embeddingsPath string, kind string) string {
+	return strings.TrimSuffix(embeddingsPath, ".gob") + "." + kind + ".gob"
+}
+
+// FieldEmbeddingsPath is where the embeddings of one document field are
+// stored, next to the combined movie_embeddings.gob.
+func FieldEmbeddingsPath(field string) string {
+	return strings.TrimSuffix(EmbeddingsPath, ".gob") + "." + field + ".gob"
+}
+
+// CheckpointDir holds the partial results of an interrupted build of the
+// artifact at artifactPath.
+func CheckpointDir(artifactPath string) string {
+	return artifactPath + ".partial"
+}
+
+// WriteAtomic writes path through a temp file in the same directory and
+// renames it into place, so readers never see a half-written artifact.
+func WriteAtomic(path string, write func(w io.Writer) error) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // no-op once renamed
+
+	if err := write(tmp); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(0o644); err != nil { // CreateTemp uses 0600
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func CollectionDir(name string) string {
+	return filepath.Join(CollectionsDir, name)
+}
+
 // getProjectRoot walks up until it finds go.mod (project base)
 func getProjectRoot() (string, error) {
 	dir, err := os.Getwd()
@@ -66,6 +182,29 @@ func LoadMovies() ([]model.Movie, error) {
 	return data.Movies, nil
 }
 
+// SaveMovies writes docs to DataPath using the same {"movies": [...]} layout
+// LoadMovies expects.
+func SaveMovies(docs []model.Movie) error {
+	if err := os.MkdirAll(filepath.Dir(DataPath), os.ModePerm); err != nil {
+		return fmt.Errorf("failed to create data dir: %w", err)
+	}
+
+	data := struct {
+		Movies []model.Movie `json:"movies"`
+	}{Movies: docs}
+
+	raw, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed encoding documents: %w", err)
+	}
+
+	if err := os.WriteFile(DataPath, raw, 0o644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", DataPath, err)
+	}
+
+	return nil
+}
+
 func LoadStopWords() (map[string]struct{}, error) {
 	raw, err := os.ReadFile(StopWordsPath)
 	if err != nil {
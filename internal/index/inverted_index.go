@@ -10,7 +10,9 @@ import (
 	"slices"
 	"sort"
 
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/config"
 	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/fs"
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/manifest"
 	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/model"
 	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/tokenizer"
 )
@@ -26,6 +28,8 @@ type InvertedIndex struct {
 	DocMap          map[int]model.Movie         // docID -> movie
 	TermFrequencies map[int]map[string]int      // docID -> term -> count
 	DocLengths      map[int]int                 // docID -> docLength
+
+	manifest *manifest.Manifest // set by Build, written next to the index by Save
 }
 
 func NewInvertedIndex() *InvertedIndex {
@@ -181,7 +185,8 @@ func (idx *InvertedIndex) GetBM25TF(docID int, term string, k1 float64, b float6
 
 func (idx *InvertedIndex) bm25Score(docID int, term string) float64 {
 	bm25idf := idx.GetBM25IDF(term)
-	bm25tf := idx.GetBM25TF(docID, term, 1.5, 0.75)
+	cfg := config.Get()
+	bm25tf := idx.GetBM25TF(docID, term, cfg.Keyword.K1, cfg.Keyword.B)
 
 	return bm25idf * bm25tf
 }
@@ -208,9 +213,12 @@ func (idx *InvertedIndex) Bm25Search(query string, limit int) []SearchResult {
 		})
 	}
 
-	// Sort by Score DESC
+	// Sort by Score DESC, ties by DocID so rankings are repeatable
 	sort.Slice(results, func(i, j int) bool {
-		return results[i].Score > results[j].Score
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].DocID < results[j].DocID
 	})
 
 	if limit < len(results) {
@@ -226,6 +234,12 @@ func (idx *InvertedIndex) Build() error {
 		return err
 	}
 
+	m, err := expectedManifest(movies)
+	if err != nil {
+		return err
+	}
+	idx.manifest = &m
+
 	// map[string]struct{}
 	stopWords, err := fs.LoadStopWords()
 	if err != nil {
@@ -259,7 +273,10 @@ func (idx *InvertedIndex) Bm25SearchParallel(query string, limit int) []SearchRe
 	docCount := len(idx.DocMap)
 	resultsChan := make(chan scoredDoc, docCount)
 
-	workerCount := runtime.NumCPU() // use all cores
+	workerCount := config.Get().Keyword.Workers
+	if workerCount <= 0 {
+		workerCount = runtime.NumCPU() // use all cores
+	}
 	jobs := make(chan int, docCount)
 
 	// fmt.Println("Worker Count at Jobs pool: ", workerCount)
@@ -326,6 +343,10 @@ func (idx *InvertedIndex) Save() error {
 		return fmt.Errorf("failed to encode index: %w", err)
 	}
 
+	if idx.manifest != nil {
+		return manifest.Save(fs.IndexPath, *idx.manifest)
+	}
+
 	return nil
 }
 
@@ -344,3 +365,48 @@ func (idx *InvertedIndex) Load() error {
 
 	return nil
 }
+
+// LoadOrBuild loads the saved index, building it when it's missing and
+// applying the staleness policy when its manifest no longer matches the
+// documents or the analyzer.
+func (idx *InvertedIndex) LoadOrBuild() error {
+	if _, err := os.Stat(fs.IndexPath); err == nil {
+		if err := idx.Load(); err != nil {
+			return err
+		}
+
+		movies, err := fs.LoadMovies()
+		if err != nil {
+			return err
+		}
+		expected, err := expectedManifest(movies)
+		if err != nil {
+			return err
+		}
+
+		rebuild, err := manifest.Resolve("index.gob", manifest.Check(fs.IndexPath, expected))
+		if err != nil || !rebuild {
+			return err
+		}
+		*idx = *NewInvertedIndex()
+	}
+
+	if err := idx.Build(); err != nil {
+		return err
+	}
+	return idx.Save()
+}
+
+func expectedManifest(movies []model.Movie) (manifest.Manifest, error) {
+	stopWords, err := os.ReadFile(fs.StopWordsPath)
+	if err != nil {
+		return manifest.Manifest{}, fmt.Errorf("failed to read %s: %w", fs.StopWordsPath, err)
+	}
+
+	return manifest.Manifest{
+		Artifact:   "index.gob",
+		SourceHash: manifest.SourceHash(movies),
+		Documents:  len(movies),
+		Analyzer:   tokenizer.Analyzer + "|stopwords:" + manifest.HashBytes(stopWords),
+	}, nil
+}
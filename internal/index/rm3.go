@@ -0,0 +1,146 @@
+package index
+
+import (
+	"log"
+	"math"
+	"sort"
+
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/config"
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/fs"
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/tokenizer"
+)
+
+// RM3Params control the pseudo-relevance feedback of RM3Search.
+type RM3Params struct {
+	FeedbackDocs   int     // top BM25 documents assumed relevant
+	FeedbackTerms  int     // expansion terms kept
+	OriginalWeight float64 // weight of the original query terms, 0..1
+}
+
+// WeightedTerm is an analyzed term and its weight in an expanded query.
+type WeightedTerm struct {
+	Term   string
+	Weight float64
+}
+
+// RM3Expand builds the RM3 query for query: a relevance model (the terms of
+// the top BM25 documents, each document weighted by its share of their
+// scores) cut to the strongest FeedbackTerms, interpolated with the
+// original query terms. Weights sum to 1, strongest first.
+func (idx *InvertedIndex) RM3Expand(query string, params RM3Params) []WeightedTerm {
+	stopWords, err := fs.LoadStopWords()
+	if err != nil {
+		log.Fatalf("Error loading stop words: could not tokenize term.")
+	}
+	qTokens := tokenizer.Tokenize(query, stopWords)
+	if len(qTokens) == 0 {
+		return nil
+	}
+
+	original := make(map[string]float64, len(qTokens))
+	for _, t := range qTokens {
+		original[t] += 1 / float64(len(qTokens))
+	}
+
+	feedback := idx.Bm25SearchTerms(original, params.FeedbackDocs)
+	total := 0.0
+	for _, r := range feedback {
+		total += r.Score
+	}
+
+	relevance := make(map[string]float64)
+	if total > 0 {
+		for _, r := range feedback {
+			length := float64(idx.DocLengths[r.DocID])
+			if length == 0 {
+				continue
+			}
+			for term, tf := range idx.TermFrequencies[r.DocID] {
+				relevance[term] += (r.Score / total) * float64(tf) / length
+			}
+		}
+	}
+	expansion := topTerms(relevance, params.FeedbackTerms)
+
+	expansionTotal := 0.0
+	for _, t := range expansion {
+		expansionTotal += t.Weight
+	}
+	lambda := params.OriginalWeight
+	if expansionTotal == 0 {
+		lambda = 1
+	}
+
+	weights := make(map[string]float64, len(original)+len(expansion))
+	for term, w := range original {
+		weights[term] += lambda * w
+	}
+	for _, t := range expansion {
+		weights[t.Term] += (1 - lambda) * t.Weight / expansionTotal
+	}
+	return topTerms(weights, len(weights))
+}
+
+// RM3Search runs BM25 with the RM3-expanded query.
+func (idx *InvertedIndex) RM3Search(query string, limit int, params RM3Params) []SearchResult {
+	terms := idx.RM3Expand(query, params)
+	weights := make(map[string]float64, len(terms))
+	for _, t := range terms {
+		weights[t.Term] = t.Weight
+	}
+	return idx.Bm25SearchTerms(weights, limit)
+}
+
+// Bm25SearchTerms scores documents by the weighted sum of the BM25 scores
+// of already analyzed terms. Documents matching none of them are left out.
+func (idx *InvertedIndex) Bm25SearchTerms(weights map[string]float64, limit int) []SearchResult {
+	cfg := config.Get().Keyword
+	n := float64(len(idx.DocMap))
+	avgDocLength := idx.getAvgDocLength()
+
+	scores := make(map[int]float64)
+	for term, weight := range weights {
+		docIDs := idx.Index[term]
+		df := float64(len(docIDs))
+		idf := math.Log((n-df+0.5)/(df+0.5) + 1)
+		for docID := range docIDs {
+			tf := float64(idx.TermFrequencies[docID][term])
+			lengthNorm := 1 - cfg.B + cfg.B*(float64(idx.DocLengths[docID])/avgDocLength)
+			scores[docID] += weight * idf * (tf * (cfg.K1 + 1)) / (tf + cfg.K1*lengthNorm)
+		}
+	}
+
+	results := make([]SearchResult, 0, len(scores))
+	for docID, score := range scores {
+		results = append(results, SearchResult{DocID: docID, Score: score, Movie: idx.DocMap[docID]})
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].DocID < results[j].DocID
+	})
+
+	if limit < len(results) {
+		results = results[:limit]
+	}
+	return results
+}
+
+// topTerms returns the k heaviest terms, strongest first (ties by term).
+func topTerms(weights map[string]float64, k int) []WeightedTerm {
+	terms := make([]WeightedTerm, 0, len(weights))
+	for t, w := range weights {
+		terms = append(terms, WeightedTerm{Term: t, Weight: w})
+	}
+	sort.Slice(terms, func(i, j int) bool {
+		if terms[i].Weight != terms[j].Weight {
+			return terms[i].Weight > terms[j].Weight
+		}
+		return terms[i].Term < terms[j].Term
+	})
+	if k < len(terms) {
+		terms = terms[:k]
+	}
+	return terms
+}
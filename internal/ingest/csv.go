@@ -0,0 +1,65 @@
+package ingest
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/model"
+)
+
+// readCSV expects a header row. Columns are mapped onto documents through
+// opts.Fields, any other column ends up in the document fields.
+func readCSV(path string, opts Options) ([]model.Movie, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed reading header of %s: %w", path, err)
+	}
+	for i, col := range header {
+		header[i] = strings.TrimSpace(strings.TrimPrefix(col, "\ufeff"))
+	}
+
+	if !slices.Contains(header, opts.Fields.Title) && !slices.Contains(header, opts.Fields.Description) {
+		return nil, fmt.Errorf(
+			"%s: neither title column %q nor description column %q found (columns: %s)",
+			path,
+			opts.Fields.Title,
+			opts.Fields.Description,
+			strings.Join(header, ", "),
+		)
+	}
+
+	docs := make([]model.Movie, 0)
+	for {
+		row, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed parsing %s: %w", path, err)
+		}
+
+		record := make(map[string]any, len(header))
+		for i, col := range header {
+			if i < len(row) {
+				record[col] = row[i]
+			}
+		}
+		docs = append(docs, recordToMovie(record, opts.Fields))
+	}
+
+	return docs, nil
+}
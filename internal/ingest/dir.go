@@ -0,0 +1,104 @@
+package ingest
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/model"
+	"go.yaml.in/yaml/v3"
+)
+
+var textExtensions = map[string]struct{}{
+	".txt": {},
+	".md":  {},
+}
+
+// readDir walks root and turns every .txt/.md file into one document.
+// Front matter keys are mapped like any other record, the body becomes the
+// description and the file name is the fallback title.
+func readDir(root string, opts Options) ([]model.Movie, error) {
+	docs := make([]model.Movie, 0)
+
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		if _, ok := textExtensions[strings.ToLower(filepath.Ext(path))]; !ok {
+			return nil
+		}
+
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", path, err)
+		}
+
+		frontMatter, body, err := splitFrontMatter(raw)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+
+		doc := recordToMovie(frontMatter, opts.Fields)
+		if doc.Title == "" {
+			name := filepath.Base(path)
+			doc.Title = strings.TrimSuffix(name, filepath.Ext(name))
+		}
+		if doc.Description == "" {
+			doc.Description = strings.TrimSpace(body)
+		}
+
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			rel = path
+		}
+		setField(&doc, "path", rel)
+
+		docs = append(docs, doc)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return docs, nil
+}
+
+// splitFrontMatter separates a leading YAML block delimited by "---" lines
+// from the rest of the file. Files without one return an empty record.
+func splitFrontMatter(raw []byte) (map[string]any, string, error) {
+	raw = bytes.TrimPrefix(raw, []byte("\ufeff"))
+	text := strings.ReplaceAll(string(raw), "\r\n", "\n")
+
+	if !strings.HasPrefix(text, "---\n") {
+		return map[string]any{}, text, nil
+	}
+
+	// The block ends at the next line that is exactly "---"
+	lines := strings.SplitAfter(text, "\n")
+	end := -1
+	for i := 1; i < len(lines); i++ {
+		if strings.TrimSuffix(lines[i], "\n") == "---" {
+			end = i
+			break
+		}
+	}
+	if end < 0 {
+		return map[string]any{}, text, nil
+	}
+
+	header := strings.Join(lines[1:end], "")
+	body := strings.Join(lines[end+1:], "")
+
+	record := make(map[string]any)
+	if err := yaml.Unmarshal([]byte(header), &record); err != nil {
+		return nil, "", fmt.Errorf("invalid front matter: %w", err)
+	}
+
+	return record, body, nil
+}
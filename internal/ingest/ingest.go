@@ -0,0 +1,181 @@
+package ingest
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/model"
+)
+
+const (
+	FormatAuto  = "auto"
+	FormatJSON  = "json"
+	FormatJSONL = "jsonl"
+	FormatCSV   = "csv"
+	FormatDir   = "dir"
+)
+
+var Formats = []string{FormatAuto, FormatJSON, FormatJSONL, FormatCSV, FormatDir}
+
+// FieldMap tells the readers which source field (JSON key, CSV column or
+// front matter key) feeds each document attribute.
+type FieldMap struct {
+	ID          string
+	Title       string
+	Description string
+}
+
+func DefaultFieldMap() FieldMap {
+	return FieldMap{
+		ID:          "id",
+		Title:       "title",
+		Description: "description",
+	}
+}
+
+type Options struct {
+	Format string
+	Fields FieldMap
+	// ArrayKey is the key holding the documents when a JSON source is an
+	// object instead of a top-level array (e.g. "movies").
+	ArrayKey string
+}
+
+// Load reads every document from source. The format is inferred from the
+// file extension (or the fact that source is a directory) unless set explicitly.
+func Load(source string, opts Options) ([]model.Movie, error) {
+	format, err := DetectFormat(source, opts.Format)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.Fields == (FieldMap{}) {
+		opts.Fields = DefaultFieldMap()
+	}
+
+	var docs []model.Movie
+	switch format {
+	case FormatJSON:
+		docs, err = readJSON(source, opts)
+	case FormatJSONL:
+		docs, err = readJSONL(source, opts)
+	case FormatCSV:
+		docs, err = readCSV(source, opts)
+	case FormatDir:
+		docs, err = readDir(source, opts)
+	default:
+		return nil, fmt.Errorf("unsupported format %q", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return assignMissingIDs(docs), nil
+}
+
+// DetectFormat returns format, or the one inferred from source when it's
+// empty or auto.
+func DetectFormat(source string, format string) (string, error) {
+	info, err := os.Stat(source)
+	if err != nil {
+		return "", fmt.Errorf("failed to read source %s: %w", source, err)
+	}
+
+	if format != "" && format != FormatAuto {
+		return format, nil
+	}
+
+	if info.IsDir() {
+		return FormatDir, nil
+	}
+
+	switch strings.ToLower(filepath.Ext(source)) {
+	case ".json":
+		return FormatJSON, nil
+	case ".jsonl", ".ndjson":
+		return FormatJSONL, nil
+	case ".csv":
+		return FormatCSV, nil
+	}
+
+	return "", fmt.Errorf("could not infer format of %s, use --format", source)
+}
+
+// recordToMovie maps a flat record onto a document. Keys not claimed by the
+// field map are kept in Fields so nothing from the source is lost.
+func recordToMovie(record map[string]any, fields FieldMap) model.Movie {
+	doc := model.Movie{}
+
+	for key, raw := range record {
+		value := stringify(raw)
+
+		switch key {
+		case fields.ID:
+			if id, err := strconv.Atoi(value); err == nil {
+				doc.ID = id
+			} else if value != "" {
+				setField(&doc, "source_id", value)
+			}
+		case fields.Title:
+			doc.Title = value
+		case fields.Description:
+			doc.Description = value
+		default:
+			if value != "" {
+				setField(&doc, key, value)
+			}
+		}
+	}
+
+	return doc
+}
+
+func setField(doc *model.Movie, key string, value string) {
+	if doc.Fields == nil {
+		doc.Fields = make(map[string]string)
+	}
+	doc.Fields[key] = value
+}
+
+func stringify(v any) string {
+	switch t := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return strings.TrimSpace(t)
+	case float64:
+		return strconv.FormatFloat(t, 'f', -1, 64)
+	case []any:
+		parts := make([]string, 0, len(t))
+		for _, item := range t {
+			parts = append(parts, stringify(item))
+		}
+		return strings.Join(parts, ", ")
+	default:
+		return strings.TrimSpace(fmt.Sprint(t))
+	}
+}
+
+// assignMissingIDs gives documents without a numeric id (or with a duplicate
+// one) a fresh id after the highest one seen, keeping ids unique.
+func assignMissingIDs(docs []model.Movie) []model.Movie {
+	seen := make(map[int]struct{}, len(docs))
+	maxID := 0
+	for _, doc := range docs {
+		maxID = max(maxID, doc.ID)
+	}
+
+	for i := range docs {
+		_, dup := seen[docs[i].ID]
+		if docs[i].ID <= 0 || dup {
+			maxID++
+			docs[i].ID = maxID
+		}
+		seen[docs[i].ID] = struct{}{}
+	}
+
+	return docs
+}
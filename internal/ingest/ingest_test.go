@@ -0,0 +1,195 @@
+package ingest
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/model"
+)
+
+func writeFile(t *testing.T, name string, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestSplitFrontMatter(t *testing.T) {
+	tests := []struct {
+		name       string
+		raw        string
+		wantRecord map[string]any
+		wantBody   string
+		wantErr    bool
+	}{
+		{
+			name:       "no front matter",
+			raw:        "Just text.\n",
+			wantRecord: map[string]any{},
+			wantBody:   "Just text.\n",
+		},
+		{
+			name:       "front matter",
+			raw:        "---\ntitle: Merlin\nyear: 1998\n---\nA wizard.\n",
+			wantRecord: map[string]any{"title": "Merlin", "year": 1998},
+			wantBody:   "A wizard.\n",
+		},
+		{
+			name:       "empty block",
+			raw:        "---\n---\nBody.",
+			wantRecord: map[string]any{},
+			wantBody:   "Body.",
+		},
+		{
+			name:       "ends only at an exact --- line",
+			raw:        "---\ntitle: A --- B\nnote: ---x\n---\nBody.",
+			wantRecord: map[string]any{"title": "A --- B", "note": "---x"},
+			wantBody:   "Body.",
+		},
+		{
+			name:       "CRLF and BOM",
+			raw:        "\ufeff---\r\ntitle: Noir\r\n---\r\nRain.\r\n",
+			wantRecord: map[string]any{"title": "Noir"},
+			wantBody:   "Rain.\n",
+		},
+		{
+			name:       "unterminated block is body",
+			raw:        "---\ntitle: Open\n",
+			wantRecord: map[string]any{},
+			wantBody:   "---\ntitle: Open\n",
+		},
+		{
+			name:    "invalid yaml",
+			raw:     "---\ntitle: [unclosed\n---\nBody.",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			record, body, err := splitFrontMatter([]byte(tt.raw))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, want error: %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if !reflect.DeepEqual(record, tt.wantRecord) {
+				t.Errorf("record = %#v, want %#v", record, tt.wantRecord)
+			}
+			if body != tt.wantBody {
+				t.Errorf("body = %q, want %q", body, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestLoadCSV(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		fields  FieldMap
+		want    []model.Movie
+		wantErr bool
+	}{
+		{
+			name:    "default columns and extra fields",
+			content: "\ufeffid,title,description,year\n3,Heat,A heist.,1995\n1,Up,\"A house, balloons.\",\n",
+			want: []model.Movie{
+				{ID: 3, Title: "Heat", Description: "A heist.", Fields: map[string]string{"year": "1995"}},
+				{ID: 1, Title: "Up", Description: "A house, balloons."},
+			},
+		},
+		{
+			name:    "mapped columns, short rows and text ids",
+			content: "key,name,plot\ntt01,Alien,In space.\ntt02,Brazil\n",
+			fields:  FieldMap{ID: "key", Title: "name", Description: "plot"},
+			want: []model.Movie{
+				{ID: 1, Title: "Alien", Description: "In space.", Fields: map[string]string{"source_id": "tt01"}},
+				{ID: 2, Title: "Brazil", Fields: map[string]string{"source_id": "tt02"}},
+			},
+		},
+		{
+			name:    "no title or description column",
+			content: "id,name\n1,Heat\n",
+			wantErr: true,
+		},
+		{
+			name:    "empty file",
+			content: "",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeFile(t, "movies.csv", tt.content)
+			docs, err := Load(path, Options{Fields: tt.fields})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, want error: %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(docs, tt.want) {
+				t.Errorf("docs = %+v, want %+v", docs, tt.want)
+			}
+		})
+	}
+}
+
+func TestAssignMissingIDs(t *testing.T) {
+	tests := []struct {
+		name string
+		ids  []int
+		want []int
+	}{
+		{"all set", []int{1, 2, 3}, []int{1, 2, 3}},
+		{"missing get ids after the highest", []int{0, 5, 0}, []int{6, 5, 7}},
+		{"duplicates keep the first", []int{2, 2, 1}, []int{2, 3, 1}},
+		{"negative ids", []int{-1, 4}, []int{5, 4}},
+		{"none", nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			docs := make([]model.Movie, len(tt.ids))
+			for i, id := range tt.ids {
+				docs[i].ID = id
+			}
+			var got []int
+			for _, doc := range assignMissingIDs(docs) {
+				got = append(got, doc.ID)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ids = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDetectFormat(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		source  string
+		format  string
+		want    string
+		wantErr bool
+	}{
+		{source: writeFile(t, "a.json", "[]"), want: FormatJSON},
+		{source: writeFile(t, "a.NDJSON", ""), want: FormatJSONL},
+		{source: writeFile(t, "a.csv", ""), format: FormatAuto, want: FormatCSV},
+		{source: dir, want: FormatDir},
+		{source: writeFile(t, "a.txt", ""), format: FormatCSV, want: FormatCSV},
+		{source: writeFile(t, "a.txt", ""), wantErr: true},
+		{source: filepath.Join(dir, "missing.json"), wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := DetectFormat(tt.source, tt.format)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("DetectFormat(%s, %q) error = %v, want error: %v", tt.source, tt.format, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("DetectFormat(%s, %q) = %q, want %q", tt.source, tt.format, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package ingest
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/model"
+)
+
+// readJSON accepts either a top-level array of objects or an object holding
+// that array (like data/movies.json and its "movies" key).
+func readJSON(path string, opts Options) ([]model.Movie, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+
+	var data any
+	if err := decoder.Decode(&data); err != nil {
+		return nil, fmt.Errorf("failed parsing %s: %w", path, err)
+	}
+
+	var items []any
+	switch t := data.(type) {
+	case []any:
+		items = t
+	case map[string]any:
+		items, err = findArray(t, opts.ArrayKey)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	default:
+		return nil, fmt.Errorf("%s: expected a JSON array or object", path)
+	}
+
+	docs := make([]model.Movie, 0, len(items))
+	for i, item := range items {
+		record, ok := item.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("%s: item %d is not an object", path, i)
+		}
+		docs = append(docs, recordToMovie(record, opts.Fields))
+	}
+
+	return docs, nil
+}
+
+func findArray(obj map[string]any, key string) ([]any, error) {
+	if key != "" {
+		items, ok := obj[key].([]any)
+		if !ok {
+			return nil, fmt.Errorf("key %q is not an array", key)
+		}
+		return items, nil
+	}
+
+	// Without an explicit key, use the only array-valued field
+	var keys []string
+	for k, v := range obj {
+		if _, ok := v.([]any); ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	switch len(keys) {
+	case 0:
+		return nil, fmt.Errorf("no array of documents found")
+	case 1:
+		return obj[keys[0]].([]any), nil
+	default:
+		return nil, fmt.Errorf("several arrays found (%s), use --arrayKey", strings.Join(keys, ", "))
+	}
+}
+
+// readJSONL reads one JSON object per line, skipping blank lines.
+func readJSONL(path string, opts Options) ([]model.Movie, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+
+	docs := make([]model.Movie, 0)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		decoder := json.NewDecoder(bytes.NewReader(line))
+		decoder.UseNumber()
+
+		var record map[string]any
+		if err := decoder.Decode(&record); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, lineNum, err)
+		}
+		docs = append(docs, recordToMovie(record, opts.Fields))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	return docs, nil
+}
@@ -0,0 +1,40 @@
+package llms
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/fs"
+)
+
+// enrichVersion is part of the enrichment cache key; bump it when the
+// prompt changes so old fields are regenerated.
+const enrichVersion = 1
+
+// MovieKeywords returns a comma-separated list of genres, themes, setting
+// and characters of a movie: a field for the words a description implies
+// but doesn't always use.
+func MovieKeywords(ctx context.Context, title string, description string) (string, error) {
+	prompt := fmt.Sprintf(`List search keywords for this movie.
+
+Movie Title: %s
+Movie Description: %s
+
+Give its genres, themes, setting, mood and main characters as one comma-separated line
+of short keywords that people might search for. Answer only with the keywords.`,
+		title,
+		strings.Join(strings.Fields(description), " "),
+	)
+
+	text, _, err := Generate(ctx, prompt)
+	if err != nil {
+		return "", err
+	}
+	return strings.Join(strings.Fields(text), " "), nil
+}
+
+// OpenEnrichCache loads the generated fields of the configured LLM.
+func OpenEnrichCache() (*TextCache, error) {
+	return openTextCache(fs.EnrichCacheDir, enrichVersion)
+}
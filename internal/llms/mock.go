@@ -0,0 +1,237 @@
+package llms
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/fs"
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/tokenizer"
+	"go.yaml.in/yaml/v3"
+)
+
+// MockRule is a canned response from a mock script: the first rule whose
+// Match regexp matches the prompt wins.
+type MockRule struct {
+	Match    string `yaml:"match"`
+	Response string `yaml:"response"`
+
+	re *regexp.Regexp
+}
+
+// Mock answers every prompt in this package without a model. Scripted
+// rules (llm.mock_script) are tried first; otherwise the prompt is
+// recognised and answered with a simple rule:
+//   - spell/rewrite/expand: the query unchanged
+//   - keywords: the title and the first sentence of the description
+//   - query classifier: conceptual for 4+ words, otherwise mixed
+//   - rerank, evaluate, cross-encoder: query/document token overlap
+//   - RAG: an extractive answer from the first sentence of each document
+type Mock struct {
+	rules     []MockRule
+	stopWords map[string]struct{}
+}
+
+func NewMock(scriptPath string) (*Mock, error) {
+	m := &Mock{}
+	m.stopWords, _ = fs.LoadStopWords()
+
+	if scriptPath == "" {
+		return m, nil
+	}
+
+	raw, err := os.ReadFile(scriptPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read mock script: %w", err)
+	}
+	if err := yaml.Unmarshal(raw, &m.rules); err != nil {
+		return nil, fmt.Errorf("failed parsing mock script %s: %w", scriptPath, err)
+	}
+	for i := range m.rules {
+		re, err := regexp.Compile(m.rules[i].Match)
+		if err != nil {
+			return nil, fmt.Errorf("mock script rule %d: %w", i+1, err)
+		}
+		m.rules[i].re = re
+	}
+
+	return m, nil
+}
+
+var (
+	mockQueryRe = regexp.MustCompile(`(?m)^\s*(?:Query|Question|Original|Text query): "?(.*?)"?\s*$`)
+	mockDocRe   = regexp.MustCompile(`(?m)^\s*ID: (\d+)\n\s*Title: (.*)\n\s*(?:Description|Passages): (.*)$`)
+	mockTitleRe = regexp.MustCompile(`(?m)^Movie Title: (.*)$`)
+	mockDescRe  = regexp.MustCompile(`(?m)^Movie Description: (.*)$`)
+)
+
+func (m *Mock) Generate(ctx context.Context, prompt string) (string, int, error) {
+	response := m.respond(prompt)
+	return response, mockTokens(prompt, response), nil
+}
+
+func (m *Mock) GenerateMultimodal(ctx context.Context, prompt string, img []byte, mime string) (string, int, error) {
+	for _, rule := range m.rules {
+		if rule.re.MatchString(prompt) {
+			return rule.Response, mockTokens(prompt, rule.Response), nil
+		}
+	}
+
+	response := fmt.Sprintf("An image (%s, %d bytes).", mime, len(img))
+	if query := match(mockQueryRe, prompt); query != "" {
+		// Query rewrite from image: nothing to add without a model
+		response = query
+	}
+	return response, mockTokens(prompt, response), nil
+}
+
+func (m *Mock) CrossEncode(ctx context.Context, query string, docs []string) ([]float64, error) {
+	scores := make([]float64, len(docs))
+	for i, doc := range docs {
+		scores[i] = m.overlap(query, doc)
+	}
+	return scores, nil
+}
+
+func (m *Mock) respond(prompt string) string {
+	for _, rule := range m.rules {
+		if rule.re.MatchString(prompt) {
+			return rule.Response
+		}
+	}
+
+	query := match(mockQueryRe, prompt)
+	task := strings.TrimSpace(prompt)
+
+	switch {
+	case strings.HasPrefix(task, "Fix any spelling errors"),
+		strings.HasPrefix(task, "Rewrite this movie search query"),
+		strings.HasPrefix(task, "Expand this movie search query"):
+		return query
+
+	case strings.HasPrefix(task, "Write a short hypothetical movie description"):
+		return "A movie about " + query + "."
+
+	case strings.HasPrefix(task, "Classify this movie search query"):
+		if len(strings.Fields(query)) >= 4 {
+			return "conceptual: a description of what to watch"
+		}
+		return "mixed: a short query"
+
+	case strings.HasPrefix(task, "List search keywords for this movie"):
+		sentence, _, _ := strings.Cut(match(mockDescRe, prompt), ". ")
+		return match(mockTitleRe, prompt) + ", " + strings.TrimSuffix(sentence, ".")
+
+	case strings.HasPrefix(task, "Situate this chunk"):
+		return "This passage is from the movie " + match(mockTitleRe, prompt) + "."
+
+	case strings.HasPrefix(task, "You are a movie search relevance rater"):
+		doc := match(mockTitleRe, prompt) + " " + match(mockDescRe, prompt)
+		return strconv.Itoa(int(math.Round(10 * m.overlap(query, doc))))
+
+	case strings.HasPrefix(task, "Rank these movies by relevance"):
+		docs := parseMockDocs(prompt)
+		sort.SliceStable(docs, func(i, j int) bool {
+			return m.overlap(query, docs[i].text) > m.overlap(query, docs[j].text)
+		})
+		ids := make([]int, len(docs))
+		for i, doc := range docs {
+			ids[i] = doc.id
+		}
+		raw, _ := json.Marshal(ids)
+		return string(raw)
+
+	case strings.HasPrefix(task, "Rate how relevant each result"):
+		docs := parseMockDocs(prompt)
+		scores := make([]int, len(docs))
+		for i, doc := range docs {
+			scores[i] = int(math.Round(3 * m.overlap(query, doc.text)))
+		}
+		raw, _ := json.Marshal(scores)
+		return string(raw)
+
+	case strings.Contains(prompt, "Documents:"), strings.Contains(prompt, "Search Results:"):
+		return extractiveAnswer(query, parseMockDocs(prompt), strings.Contains(prompt, "[1], [2]"))
+
+	default:
+		return "I don't have enough information"
+	}
+}
+
+// overlap is the fraction of analyzed query tokens found in text.
+func (m *Mock) overlap(query string, text string) float64 {
+	queryTokens := tokenizer.Tokenize(query, m.stopWords)
+	if len(queryTokens) == 0 {
+		return 0
+	}
+
+	textTokens := make(map[string]struct{})
+	for _, t := range tokenizer.Tokenize(text, m.stopWords) {
+		textTokens[t] = struct{}{}
+	}
+
+	hits := 0
+	for _, t := range queryTokens {
+		if _, ok := textTokens[t]; ok {
+			hits++
+		}
+	}
+	return float64(hits) / float64(len(queryTokens))
+}
+
+type mockDoc struct {
+	id          int
+	title       string
+	description string
+	text        string
+}
+
+func parseMockDocs(prompt string) []mockDoc {
+	var docs []mockDoc
+	for _, m := range mockDocRe.FindAllStringSubmatch(prompt, -1) {
+		id, _ := strconv.Atoi(m[1])
+		docs = append(docs, mockDoc{
+			id:          id,
+			title:       strings.TrimSpace(m[2]),
+			description: strings.TrimSpace(m[3]),
+			text:        m[2] + " " + m[3],
+		})
+	}
+	return docs
+}
+
+func extractiveAnswer(query string, docs []mockDoc, cite bool) string {
+	if len(docs) == 0 {
+		return "I don't have enough information"
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Based on the search results for %q:", query)
+	for i, doc := range docs[:min(3, len(docs))] {
+		sentence, _, _ := strings.Cut(doc.description, ". ")
+		fmt.Fprintf(&b, " %s: %s.", doc.title, strings.TrimSuffix(sentence, "."))
+		if cite {
+			fmt.Fprintf(&b, " [%d]", i+1)
+		}
+	}
+	return b.String()
+}
+
+func match(re *regexp.Regexp, s string) string {
+	m := re.FindStringSubmatch(s)
+	if m == nil {
+		return ""
+	}
+	return strings.TrimSpace(m[1])
+}
+
+// mockTokens approximates token usage (~4 characters per token).
+func mockTokens(prompt string, response string) int {
+	return (len(prompt) + len(response)) / 4
+}
@@ -16,7 +16,7 @@ Make sure to:
 Text query: %s`, query)
 
 	// Call the generic multimodal generation function
-	text, totalTokens, err := GeminiMultimodalGenerateContent(ctx, prompt, img, mime)
+	text, totalTokens, err := GenerateMultimodal(ctx, prompt, img, mime)
 	if err != nil {
 		return "", 0, err
 	}
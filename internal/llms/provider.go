@@ -0,0 +1,85 @@
+package llms
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/config"
+)
+
+const (
+	ProviderGemini = "gemini" // Gemini for generation, Cohere for cross-encoder reranking
+	ProviderMock   = "mock"   // scripted/rule-based responses, no network (used by --offline)
+)
+
+var Providers = []string{ProviderGemini, ProviderMock}
+
+// Provider is the backend behind every prompt in this package.
+type Provider interface {
+	// Generate returns the response text and the total tokens used.
+	Generate(ctx context.Context, prompt string) (string, int, error)
+	GenerateMultimodal(ctx context.Context, prompt string, img []byte, mime string) (string, int, error)
+	// CrossEncode scores each document's relevance to query.
+	CrossEncode(ctx context.Context, query string, docs []string) ([]float64, error)
+}
+
+var (
+	mockOnce sync.Once
+	mock     *Mock
+	mockErr  error
+)
+
+// Current returns the provider selected by llm.provider.
+func Current() (Provider, error) {
+	switch config.Get().LLM.Provider {
+	case ProviderGemini, "":
+		return gemini{}, nil
+	case ProviderMock:
+		mockOnce.Do(func() {
+			mock, mockErr = NewMock(config.Get().LLM.MockScript)
+		})
+		return mock, mockErr
+	default:
+		return nil, fmt.Errorf("unknown llm provider %q (allowed: %s)", config.Get().LLM.Provider, strings.Join(Providers, ", "))
+	}
+}
+
+func Generate(ctx context.Context, prompt string) (string, int, error) {
+	p, err := Current()
+	if err != nil {
+		return "", 0, err
+	}
+	return p.Generate(ctx, prompt)
+}
+
+func GenerateMultimodal(ctx context.Context, prompt string, img []byte, mime string) (string, int, error) {
+	p, err := Current()
+	if err != nil {
+		return "", 0, err
+	}
+	return p.GenerateMultimodal(ctx, prompt, img, mime)
+}
+
+func CrossEncode(ctx context.Context, query string, docs []string) ([]float64, error) {
+	p, err := Current()
+	if err != nil {
+		return nil, err
+	}
+	return p.CrossEncode(ctx, query, docs)
+}
+
+type gemini struct{}
+
+func (gemini) Generate(ctx context.Context, prompt string) (string, int, error) {
+	return GeminiGenerateContent(ctx, prompt)
+}
+
+func (gemini) GenerateMultimodal(ctx context.Context, prompt string, img []byte, mime string) (string, int, error) {
+	return GeminiMultimodalGenerateContent(ctx, prompt, img, mime)
+}
+
+func (gemini) CrossEncode(ctx context.Context, query string, docs []string) ([]float64, error) {
+	return CohereRerankCrossEncoder(ctx, query, docs)
+}
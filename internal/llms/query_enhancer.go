@@ -3,8 +3,19 @@ package llms
 import (
 	"context"
 	"fmt"
+	"strings"
 )
 
+// Query enhancement modes (--enhance).
+const (
+	EnhanceSpell   = "spell"
+	EnhanceRewrite = "rewrite"
+	EnhanceExpand  = "expand"
+	EnhanceHyDE    = "hyde" // hypothetical document: replaces the query for the semantic leg only
+)
+
+var Enhancements = []string{EnhanceSpell, EnhanceRewrite, EnhanceExpand, EnhanceHyDE}
+
 func QueryEnhanceSpell(ctx context.Context, query string) (string, error) {
 
 	// Build the text prompt
@@ -19,7 +30,7 @@ func QueryEnhanceSpell(ctx context.Context, query string) (string, error) {
 	)
 
 	// Call llm
-	text, _, err := GeminiGenerateContent(ctx, prompt)
+	text, _, err := Generate(ctx, prompt)
 	if err != nil {
 		return "", err
 	}
@@ -52,7 +63,7 @@ func QueryEnhanceRewrite(ctx context.Context, query string) (string, error) {
 	)
 
 	// Call llm
-	text, _, err := GeminiGenerateContent(ctx, prompt)
+	text, _, err := Generate(ctx, prompt)
 	if err != nil {
 		return "", err
 	}
@@ -82,7 +93,32 @@ func QueryEnhanceExpand(ctx context.Context, query string) (string, error) {
 	)
 
 	// Call llm
-	text, _, err := GeminiGenerateContent(ctx, prompt)
+	text, _, err := Generate(ctx, prompt)
+	if err != nil {
+		return "", err
+	}
+	return text, nil
+}
+
+// QueryEnhanceHyDE writes a short hypothetical movie description answering
+// the query (Hypothetical Document Embeddings): it's embedded in place of
+// the query, since it looks more like the descriptions being searched.
+func QueryEnhanceHyDE(ctx context.Context, query string) (string, error) {
+
+	// Build the text prompt
+	prompt := fmt.Sprintf(`Write a short hypothetical movie description that answers this movie search query.
+
+		Query: "%s"
+
+    Write it like the plot description of a movie catalog entry (2-4 sentences).
+    Mention the kind of characters, setting, genre and events the searcher is looking for.
+    It doesn't need to describe a real movie.
+    Just return the description (without a title or additional text).`,
+		query,
+	)
+
+	// Call llm
+	text, _, err := Generate(ctx, prompt)
 	if err != nil {
 		return "", err
 	}
@@ -91,13 +127,42 @@ func QueryEnhanceExpand(ctx context.Context, query string) (string, error) {
 
 func PreProcessQuery(ctx context.Context, query string, enhance string) (string, error) {
 	switch enhance {
-	case "spell":
+	case EnhanceSpell:
 		return QueryEnhanceSpell(ctx, query)
-	case "rewrite":
+	case EnhanceRewrite:
 		return QueryEnhanceRewrite(ctx, query)
-	case "expand":
+	case EnhanceExpand:
 		return QueryEnhanceExpand(ctx, query)
+	case EnhanceHyDE:
+		return QueryEnhanceHyDE(ctx, query)
 	default:
 		return query, nil
 	}
 }
+
+// ClassifyQuery asks the LLM whether query names a movie (exact), describes
+// what to watch (conceptual) or both (mixed). It returns the lowercased
+// class and the model's one-line reason.
+func ClassifyQuery(ctx context.Context, query string) (string, string, error) {
+	prompt := fmt.Sprintf(`Classify this movie search query.
+
+Query: "%s"
+
+Answer with one of these classes, a colon and a short reason, on one line:
+- exact: the query is (part of) a movie title or a quoted phrase, so keyword matches matter most
+- conceptual: the query describes a kind of movie, theme or mood, so meaning matters most
+- mixed: the query combines specific terms (names, years, places) with a concept
+
+Example: "conceptual: asks for a genre and mood, not a title"`,
+		query,
+	)
+
+	text, _, err := Generate(ctx, prompt)
+	if err != nil {
+		return "", "", err
+	}
+	line, _, _ := strings.Cut(strings.TrimSpace(text), "\n")
+	class, reason, _ := strings.Cut(line, ":")
+	class = strings.ToLower(strings.Trim(strings.TrimSpace(class), `"*-`))
+	return class, strings.TrimSpace(reason), nil
+}
@@ -9,6 +9,8 @@ import (
 	"net/http"
 	"os"
 	"strconv"
+
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/config"
 )
 
 type CohereRerankRequest struct {
@@ -61,7 +63,7 @@ Return ONLY a single integer from 0-10, nothing else. No explanation, no text, j
 	)
 
 	// Call to llm
-	resp, _, err := GeminiGenerateContent(ctx, prompt)
+	resp, _, err := Generate(ctx, prompt)
 	if err != nil {
 		return 0.0, err
 	}
@@ -107,7 +109,7 @@ func ReRankDocsBatch(ctx context.Context, query string, docsListStr string) ([]i
 	)
 
 	// Call to llm
-	jsonData, _, err := GeminiGenerateContent(ctx, prompt)
+	jsonData, _, err := Generate(ctx, prompt)
 	if err != nil {
 		return nil, err
 	}
@@ -140,7 +142,7 @@ func CohereRerankCrossEncoder(
 	}
 
 	reqBody := CohereRerankRequest{
-		Model:     "rerank-english-v3.0",
+		Model:     config.Get().Rerank.CohereModel,
 		Query:     query,
 		Documents: docs,
 	}
@@ -0,0 +1,51 @@
+package llms
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/config"
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/fs"
+)
+
+// situateVersion is part of the situating cache key; bump it when the
+// prompt changes so old sentences are regenerated.
+const situateVersion = 1
+
+// SituateChunk returns one sentence placing chunk within its movie, to be
+// embedded together with the chunk.
+func SituateChunk(ctx context.Context, title string, description string, chunk string) (string, error) {
+	prompt := fmt.Sprintf(`Situate this chunk within its movie description.
+
+Movie Title: %s
+Movie Description: %s
+Chunk: %s
+
+Write one short sentence that places the chunk within the movie (who or what it is about),
+to improve search retrieval of the chunk. Answer only with the sentence.`,
+		title,
+		strings.Join(strings.Fields(description), " "),
+		strings.Join(strings.Fields(chunk), " "),
+	)
+
+	text, _, err := Generate(ctx, prompt)
+	if err != nil {
+		return "", err
+	}
+	return strings.Join(strings.Fields(text), " "), nil
+}
+
+// ModelName identifies the configured LLM, e.g. "gemini:gemini-3-flash-preview".
+func ModelName() string {
+	cfg := config.Get().LLM
+	if cfg.Provider == ProviderMock {
+		return ProviderMock
+	}
+	return cfg.Provider + ":" + cfg.Model
+}
+
+// OpenSituateCache loads the situating sentences of the configured LLM.
+func OpenSituateCache() (*TextCache, error) {
+	return openTextCache(fs.SituateCacheDir, situateVersion)
+}
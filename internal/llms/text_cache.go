@@ -0,0 +1,83 @@
+package llms
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/fs"
+)
+
+var unsafeChars = regexp.MustCompile(`[^a-zA-Z0-9._-]+`)
+
+// TextCache stores what one model generated for a task, keyed by a hash of
+// the inputs, so only new or changed inputs reach the LLM.
+type TextCache struct {
+	Path    string
+	Texts   map[string]string
+	version int
+	dirty   bool
+}
+
+// openTextCache loads the cache of the configured LLM in dir; a missing
+// file is an empty cache. version is part of every key: bump it when the
+// prompt changes so old texts are regenerated.
+func openTextCache(dir string, version int) (*TextCache, error) {
+	safe := strings.Trim(unsafeChars.ReplaceAllString(ModelName(), "_"), "_")
+	c := &TextCache{
+		Path:    filepath.Join(dir, safe+".json"),
+		Texts:   make(map[string]string),
+		version: version,
+	}
+
+	data, err := os.ReadFile(c.Path)
+	if os.IsNotExist(err) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &c.Texts); err != nil {
+		return nil, fmt.Errorf("failed to read LLM cache %s: %w", c.Path, err)
+	}
+	return c, nil
+}
+
+// Key is the cache key of a set of inputs.
+func (c *TextCache) Key(inputs ...string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "v%d", c.version)
+	for _, input := range inputs {
+		b.WriteString("\x00" + input)
+	}
+	sum := sha256.Sum256([]byte(b.String()))
+	return hex.EncodeToString(sum[:])
+}
+
+func (c *TextCache) Put(key string, text string) {
+	c.Texts[key] = text
+	c.dirty = true
+}
+
+// Save writes the cache if anything was added.
+func (c *TextCache) Save() error {
+	if !c.dirty {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(c.Path), os.ModePerm); err != nil {
+		return err
+	}
+	err := fs.WriteAtomic(c.Path, func(w io.Writer) error {
+		return json.NewEncoder(w).Encode(c.Texts)
+	})
+	if err == nil {
+		c.dirty = false
+	}
+	return err
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/config"
 	"google.golang.org/genai"
 )
 
@@ -28,7 +29,7 @@ func GeminiGenerateContent(ctx context.Context, prompt string) (string, int, err
 	}
 
 	// Call GenerateContent
-	response, err := client.Models.GenerateContent(ctx, "gemini-3-flash-preview", contents, nil)
+	response, err := client.Models.GenerateContent(ctx, config.Get().LLM.Model, contents, nil)
 	if err != nil {
 		return "", 0, fmt.Errorf("generate content error: %w", err)
 	}
@@ -77,7 +78,7 @@ func GeminiMultimodalGenerateContent(ctx context.Context, textPrompt string, img
 	}
 
 	// Call GenerateContent
-	response, err := client.Models.GenerateContent(ctx, "gemini-3-flash-preview", contents, nil)
+	response, err := client.Models.GenerateContent(ctx, config.Get().LLM.Model, contents, nil)
 	if err != nil {
 		return "", 0, fmt.Errorf("generate content error: %w", err)
 	}
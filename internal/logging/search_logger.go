@@ -33,6 +33,57 @@ func LogEnhancedQuery(
 	)
 }
 
+// LogHyDEPassage records the hypothetical document embedded for the
+// semantic leg in place of the query.
+func LogHyDEPassage(
+	logger *slog.Logger,
+	ctx ExecutionContext,
+	query string,
+	passage string,
+) {
+	logger.Debug("hyde passage",
+		slog.String("run_id", ctx.RunID),
+		slog.String("query_id", ctx.QueryID),
+		slog.String("query", query),
+		slog.String("passage", passage),
+	)
+}
+
+// LogAlphaChoice records the alpha picked for the query by --alpha auto.
+func LogAlphaChoice(
+	logger *slog.Logger,
+	ctx ExecutionContext,
+	query string,
+	alpha float64,
+	class string,
+	classifier string,
+	reason string,
+) {
+	logger.Debug("alpha choice",
+		slog.String("run_id", ctx.RunID),
+		slog.String("query_id", ctx.QueryID),
+		slog.String("query", query),
+		slog.Float64("alpha", alpha),
+		slog.String("class", class),
+		slog.String("classifier", classifier),
+		slog.String("reason", reason),
+	)
+}
+
+// LogQueryCache records the query embedding cache lookups of the run.
+func LogQueryCache(
+	logger *slog.Logger,
+	ctx ExecutionContext,
+	hits int,
+	misses int,
+) {
+	logger.Debug("query embedding cache",
+		slog.String("run_id", ctx.RunID),
+		slog.Int("hits", hits),
+		slog.Int("misses", misses),
+	)
+}
+
 func LogRRFResults(
 	logger *slog.Logger,
 	ctx ExecutionContext,
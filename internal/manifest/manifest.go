@@ -0,0 +1,288 @@
+package manifest
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/config"
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/fs"
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/model"
+)
+
+const (
+	PolicyWarn    = "warn"
+	PolicyRebuild = "rebuild"
+	PolicyFail    = "fail"
+)
+
+var Policies = []string{PolicyWarn, PolicyRebuild, PolicyFail}
+
+// Manifest describes how a cache artifact was built, so a later run can
+// tell whether it still matches the documents and settings in use.
+type Manifest struct {
+	Artifact       string    `json:"artifact"`
+	SourceHash     string    `json:"source_hash"`
+	Documents      int       `json:"documents"`
+	Analyzer       string    `json:"analyzer,omitempty"`
+	EmbeddingModel string    `json:"embedding_model,omitempty"`
+	Dimension      int       `json:"dimension,omitempty"`
+	Vectors        int       `json:"vectors,omitempty"`
+	Normalized     bool      `json:"normalized,omitempty"` // every vector has unit length
+	DocIDs         string    `json:"doc_ids,omitempty"`    // hash of the document IDs, in order
+	Chunking       string    `json:"chunking,omitempty"`
+	Params         string    `json:"params,omitempty"`
+	BuiltAt        time.Time `json:"built_at"`
+}
+
+func pathFor(artifactPath string) string {
+	return artifactPath + ".manifest.json"
+}
+
+func Save(artifactPath string, m Manifest) error {
+	m.BuiltAt = time.Now().UTC()
+
+	raw, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	err = fs.WriteAtomic(pathFor(artifactPath), func(w io.Writer) error {
+		_, err := w.Write(raw)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write manifest for %s: %w", m.Artifact, err)
+	}
+
+	return nil
+}
+
+func Load(artifactPath string) (*Manifest, error) {
+	raw, err := os.ReadFile(pathFor(artifactPath))
+	if err != nil {
+		return nil, err
+	}
+
+	var m Manifest
+	if err := json.Unmarshal(raw, &m); err != nil {
+		return nil, fmt.Errorf("corrupt manifest %s: %w", pathFor(artifactPath), err)
+	}
+
+	return &m, nil
+}
+
+// Diff lists every field where the stored manifest doesn't match the
+// expected one. Empty expected fields are not compared.
+func Diff(expected Manifest, stored Manifest) []string {
+	var reasons []string
+
+	if expected.SourceHash != "" && expected.SourceHash != stored.SourceHash {
+		reasons = append(reasons, fmt.Sprintf(
+			"source documents changed (%d docs, hash %s → %d docs, hash %s)",
+			stored.Documents, short(stored.SourceHash), expected.Documents, short(expected.SourceHash),
+		))
+	}
+	if expected.DocIDs != "" && stored.DocIDs != "" && expected.DocIDs != stored.DocIDs {
+		reasons = append(reasons, fmt.Sprintf(
+			"document IDs or their order changed (hash %s → %s); stored vectors would map to the wrong documents",
+			short(stored.DocIDs), short(expected.DocIDs),
+		))
+	}
+	if expected.Analyzer != "" && expected.Analyzer != stored.Analyzer {
+		reasons = append(reasons, fmt.Sprintf("analyzer changed (%q → %q)", stored.Analyzer, expected.Analyzer))
+	}
+	if expected.EmbeddingModel != "" && expected.EmbeddingModel != stored.EmbeddingModel {
+		reasons = append(reasons, fmt.Sprintf("embedding model changed (%q → %q)", stored.EmbeddingModel, expected.EmbeddingModel))
+	}
+	if expected.Dimension != 0 && expected.Dimension != stored.Dimension {
+		reasons = append(reasons, fmt.Sprintf("embedding dimension changed (%d → %d)", stored.Dimension, expected.Dimension))
+	}
+	if expected.Chunking != "" && expected.Chunking != stored.Chunking {
+		reasons = append(reasons, fmt.Sprintf("chunking changed (%q → %q)", stored.Chunking, expected.Chunking))
+	}
+
+	if expected.Params != "" && expected.Params != stored.Params {
+		reasons = append(reasons, fmt.Sprintf("index parameters changed (%q → %q)", stored.Params, expected.Params))
+	}
+
+	return reasons
+}
+
+// Check compares the manifest stored next to artifactPath with expected.
+// A missing manifest counts as stale: the artifact predates manifests.
+func Check(artifactPath string, expected Manifest) []string {
+	stored, err := Load(artifactPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []string{"no manifest found (artifact built by an older version)"}
+		}
+		return []string{err.Error()}
+	}
+
+	return Diff(expected, *stored)
+}
+
+// CheckEmbeddings is Check for an embedding artifact that also validates
+// the loaded vectors against the stored manifest (count, dimension of every
+// vector, normalization) and the stored dimension against modelDim, the
+// embedder's dimension when it's known (0 otherwise).
+func CheckEmbeddings(artifactPath string, expected Manifest, vectors [][]float32, modelDim int) []string {
+	stored, err := Load(artifactPath)
+	if err != nil {
+		return Check(artifactPath, expected)
+	}
+
+	reasons := Diff(expected, *stored)
+	if stored.Vectors != 0 && len(vectors) != stored.Vectors {
+		reasons = append(reasons, fmt.Sprintf("artifact has %d vectors, manifest records %d", len(vectors), stored.Vectors))
+	}
+	if stored.Dimension != 0 {
+		for i, v := range vectors {
+			if len(v) != stored.Dimension {
+				reasons = append(reasons, fmt.Sprintf("vector %d has dimension %d, manifest records %d", i, len(v), stored.Dimension))
+				break
+			}
+		}
+	}
+	// Diff already reports it when expected carries modelDim
+	if modelDim != 0 && stored.Dimension != 0 && modelDim != stored.Dimension && expected.Dimension != modelDim {
+		reasons = append(reasons, fmt.Sprintf("%s embeds with dimension %d, stored vectors have %d", expected.EmbeddingModel, modelDim, stored.Dimension))
+	}
+	if stored.Normalized && !IsNormalized(vectors) {
+		reasons = append(reasons, "vectors are recorded as normalized but aren't unit length")
+	}
+
+	return reasons
+}
+
+// Resolve applies the configured staleness policy (cache.on_stale, or
+// fail under --strict). It reports whether the artifact must be rebuilt;
+// under the warn policy the stale artifact is kept and used.
+func Resolve(artifact string, reasons []string) (bool, error) {
+	if len(reasons) == 0 {
+		return false, nil
+	}
+
+	switch config.Get().Cache.OnStale {
+	case PolicyWarn:
+		fmt.Printf("⚠️ %s is stale, using it anyway:\n", artifact)
+		printReasons(reasons)
+		return false, nil
+	case PolicyFail:
+		return false, fmt.Errorf("%s is stale: %s (rebuild it or drop --strict)", artifact, strings.Join(reasons, "; "))
+	default:
+		fmt.Printf("🔄 %s is stale, rebuilding:\n", artifact)
+		printReasons(reasons)
+		return true, nil
+	}
+}
+
+func printReasons(reasons []string) {
+	for _, r := range reasons {
+		fmt.Printf("\t- %s\n", r)
+	}
+}
+
+// SourceHash fingerprints the document contents (order included, since
+// embeddings are stored positionally).
+func SourceHash(docs []model.Movie) string {
+	h := sha256.New()
+	for _, doc := range docs {
+		h.Write([]byte(strconv.Itoa(doc.ID)))
+		h.Write([]byte{0})
+		h.Write([]byte(doc.Title))
+		h.Write([]byte{0})
+		h.Write([]byte(doc.Description))
+		h.Write([]byte{0})
+
+		keys := make([]string, 0, len(doc.Fields))
+		for k := range doc.Fields {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			h.Write([]byte(k + "=" + doc.Fields[k]))
+			h.Write([]byte{0})
+		}
+		h.Write([]byte{1})
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// DocIDsHash fingerprints the document IDs in order: embeddings are stored
+// positionally, so a reordering breaks the vector → document mapping.
+func DocIDsHash(docs []model.Movie) string {
+	h := sha256.New()
+	for _, doc := range docs {
+		h.Write([]byte(strconv.Itoa(doc.ID)))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// IsNormalized reports whether every vector has unit length.
+func IsNormalized(vectors [][]float32) bool {
+	for _, v := range vectors {
+		var norm float64
+		for _, x := range v {
+			norm += float64(x) * float64(x)
+		}
+		if math.Abs(norm-1) > 1e-3 {
+			return false
+		}
+	}
+	return len(vectors) > 0
+}
+
+// SetVectors fills the vector fields of an embedding manifest from vectors.
+// The dimension is modelDim, the embedder's, when it's known: taking it
+// from the vectors would make a stored artifact match itself.
+func (m *Manifest) SetVectors(vectors [][]float32, modelDim int) {
+	m.Vectors = len(vectors)
+	m.Normalized = IsNormalized(vectors)
+	switch {
+	case modelDim != 0:
+		m.Dimension = modelDim
+	case len(vectors) > 0:
+		m.Dimension = len(vectors[0])
+	}
+}
+
+// HashBytes is a short content hash used for small inputs (stop words, etc.).
+func HashBytes(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])[:12]
+}
+
+// HashFile fingerprints an artifact another one was derived from (e.g. the
+// embeddings an ANN index was built over).
+func HashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func short(hash string) string {
+	if len(hash) > 12 {
+		return hash[:12]
+	}
+	return hash
+}
@@ -0,0 +1,192 @@
+package manifest
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/model"
+)
+
+func baseManifest() Manifest {
+	return Manifest{
+		Artifact:       "movie_embeddings.gob",
+		SourceHash:     "aaaaaaaaaaaaaaaa",
+		Documents:      10,
+		Analyzer:       "porter",
+		EmbeddingModel: "ollama:nomic-embed-text",
+		Dimension:      768,
+		DocIDs:         "bbbbbbbbbbbbbbbb",
+		Chunking:       "v2:sentences(max=4,overlap=1)",
+		Params:         "hnsw(M=16,efConstruction=200)",
+	}
+}
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		name   string
+		mutate func(expected *Manifest)
+		want   []string // substrings, one per expected reason
+	}{
+		{name: "up to date", mutate: func(*Manifest) {}},
+		{name: "source", mutate: func(m *Manifest) { m.SourceHash = "cccccccccccccccc"; m.Documents = 11 }, want: []string{"source documents changed (10 docs"}},
+		{name: "doc ids", mutate: func(m *Manifest) { m.DocIDs = "dddd" }, want: []string{"document IDs or their order changed"}},
+		{name: "analyzer", mutate: func(m *Manifest) { m.Analyzer = "snowball" }, want: []string{`analyzer changed ("porter" → "snowball")`}},
+		{name: "model", mutate: func(m *Manifest) { m.EmbeddingModel = "openai:small" }, want: []string{"embedding model changed"}},
+		{name: "dimension", mutate: func(m *Manifest) { m.Dimension = 384 }, want: []string{"embedding dimension changed (768 → 384)"}},
+		{name: "chunking", mutate: func(m *Manifest) { m.Chunking = "v2:tokens(size=256,overlap=32)" }, want: []string{"chunking changed"}},
+		{name: "params", mutate: func(m *Manifest) { m.Params = "hnsw(M=8,efConstruction=200)" }, want: []string{"index parameters changed"}},
+		{
+			name:   "several",
+			mutate: func(m *Manifest) { m.Analyzer = "none"; m.Dimension = 1024 },
+			want:   []string{"analyzer changed", "embedding dimension changed"},
+		},
+		{
+			name: "empty expected fields aren't compared",
+			mutate: func(m *Manifest) {
+				*m = Manifest{Artifact: m.Artifact}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expected := baseManifest()
+			tt.mutate(&expected)
+			reasons := Diff(expected, baseManifest())
+			if len(reasons) != len(tt.want) {
+				t.Fatalf("reasons = %q, want %d", reasons, len(tt.want))
+			}
+			for i, want := range tt.want {
+				if !strings.Contains(reasons[i], want) {
+					t.Errorf("reason %d = %q, want it to contain %q", i, reasons[i], want)
+				}
+			}
+		})
+	}
+}
+
+func TestDiffIgnoresMissingStoredDocIDs(t *testing.T) {
+	stored := baseManifest()
+	stored.DocIDs = "" // built before document IDs were recorded
+	if reasons := Diff(baseManifest(), stored); len(reasons) != 0 {
+		t.Errorf("reasons = %q, want none", reasons)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	dir := t.TempDir()
+	artifact := filepath.Join(dir, "movie_embeddings.gob")
+
+	if reasons := Check(artifact, baseManifest()); len(reasons) != 1 || !strings.Contains(reasons[0], "no manifest found") {
+		t.Errorf("missing manifest: reasons = %q", reasons)
+	}
+
+	if err := Save(artifact, baseManifest()); err != nil {
+		t.Fatal(err)
+	}
+	if reasons := Check(artifact, baseManifest()); len(reasons) != 0 {
+		t.Errorf("saved manifest: reasons = %q, want none", reasons)
+	}
+	stored, err := Load(artifact)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.BuiltAt.IsZero() {
+		t.Error("BuiltAt not set on save")
+	}
+
+	if err := os.WriteFile(artifact+".manifest.json", []byte("{"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if reasons := Check(artifact, baseManifest()); len(reasons) != 1 || !strings.Contains(reasons[0], "corrupt manifest") {
+		t.Errorf("corrupt manifest: reasons = %q", reasons)
+	}
+}
+
+func TestCheckEmbeddings(t *testing.T) {
+	unit := [][]float32{{1, 0}, {0, 1}, {0.6, 0.8}}
+	tests := []struct {
+		name     string
+		vectors  [][]float32
+		modelDim int
+		want     []string
+	}{
+		{name: "matches", vectors: unit, modelDim: 2},
+		{name: "unknown model dimension", vectors: unit},
+		{name: "vector count", vectors: unit[:2], modelDim: 2, want: []string{"artifact has 2 vectors, manifest records 3"}},
+		{name: "vector dimension", vectors: [][]float32{{1, 0}, {1}, {0, 1}}, modelDim: 2, want: []string{"vector 1 has dimension 1"}},
+		{name: "model dimension", vectors: unit, modelDim: 3, want: []string{"embeds with dimension 3, stored vectors have 2"}},
+		{name: "not normalized", vectors: [][]float32{{1, 0}, {0, 2}, {0.6, 0.8}}, modelDim: 2, want: []string{"aren't unit length"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			artifact := filepath.Join(t.TempDir(), "movie_embeddings.gob")
+			stored := Manifest{Artifact: "movie_embeddings.gob", EmbeddingModel: "test:model"}
+			stored.SetVectors(unit, 2)
+			if err := Save(artifact, stored); err != nil {
+				t.Fatal(err)
+			}
+
+			expected := Manifest{Artifact: "movie_embeddings.gob", EmbeddingModel: "test:model"}
+			reasons := CheckEmbeddings(artifact, expected, tt.vectors, tt.modelDim)
+			if len(reasons) != len(tt.want) {
+				t.Fatalf("reasons = %q, want %d", reasons, len(tt.want))
+			}
+			for i, want := range tt.want {
+				if !strings.Contains(reasons[i], want) {
+					t.Errorf("reason %d = %q, want it to contain %q", i, reasons[i], want)
+				}
+			}
+		})
+	}
+}
+
+func TestSetVectors(t *testing.T) {
+	vectors := [][]float32{{1, 0, 0}, {0, 1, 0}}
+	tests := []struct {
+		name     string
+		modelDim int
+		want     int
+	}{
+		{"from the embedder", 768, 768},
+		{"from the vectors when unknown", 0, 3},
+	}
+	for _, tt := range tests {
+		var m Manifest
+		m.SetVectors(vectors, tt.modelDim)
+		if m.Dimension != tt.want || m.Vectors != 2 || !m.Normalized {
+			t.Errorf("%s: got %+v, want dimension %d, 2 normalized vectors", tt.name, m, tt.want)
+		}
+	}
+}
+
+func TestSourceHash(t *testing.T) {
+	docs := []model.Movie{
+		{ID: 1, Title: "Heat", Description: "A heist.", Fields: map[string]string{"year": "1995", "genre": "crime"}},
+		{ID: 2, Title: "Up", Description: "Balloons."},
+	}
+	base := SourceHash(docs)
+
+	tests := []struct {
+		name    string
+		docs    []model.Movie
+		changes bool
+	}{
+		{"same documents", []model.Movie{docs[0], docs[1]}, false},
+		{"same fields in another map order", []model.Movie{
+			{ID: 1, Title: "Heat", Description: "A heist.", Fields: map[string]string{"genre": "crime", "year": "1995"}},
+			docs[1],
+		}, false},
+		{"order", []model.Movie{docs[1], docs[0]}, true},
+		{"title", []model.Movie{{ID: 1, Title: "Heat!", Description: "A heist.", Fields: docs[0].Fields}, docs[1]}, true},
+		{"field", []model.Movie{{ID: 1, Title: "Heat", Description: "A heist.", Fields: map[string]string{"year": "1996", "genre": "crime"}}, docs[1]}, true},
+		// Field separators keep "ab"+"c" apart from "a"+"bc"
+		{"boundaries", []model.Movie{{ID: 1, Title: "HeatA", Description: " heist.", Fields: docs[0].Fields}, docs[1]}, true},
+	}
+	for _, tt := range tests {
+		if got := SourceHash(tt.docs) != base; got != tt.changes {
+			t.Errorf("%s: hash changed = %v, want %v", tt.name, got, tt.changes)
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package methods
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/config"
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/fs"
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/llms"
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/tokenizer"
+)
+
+// AlphaAuto as --alpha picks alpha per query (see AutoAlpha).
+const AlphaAuto = "auto"
+
+// Query classes of --alpha auto (see HybridScore)
+const (
+	QueryExact      = "exact"      // a title or quoted phrase: keywords matter most
+	QueryConceptual = "conceptual" // a description of what to watch: meaning matters most
+	QueryMixed      = "mixed"      // specific terms and a concept
+)
+
+var QueryClasses = []string{QueryExact, QueryConceptual, QueryMixed}
+
+// Query classifiers of --alpha auto
+const (
+	ClassifierHeuristic = "heuristic" // titles, quotes, length and IDF of the terms
+	ClassifierLLM       = "llm"       // asks the LLM, heuristic if its answer isn't a class
+)
+
+var AlphaClassifiers = []string{ClassifierHeuristic, ClassifierLLM}
+
+// Heuristic thresholds
+const (
+	conceptualTerms = 4   // analyzed terms from which a query reads as a description
+	rareTermIDF     = 0.7 // mean IDF (relative to the rarest possible term) of specific terms
+)
+
+var (
+	quotedRe = regexp.MustCompile(`["“]([^"”]+)["”]`)
+	yearRe   = regexp.MustCompile(`\b(19|20)\d\d\b`)
+)
+
+// AlphaChoice is the alpha picked for a query and why.
+type AlphaChoice struct {
+	Alpha      float64
+	Class      string
+	Reason     string
+	Classifier string // the one that decided
+}
+
+func (c AlphaChoice) String() string {
+	return fmt.Sprintf("%.2f (%s, %s: %s)", c.Alpha, c.Class, c.Classifier, c.Reason)
+}
+
+// AutoAlpha classifies query and returns the alpha configured for its
+// class (hybrid.alpha_exact, alpha_conceptual, alpha_mixed).
+func (hs *HybridSearch) AutoAlpha(ctx context.Context, query string, classifier string) (AlphaChoice, error) {
+	choice := AlphaChoice{Classifier: classifier}
+	switch classifier {
+	case ClassifierHeuristic:
+		choice.Class, choice.Reason = hs.classifyQuery(query)
+	case ClassifierLLM:
+		class, reason, err := llms.ClassifyQuery(ctx, query)
+		if err != nil {
+			return AlphaChoice{}, err
+		}
+		if slices.Contains(QueryClasses, class) {
+			choice.Class, choice.Reason = class, reason
+			break
+		}
+		choice.Classifier = ClassifierHeuristic
+		choice.Class, choice.Reason = hs.classifyQuery(query)
+		choice.Reason = fmt.Sprintf("%s (LLM answered %q)", choice.Reason, class)
+	default:
+		return AlphaChoice{}, fmt.Errorf("unknown classifier %q (allowed: %s)", classifier, strings.Join(AlphaClassifiers, ", "))
+	}
+
+	cfg := config.Get().Hybrid
+	switch choice.Class {
+	case QueryExact:
+		choice.Alpha = cfg.AlphaExact
+	case QueryConceptual:
+		choice.Alpha = cfg.AlphaConceptual
+	default:
+		choice.Alpha = cfg.AlphaMixed
+	}
+	return choice, nil
+}
+
+// classifyQuery looks, in order, for a quoted phrase or a movie title
+// (exact), a year (mixed), terms the index doesn't know or a long query
+// (conceptual), and finally at how rare the terms are: rare terms are
+// names and places (mixed), common ones a concept (conceptual).
+func (hs *HybridSearch) classifyQuery(query string) (string, string) {
+	if m := quotedRe.FindStringSubmatch(query); m != nil {
+		return QueryExact, fmt.Sprintf("quoted phrase %q", m[1])
+	}
+	if title, whole := hs.matchTitle(query); title != "" {
+		if whole {
+			return QueryExact, fmt.Sprintf("matches the title %q", title)
+		}
+		return QueryExact, fmt.Sprintf("contains the title %q", title)
+	}
+	if yearRe.MatchString(query) {
+		return QueryMixed, "mentions a year"
+	}
+
+	stopWords, err := fs.LoadStopWords()
+	if err != nil {
+		return QueryMixed, "no stop words to analyze the query"
+	}
+	terms := tokenizer.Tokenize(query, stopWords)
+	if len(terms) == 0 {
+		return QueryMixed, "no searchable terms"
+	}
+
+	n := float64(len(hs.Idx.DocMap))
+	maxIDF := math.Log((n-1+0.5)/(1+0.5) + 1)
+	unknown, idfSum := 0, 0.0
+	for _, t := range terms {
+		df := float64(len(hs.Idx.Index[t]))
+		if df == 0 {
+			unknown++
+			continue
+		}
+		idfSum += math.Log((n-df+0.5)/(df+0.5)+1) / maxIDF
+	}
+	if 2*unknown >= len(terms) {
+		return QueryConceptual, fmt.Sprintf("%d of %d terms aren't in any document", unknown, len(terms))
+	}
+	if len(terms) >= conceptualTerms {
+		return QueryConceptual, fmt.Sprintf("long query (%d terms)", len(terms))
+	}
+	meanIDF := idfSum / float64(len(terms)-unknown)
+	if meanIDF >= rareTermIDF {
+		return QueryMixed, fmt.Sprintf("rare terms (mean IDF %.2f)", meanIDF)
+	}
+	return QueryConceptual, fmt.Sprintf("common terms (mean IDF %.2f)", meanIDF)
+}
+
+// matchTitle returns the longest movie title the query is or contains
+// (titles of one word only when they are the whole query), and whether it
+// is the whole query.
+func (hs *HybridSearch) matchTitle(query string) (string, bool) {
+	q := normalizeTitle(query)
+	if q == "" {
+		return "", false
+	}
+	best, bestLen := "", 0
+	for _, doc := range hs.Css.Documents {
+		t := normalizeTitle(doc.Title)
+		if t == "" {
+			continue
+		}
+		if t == q {
+			return doc.Title, true
+		}
+		if strings.Contains(t, " ") && len(t) > bestLen && strings.Contains(" "+q+" ", " "+t+" ") {
+			best, bestLen = doc.Title, len(t)
+		}
+	}
+	return best, false
+}
+
+// normalizeTitle lowercases s and keeps only words.
+func normalizeTitle(s string) string {
+	words := strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+	return strings.Join(words, " ")
+}
@@ -0,0 +1,132 @@
+package methods
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/config"
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/fs"
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/index"
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/model"
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/tokenizer"
+)
+
+// useStopWords points fs.StopWordsPath at a list of words for the test.
+func useStopWords(t *testing.T, words string) map[string]struct{} {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "stopwords.txt")
+	if err := os.WriteFile(path, []byte(words), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	previous := fs.StopWordsPath
+	fs.StopWordsPath = path
+	t.Cleanup(func() { fs.StopWordsPath = previous })
+
+	stopWords, err := fs.LoadStopWords()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return stopWords
+}
+
+// alphaFixture indexes a few specific movies among many about friendship
+// and family.
+func alphaFixture(t *testing.T) *HybridSearch {
+	stopWords := useStopWords(t, "the\na\nis\nof\nand\nabout\n")
+
+	docs := []model.Movie{
+		{ID: 1, Title: "The Matrix", Description: "A hacker learns reality is a simulation."},
+		{ID: 2, Title: "Jaws", Description: "A shark attacks a beach town."},
+		{ID: 3, Title: "Toy Story", Description: "Toys come alive, a story of friendship."},
+	}
+	for id := 4; id <= 10; id++ {
+		docs = append(docs, model.Movie{ID: id, Title: "Untitled", Description: "Friendship and family."})
+	}
+
+	idx := &index.InvertedIndex{Index: make(map[string]map[int]struct{}), DocMap: make(map[int]model.Movie)}
+	for _, doc := range docs {
+		idx.DocMap[doc.ID] = doc
+		for _, term := range tokenizer.Tokenize(doc.Title+" "+doc.Description, stopWords) {
+			if idx.Index[term] == nil {
+				idx.Index[term] = make(map[int]struct{})
+			}
+			idx.Index[term][doc.ID] = struct{}{}
+		}
+	}
+
+	css := &ChunkedSemanticSearch{SemanticSearch: &SemanticSearch{Documents: docs}}
+	return &HybridSearch{Idx: idx, Css: css}
+}
+
+func TestClassifyQuery(t *testing.T) {
+	tests := []struct {
+		name       string
+		query      string
+		wantClass  string
+		wantReason string
+	}{
+		{"quoted", `movies like "the matrix"`, QueryExact, `quoted phrase "the matrix"`},
+		{"curly quotes", "films with “a shark”", QueryExact, `quoted phrase "a shark"`},
+		{"whole title", "the MATRIX!", QueryExact, `matches the title "The Matrix"`},
+		{"one word title", "jaws", QueryExact, `matches the title "Jaws"`},
+		{"contains title", "toy story sequels", QueryExact, `contains the title "Toy Story"`},
+		// One word titles only count as the whole query
+		{"contains one word title", "jaws hacker", QueryMixed, "rare terms (mean IDF 1.00)"},
+		{"year", "space movies from 1999", QueryMixed, "mentions a year"},
+		{"not a year", "top 100 space movies", QueryConceptual, "4 of 4 terms aren't in any document"},
+		{"no terms", "the and of", QueryMixed, "no searchable terms"},
+		{"unknown terms", "robot uprising", QueryConceptual, "2 of 2 terms aren't in any document"},
+		{"half unknown", "shark robot", QueryConceptual, "1 of 2 terms aren't in any document"},
+		{"long", "friendship family shark beach", QueryConceptual, "long query (4 terms)"},
+		{"rare terms", "hacker simulation", QueryMixed, "rare terms (mean IDF 1.00)"},
+		{"common terms", "friendship family", QueryConceptual, "common terms (mean IDF 0.16)"},
+	}
+
+	hs := alphaFixture(t)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			class, reason := hs.classifyQuery(tt.query)
+			if class != tt.wantClass || reason != tt.wantReason {
+				t.Errorf("classifyQuery(%q) = %s (%s), want %s (%s)", tt.query, class, reason, tt.wantClass, tt.wantReason)
+			}
+		})
+	}
+}
+
+func TestClassifyQueryWithoutStopWords(t *testing.T) {
+	hs := alphaFixture(t)
+	fs.StopWordsPath = filepath.Join(t.TempDir(), "missing.txt")
+
+	if class, reason := hs.classifyQuery("hacker simulation"); class != QueryMixed || reason != "no stop words to analyze the query" {
+		t.Errorf("classifyQuery = %s (%s)", class, reason)
+	}
+}
+
+func TestAutoAlpha(t *testing.T) {
+	cfg := config.Get().Hybrid
+	tests := []struct {
+		query string
+		want  float64
+	}{
+		{query: "the matrix", want: cfg.AlphaExact},
+		{query: "hacker simulation", want: cfg.AlphaMixed},
+		{query: "friendship family", want: cfg.AlphaConceptual},
+	}
+
+	hs := alphaFixture(t)
+	for _, tt := range tests {
+		choice, err := hs.AutoAlpha(context.Background(), tt.query, ClassifierHeuristic)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if choice.Alpha != tt.want || choice.Classifier != ClassifierHeuristic {
+			t.Errorf("AutoAlpha(%q) = %s, want alpha %.2f", tt.query, choice, tt.want)
+		}
+	}
+
+	if _, err := hs.AutoAlpha(context.Background(), "the matrix", "other"); err == nil {
+		t.Error("AutoAlpha with an unknown classifier didn't fail")
+	}
+}
@@ -0,0 +1,169 @@
+package methods
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/ann"
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/config"
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/fs"
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/manifest"
+
+	"github.com/vbauerster/mpb/v8"
+	"github.com/vbauerster/mpb/v8/decor"
+)
+
+// ANN targets: which cached embeddings an HNSW index is built over.
+const (
+	ANNTargetDocs       = "docs"
+	ANNTargetChunks     = "chunks"
+	ANNTargetMultimodal = "multimodal"
+)
+
+var ANNTargets = []string{ANNTargetDocs, ANNTargetChunks, ANNTargetMultimodal}
+
+// annChunkOverfetch is how many chunks per requested document an approximate
+// (ANN or quantized) chunk search retrieves, since several chunks can belong
+// to the same document.
+const annChunkOverfetch = 10
+
+// ANNParams returns the configured HNSW parameters.
+func ANNParams() ann.Params {
+	cfg := config.Get().ANN
+	return ann.Params{
+		M:              cfg.M,
+		EfConstruction: cfg.EfConstruction,
+		EfSearch:       cfg.EfSearch,
+	}
+}
+
+// ANNPaths returns the index path and the embeddings file it is built from.
+func ANNPaths(target string) (indexPath string, sourcePath string) {
+	switch target {
+	case ANNTargetChunks:
+		return fs.ChunksANNPath, fs.ChunksEmbeddingsPath
+	case ANNTargetMultimodal:
+		return fs.MultimodalANNPath, fs.MultimodalEmbeddingsPath
+	default:
+		return fs.EmbeddingsANNPath, fs.EmbeddingsPath
+	}
+}
+
+// derivedManifest describes an artifact built from another cached
+// embeddings file (HNSW graph, quantized codes).
+func derivedManifest(artifactPath string, sourcePath string, vectors [][]float32, params string) (manifest.Manifest, error) {
+	sourceHash, err := manifest.HashFile(sourcePath)
+	if err != nil {
+		return manifest.Manifest{}, fmt.Errorf("failed to hash %s: %w", sourcePath, err)
+	}
+
+	m := manifest.Manifest{
+		Artifact:   filepath.Base(artifactPath),
+		SourceHash: sourceHash,
+		Documents:  len(vectors),
+		Params:     params,
+	}
+	if len(vectors) > 0 {
+		m.Dimension = len(vectors[0])
+	}
+	return m, nil
+}
+
+// BuildANN builds an HNSW index over vectors and saves it (with its
+// manifest) next to the embeddings it came from.
+func BuildANN(target string, vectors [][]float32, params ann.Params) (*ann.HNSW, error) {
+	indexPath, sourcePath := ANNPaths(target)
+
+	fmt.Printf("🔄 Building HNSW index over %d %s vectors (M=%d, efConstruction=%d)…\n",
+		len(vectors), target, params.M, params.EfConstruction)
+
+	p := mpb.New(mpb.WithWidth(60))
+	bar := p.AddBar(int64(len(vectors)),
+		mpb.PrependDecorators(
+			decor.Name("Indexing: "),
+			decor.CountersNoUnit("%d/%d"),
+		),
+		mpb.AppendDecorators(
+			decor.Percentage(),
+		),
+	)
+	index := ann.Build(vectors, params, bar.Increment)
+	p.Wait()
+
+	if err := index.Save(indexPath); err != nil {
+		return nil, err
+	}
+
+	m, err := derivedManifest(indexPath, sourcePath, vectors, params.String())
+	if err != nil {
+		return nil, err
+	}
+	if err := manifest.Save(indexPath, m); err != nil {
+		return nil, err
+	}
+
+	return index, nil
+}
+
+// LoadOrBuildANN loads the target's HNSW index, rebuilding it when missing
+// or stale (embeddings rebuilt, M/efConstruction changed). efSearch is a
+// query-time setting, so it is applied without a rebuild.
+func LoadOrBuildANN(target string, vectors [][]float32, params ann.Params) (*ann.HNSW, error) {
+	indexPath, sourcePath := ANNPaths(target)
+
+	if _, err := os.Stat(indexPath); err == nil {
+		index, err := ann.Load(indexPath)
+		if err == nil {
+			expected, err := derivedManifest(indexPath, sourcePath, vectors, params.String())
+			if err != nil {
+				return nil, err
+			}
+			reasons := manifest.Check(indexPath, expected)
+			rebuild, err := manifest.Resolve(filepath.Base(indexPath), reasons)
+			if err != nil {
+				return nil, err
+			}
+			if !rebuild {
+				index.EfSearch = params.EfSearch
+				fmt.Println("📂 Loaded HNSW index from disk.")
+				return index, nil
+			}
+		} else {
+			fmt.Printf("⚠️ %v. Rebuilding HNSW index...\n", err)
+		}
+	}
+
+	return BuildANN(target, vectors, params)
+}
+
+// LoadOrBuildANN attaches an HNSW index over the document embeddings, so
+// Search no longer scans every vector.
+func (ss *SemanticSearch) LoadOrBuildANN(params ann.Params) error {
+	index, err := LoadOrBuildANN(ANNTargetDocs, ss.Embeddings, params)
+	if err != nil {
+		return err
+	}
+	ss.ANN = index
+	return nil
+}
+
+// LoadOrBuildChunksANN attaches an HNSW index over the chunk embeddings.
+func (css *ChunkedSemanticSearch) LoadOrBuildChunksANN(params ann.Params) error {
+	index, err := LoadOrBuildANN(ANNTargetChunks, css.ChunksEmbeddings, params)
+	if err != nil {
+		return err
+	}
+	css.ChunksANN = index
+	return nil
+}
+
+// LoadOrBuildANN attaches an HNSW index over the multimodal embeddings.
+func (mms *MultimodalSearch) LoadOrBuildANN(params ann.Params) error {
+	index, err := LoadOrBuildANN(ANNTargetMultimodal, mms.DocsEmbeddings, params)
+	if err != nil {
+		return err
+	}
+	mms.ANN = index
+	return nil
+}
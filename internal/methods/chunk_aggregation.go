@@ -0,0 +1,104 @@
+package methods
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/config"
+)
+
+// How SearchChunked turns the scores of a document's chunks into one score.
+const (
+	AggregateMax      = "max"      // best chunk only
+	AggregateTopMean  = "topMean"  // mean of the best n chunks
+	AggregateDecaySum = "decaySum" // best + decay*second + decay²*third ...
+	AggregateSoftmax  = "softmax"  // softmax-weighted mean (a smooth max)
+	AggregateMaxMean  = "maxMean"  // blend*max + (1-blend)*mean of all chunks
+)
+
+var Aggregations = []string{AggregateMax, AggregateTopMean, AggregateDecaySum, AggregateSoftmax, AggregateMaxMean}
+
+type Aggregation struct {
+	Method      string
+	TopN        int
+	Decay       float64
+	Temperature float64
+	Blend       float64
+}
+
+// AggregationFromConfig reads the aggregation section.
+func AggregationFromConfig() Aggregation {
+	cfg := config.Get().Aggregation
+	return Aggregation{
+		Method:      cfg.Method,
+		TopN:        cfg.TopN,
+		Decay:       cfg.Decay,
+		Temperature: cfg.Temperature,
+		Blend:       cfg.Blend,
+	}
+}
+
+func (a Aggregation) String() string {
+	switch a.Method {
+	case AggregateTopMean:
+		return fmt.Sprintf("topMean(n=%d)", a.TopN)
+	case AggregateDecaySum:
+		return fmt.Sprintf("decaySum(decay=%g)", a.Decay)
+	case AggregateSoftmax:
+		return fmt.Sprintf("softmax(t=%g)", a.Temperature)
+	case AggregateMaxMean:
+		return fmt.Sprintf("maxMean(blend=%g)", a.Blend)
+	default:
+		return a.Method
+	}
+}
+
+// Score reduces the chunk scores of one document (in any order). Only the
+// chunks that were scored count: with --ann or --storage that's the
+// nearest ones, not every chunk of the document.
+func (a Aggregation) Score(scores []float64) float64 {
+	if len(scores) == 0 {
+		return 0
+	}
+
+	sorted := append([]float64(nil), scores...)
+	sort.Sort(sort.Reverse(sort.Float64Slice(sorted)))
+
+	switch a.Method {
+	case AggregateTopMean:
+		return mean(sorted[:min(max(a.TopN, 1), len(sorted))])
+	case AggregateDecaySum:
+		sum, weight := 0.0, 1.0
+		for _, s := range sorted {
+			sum += weight * s
+			weight *= a.Decay
+		}
+		return sum
+	case AggregateSoftmax:
+		t := a.Temperature
+		if t <= 0 {
+			return sorted[0]
+		}
+		// Shift by the max so exp never overflows
+		var num, den float64
+		for _, s := range sorted {
+			w := math.Exp((s - sorted[0]) / t)
+			num += w * s
+			den += w
+		}
+		return num / den
+	case AggregateMaxMean:
+		return a.Blend*sorted[0] + (1-a.Blend)*mean(sorted)
+	default:
+		return sorted[0]
+	}
+}
+
+func mean(values []float64) float64 {
+	sum := 0.0
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
@@ -0,0 +1,59 @@
+package methods
+
+import (
+	"math"
+	"testing"
+)
+
+func TestAggregationScore(t *testing.T) {
+	scores := []float64{0.5, 0.9, 0.7}
+	softmax := func(t float64) float64 {
+		var num, den float64
+		for _, s := range scores {
+			w := math.Exp(s / t)
+			num += w * s
+			den += w
+		}
+		return num / den
+	}
+
+	tests := []struct {
+		name   string
+		agg    Aggregation
+		scores []float64
+		want   float64
+	}{
+		{"empty", Aggregation{Method: AggregateMax}, nil, 0},
+		{"max", Aggregation{Method: AggregateMax}, scores, 0.9},
+		{"unknown method is max", Aggregation{Method: "other"}, scores, 0.9},
+		{"topMean", Aggregation{Method: AggregateTopMean, TopN: 2}, scores, 0.8},
+		{"topMean over chunks", Aggregation{Method: AggregateTopMean, TopN: 5}, scores, 0.7},
+		{"topMean zero is max", Aggregation{Method: AggregateTopMean}, scores, 0.9},
+		{"decaySum", Aggregation{Method: AggregateDecaySum, Decay: 0.5}, scores, 0.9 + 0.5*0.7 + 0.25*0.5},
+		{"decaySum zero is max", Aggregation{Method: AggregateDecaySum}, scores, 0.9},
+		{"decaySum one is sum", Aggregation{Method: AggregateDecaySum, Decay: 1}, scores, 2.1},
+		{"softmax", Aggregation{Method: AggregateSoftmax, Temperature: 0.1}, scores, softmax(0.1)},
+		{"softmax hot is mean", Aggregation{Method: AggregateSoftmax, Temperature: 1e9}, scores, 0.7},
+		{"softmax zero is max", Aggregation{Method: AggregateSoftmax}, scores, 0.9},
+		{"softmax large scores", Aggregation{Method: AggregateSoftmax, Temperature: 0.01}, []float64{1000, 1000}, 1000},
+		{"maxMean", Aggregation{Method: AggregateMaxMean, Blend: 0.5}, scores, 0.5*0.9 + 0.5*0.7},
+		{"maxMean one is max", Aggregation{Method: AggregateMaxMean, Blend: 1}, scores, 0.9},
+		{"maxMean zero is mean", Aggregation{Method: AggregateMaxMean}, scores, 0.7},
+		{"single chunk", Aggregation{Method: AggregateDecaySum, Decay: 0.5}, []float64{0.4}, 0.4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.agg.Score(tt.scores); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("%s.Score(%v) = %v, want %v", tt.agg, tt.scores, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAggregationScoreKeepsInput(t *testing.T) {
+	scores := []float64{0.1, 0.3, 0.2}
+	Aggregation{Method: AggregateTopMean, TopN: 2}.Score(scores)
+	if scores[0] != 0.1 || scores[1] != 0.3 || scores[2] != 0.2 {
+		t.Errorf("Score reordered its input: %v", scores)
+	}
+}
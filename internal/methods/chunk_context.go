@@ -0,0 +1,102 @@
+package methods
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/chunk"
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/llms"
+)
+
+// DefaultContextTemplate embeds chunks as they are.
+const DefaultContextTemplate = "{chunk}"
+
+// contextTemplate is the configured template, with {context} prepended
+// when situating is on and the template doesn't place it.
+func (css *ChunkedSemanticSearch) contextTemplate() string {
+	template := css.ContextTemplate
+	if template == "" {
+		template = DefaultContextTemplate
+	}
+	if css.Situate && !strings.Contains(template, "{context}") {
+		template = "{context} " + template
+	}
+	return template
+}
+
+// contextFingerprint is appended to the chunking fingerprint so changing the
+// template or the situating model rebuilds the chunk embeddings. It's empty
+// for the default, so existing embeddings stay valid.
+func (css *ChunkedSemanticSearch) contextFingerprint() string {
+	template := css.contextTemplate()
+	var b strings.Builder
+	if template != DefaultContextTemplate {
+		fmt.Fprintf(&b, "|template=%q", template)
+	}
+	if css.Situate {
+		fmt.Fprintf(&b, "|situate=%s", llms.ModelName())
+	}
+	return b.String()
+}
+
+// contextualize returns the text to embed for every chunk, in order.
+func (css *ChunkedSemanticSearch) contextualize(ctx context.Context, docChunks [][]chunk.Chunk) ([]string, error) {
+	template := css.contextTemplate()
+
+	var situate func(docIndex int, text string) (string, error)
+	if css.Situate {
+		cache, err := llms.OpenSituateCache()
+		if err != nil {
+			return nil, err
+		}
+		// Keep what was generated even if a later call fails
+		defer cache.Save()
+
+		hits, calls := 0, 0
+		defer func() {
+			fmt.Printf("🧭 Situating context: %d cached, %d generated\n", hits, calls)
+		}()
+
+		situate = func(docIndex int, text string) (string, error) {
+			doc := css.Documents[docIndex]
+			key := cache.Key(doc.Title, doc.Description, text)
+			if sentence, ok := cache.Texts[key]; ok {
+				hits++
+				return sentence, nil
+			}
+
+			sentence, err := llms.SituateChunk(ctx, doc.Title, doc.Description, text)
+			if err != nil {
+				return "", fmt.Errorf("failed to situate chunk of %q: %w", doc.Title, err)
+			}
+			cache.Put(key, sentence)
+			calls++
+			if calls%100 == 0 {
+				if err := cache.Save(); err != nil {
+					return "", err
+				}
+			}
+			return sentence, nil
+		}
+	}
+
+	var texts []string
+	for docIndex, descChunks := range docChunks {
+		for _, c := range descChunks {
+			situating := ""
+			if situate != nil {
+				var err error
+				if situating, err = situate(docIndex, c.Text); err != nil {
+					return nil, err
+				}
+			}
+			texts = append(texts, strings.NewReplacer(
+				"{title}", css.Documents[docIndex].Title,
+				"{chunk}", c.Text,
+				"{context}", situating,
+			).Replace(template))
+		}
+	}
+	return texts, nil
+}
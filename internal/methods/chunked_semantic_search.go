@@ -1,43 +1,84 @@
 package methods
 
 import (
+	"context"
 	"encoding/gob"
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"sort"
+	"unicode/utf8"
 
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/ann"
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/chunk"
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/config"
 	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/fs"
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/manifest"
 	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/model"
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/quant"
 )
 
 type ChunkSimilarityScore struct {
+	Index    int // position in ChunksMetadata
 	MovieIdx int
 	ChunkIdx int
 	Score    float64
 }
 
+// chunkMetadataVersion is the format of chunks_metadata.json. Version 1
+// added character offsets; files without a version predate them.
+const chunkMetadataVersion = 1
+
 type ChunkMetadataFile struct {
+	Version     int             `json:"version"`
 	Chunks      []ChunkMetadata `json:"chunks"`
 	TotalChunks int             `json:"total_chunks"`
 }
 
-type Embedding = []float64
+type Embedding = []float32
 
 type ChunkMetadata struct {
-	MovieIdx    int `json:"movie_idx"`
-	ChunkIdx    int `json:"chunk_idx"`
-	TotalChunks int `json:"total_chunks"`
+	MovieIdx    int    `json:"movie_idx"`
+	ChunkIdx    int    `json:"chunk_idx"`
+	TotalChunks int    `json:"total_chunks"`
+	Start       int    `json:"start"` // character offsets into the description
+	End         int    `json:"end"`
+	Text        string `json:"text"`
+}
+
+// Passage is a chunk of a result's document that matched the query.
+type Passage struct {
+	ChunkIdx int
+	Start    int // character offsets into the description
+	End      int
+	Text     string
+	Score    float64
 }
 
 type ChunkedSemanticSearch struct {
 	*SemanticSearch
 	ChunksEmbeddings []Embedding
 	ChunksMetadata   []ChunkMetadata
+	ChunksANN        *ann.HNSW       // optional, set by LoadOrBuildChunksANN
+	ChunksQuantized  quant.Quantizer // optional, set by UseChunksStorage
+
+	// Strategy overrides chunking.strategy (e.g. from --strategy)
+	Strategy string
+	// Aggregation turns chunk scores into document scores
+	Aggregation Aggregation
+	// MaxPassages is how many matching chunks each result carries
+	MaxPassages int
+	// ContextTemplate and Situate decide the text embedded for each chunk
+	ContextTemplate string
+	Situate         bool
+
+	chunkStarts  map[int]int // DocID -> first chunk in ChunksMetadata, built on demand
+	chunkVectors vectorStore // exact scans over ChunksEmbeddings
 }
 
-func NewChunkedSemanticSearch(modelName string) (*ChunkedSemanticSearch, error) {
-	ss, err := NewSemanticSearch(modelName)
+func NewChunkedSemanticSearch() (*ChunkedSemanticSearch, error) {
+	ss, err := NewSemanticSearch()
 	if err != nil {
 		return nil, err
 	}
@@ -46,29 +87,61 @@ func NewChunkedSemanticSearch(modelName string) (*ChunkedSemanticSearch, error)
 		SemanticSearch:   ss,
 		ChunksEmbeddings: make([]Embedding, 0),
 		ChunksMetadata:   make([]ChunkMetadata, 0),
+		Strategy:         config.Get().Chunking.Strategy,
+		Aggregation:      AggregationFromConfig(),
+		MaxPassages:      config.Get().Search.Passages,
+		ContextTemplate:  config.Get().Chunking.ContextTemplate,
+		Situate:          config.Get().Chunking.Situate,
 	}, nil
 }
 
+// chunker is the splitter for Strategy, sized from the chunking config.
+func (css *ChunkedSemanticSearch) chunker() (chunk.Chunker, error) {
+	return chunk.FromConfig(css.Strategy, css.Embedder)
+}
+
 func (css *ChunkedSemanticSearch) BuildChunksEmbeddings() ([]Embedding, error) {
-	chunks := make([]string, 0)
-	for docIndex, doc := range css.Documents {
-		if len(doc.Description) > 0 {
-			descChunks := SemanticChunk(doc.Description, 4, 1)
-			chunks = append(chunks, descChunks...)
-			for chunkIndex := range descChunks {
-				css.ChunksMetadata = append(css.ChunksMetadata, ChunkMetadata{
-					MovieIdx:    docIndex,
-					ChunkIdx:    chunkIndex,
-					TotalChunks: len(descChunks),
-				})
-			}
+	chunker, err := css.chunker()
+	if err != nil {
+		return nil, err
+	}
+	fmt.Printf("✂️ Chunking %d documents with %s\n", len(css.Documents), chunker.Name())
+
+	descriptions := make([]string, len(css.Documents))
+	for i, doc := range css.Documents {
+		descriptions[i] = doc.Description
+	}
+	docChunks, err := chunker.Split(context.Background(), descriptions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to chunk documents: %w", err)
+	}
+
+	chunks, err := css.contextualize(context.Background(), docChunks)
+	if err != nil {
+		return nil, err
+	}
+
+	css.ChunksMetadata = make([]ChunkMetadata, 0)
+	css.chunkStarts = nil
+	for docIndex, descChunks := range docChunks {
+		desc := descriptions[docIndex]
+		for chunkIndex, c := range descChunks {
+			css.ChunksMetadata = append(css.ChunksMetadata, ChunkMetadata{
+				MovieIdx:    docIndex,
+				ChunkIdx:    chunkIndex,
+				TotalChunks: len(descChunks),
+				Start:       utf8.RuneCountInString(desc[:c.Start]),
+				End:         utf8.RuneCountInString(desc[:c.End]),
+				Text:        c.Text,
+			})
 		}
 	}
 
 	fmt.Println("Total chunks:", len(chunks))
 
 	// Create embeddings for all chunks
-	embeddings, err := css.createEmbeddingsParallel(chunks)
+	css.ChunksEmbeddings = nil
+	embeddings, ckpt, err := css.createEmbeddingsParallel(chunks, fs.ChunksEmbeddingsPath, css.chunksManifest())
 	if err != nil {
 		return nil, err
 	}
@@ -82,36 +155,35 @@ func (css *ChunkedSemanticSearch) BuildChunksEmbeddings() ([]Embedding, error) {
 		return nil, fmt.Errorf("Error saving ChunksMetadata to file: %w", err)
 	}
 
+	if err = manifest.Save(fs.ChunksEmbeddingsPath, css.chunksManifest()); err != nil {
+		return nil, err
+	}
+	if err = ckpt.Remove(); err != nil {
+		return nil, err
+	}
+
 	return css.ChunksEmbeddings, nil
 }
 
 func (css *ChunkedSemanticSearch) saveChunksMetadata(totalChunks int) error {
-	file, err := os.Create(fs.ChunksMetadataPath)
-	if err != nil {
-		return err
-	}
-	defer file.Close()
-
-	encoder := json.NewEncoder(file)
-	encoder.SetIndent("", "  ") // same as indent=2 in Python
-
-	metadata := ChunkMetadataFile{
-		Chunks:      css.ChunksMetadata,
-		TotalChunks: totalChunks,
-	}
+	return fs.WriteAtomic(fs.ChunksMetadataPath, func(w io.Writer) error {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ") // same as indent=2 in Python
+
+		metadata := ChunkMetadataFile{
+			Version:     chunkMetadataVersion,
+			Chunks:      css.ChunksMetadata,
+			TotalChunks: totalChunks,
+		}
 
-	return encoder.Encode(metadata)
+		return encoder.Encode(metadata)
+	})
 }
 
 func (css *ChunkedSemanticSearch) saveChunksEmbeddings() error {
-	file, err := os.Create(fs.ChunksEmbeddingsPath)
-	if err != nil {
-		return err
-	}
-	defer file.Close()
-
-	encoder := gob.NewEncoder(file)
-	return encoder.Encode(css.ChunksEmbeddings)
+	return fs.WriteAtomic(fs.ChunksEmbeddingsPath, func(w io.Writer) error {
+		return gob.NewEncoder(w).Encode(css.ChunksEmbeddings)
+	})
 }
 
 func (css *ChunkedSemanticSearch) loadChunksEmbeddings() error {
@@ -138,7 +210,12 @@ func (css *ChunkedSemanticSearch) loadChunksMetadata() error {
 		return err
 	}
 
+	if data.Version != chunkMetadataVersion {
+		return fmt.Errorf("chunk metadata format v%d, expected v%d (built by another version)", data.Version, chunkMetadataVersion)
+	}
+
 	css.ChunksMetadata = data.Chunks
+	css.chunkStarts = nil
 	return nil
 }
 
@@ -157,8 +234,23 @@ func (css *ChunkedSemanticSearch) LoadOrCreateChunksEmbeddings(docs []model.Movi
 		if err := css.loadChunksEmbeddings(); err == nil {
 			// Try loading metadata
 			if err := css.loadChunksMetadata(); err == nil {
-				fmt.Println("Loaded existing chunk embeddings + metadata from disk.")
-				return css.ChunksEmbeddings, nil
+				// One vector per chunk, or the artifact is unusable
+				if len(css.ChunksEmbeddings) != len(css.ChunksMetadata) {
+					fmt.Printf("🔄 chunks_embeddings.gob has %d vectors for %d chunks, rebuilding\n", len(css.ChunksEmbeddings), len(css.ChunksMetadata))
+				} else {
+					reasons := manifest.CheckEmbeddings(fs.ChunksEmbeddingsPath, css.chunksManifest(), css.ChunksEmbeddings, css.Embedder.Info().Dimension)
+					rebuild, err := manifest.Resolve("chunks_embeddings.gob", reasons)
+					if err != nil {
+						return nil, err
+					}
+					if !rebuild {
+						fmt.Println("Loaded existing chunk embeddings + metadata from disk.")
+						return css.ChunksEmbeddings, nil
+					}
+				}
+				css.ChunksEmbeddings = make([]Embedding, 0)
+				css.ChunksMetadata = make([]ChunkMetadata, 0)
+				return css.BuildChunksEmbeddings()
 			}
 		}
 
@@ -172,37 +264,99 @@ func (css *ChunkedSemanticSearch) LoadOrCreateChunksEmbeddings(docs []model.Movi
 	return css.BuildChunksEmbeddings()
 }
 
+func (css *ChunkedSemanticSearch) chunksManifest() manifest.Manifest {
+	m := manifest.Manifest{
+		Artifact:       "chunks_embeddings.gob",
+		SourceHash:     manifest.SourceHash(css.Documents),
+		DocIDs:         manifest.DocIDsHash(css.Documents),
+		Documents:      len(css.Documents),
+		EmbeddingModel: css.Embedder.Info().Name(),
+		Chunking:       css.ChunkingFingerprint(),
+	}
+	m.SetVectors(css.ChunksEmbeddings, css.Embedder.Info().Dimension)
+	return m
+}
+
+// ChunkingFingerprint identifies the chunker used for the chunk embeddings
+// so a change in strategy or sizes invalidates the cache.
+func (css *ChunkedSemanticSearch) ChunkingFingerprint() string {
+	chunker, err := css.chunker()
+	if err != nil {
+		return "invalid:" + css.Strategy
+	}
+	return chunk.Fingerprint(chunker) + css.contextFingerprint()
+}
+
 func (css *ChunkedSemanticSearch) SearchChunked(query string, limit int) ([]SemanticSearchResult, error) {
 	// todo: check chunks_embeddings are valid/correctly loaded
 
-	queryEmbedding, err := css.EmbedText(query)
+	queryEmbedding, err := css.EmbedQuery(query, css.ChunksEmbeddings)
 	if err != nil {
 		return nil, fmt.Errorf("❌ Failed to create embedding of the query: %v\n", err)
 	}
+	rebuild, err := checkQueryDimension("chunks_embeddings.gob", css.Embedder, queryEmbedding, css.ChunksEmbeddings)
+	if err != nil {
+		return nil, err
+	}
+	if rebuild {
+		if _, err := css.BuildChunksEmbeddings(); err != nil {
+			return nil, err
+		}
+		// Indexes over the old vectors are rebuilt on the next load
+		css.ChunksANN, css.ChunksQuantized = nil, nil
+	}
 
 	scores := make([]ChunkSimilarityScore, 0, len(css.ChunksEmbeddings))
-	for i, chunkEmbedding := range css.ChunksEmbeddings {
-		similarityScore := CosineSimilarity(queryEmbedding, chunkEmbedding)
-		chunkMetadata := css.ChunksMetadata[i]
-		scores = append(scores, ChunkSimilarityScore{
-			MovieIdx: chunkMetadata.MovieIdx,
-			ChunkIdx: chunkMetadata.ChunkIdx,
-			Score:    similarityScore,
-		})
+	if css.ChunksANN != nil {
+		// Only the nearest chunks are scored; documents outside them are skipped
+		for _, hit := range css.ChunksANN.Search(queryEmbedding, limit*annChunkOverfetch) {
+			chunkMetadata := css.ChunksMetadata[hit.ID]
+			scores = append(scores, ChunkSimilarityScore{
+				Index:    hit.ID,
+				MovieIdx: chunkMetadata.MovieIdx,
+				ChunkIdx: chunkMetadata.ChunkIdx,
+				Score:    float64(hit.Score),
+			})
+		}
+	} else if css.ChunksQuantized != nil {
+		hits, err := quantizedTopK(css.ChunksQuantized, css.ChunksEmbeddings, queryEmbedding, limit*annChunkOverfetch, css.Rescore)
+		if err != nil {
+			return nil, err
+		}
+		for _, hit := range hits {
+			chunkMetadata := css.ChunksMetadata[hit.idx]
+			scores = append(scores, ChunkSimilarityScore{
+				Index:    hit.idx,
+				MovieIdx: chunkMetadata.MovieIdx,
+				ChunkIdx: chunkMetadata.ChunkIdx,
+				Score:    hit.score,
+			})
+		}
+	} else {
+		// Every chunk is scored, so aggregation sees all of a document's chunks
+		for i, similarityScore := range css.chunkVectors.get(css.ChunksEmbeddings).Scores(queryEmbedding) {
+			chunkMetadata := css.ChunksMetadata[i]
+			scores = append(scores, ChunkSimilarityScore{
+				Index:    i,
+				MovieIdx: chunkMetadata.MovieIdx,
+				ChunkIdx: chunkMetadata.ChunkIdx,
+				Score:    float64(similarityScore),
+			})
+		}
 	}
 
-	moviesScoreMap := make(map[int]float64)
+	chunkScores := make(map[int][]ChunkSimilarityScore)
 	for _, scoreItem := range scores {
-		movieIdx := scoreItem.MovieIdx
-		chunkScore := scoreItem.Score
-		_, ok := moviesScoreMap[movieIdx]
-		if ok {
-			if chunkScore > moviesScoreMap[movieIdx] {
-				moviesScoreMap[movieIdx] = chunkScore
-			}
-		} else {
-			moviesScoreMap[movieIdx] = chunkScore
+		chunkScores[scoreItem.MovieIdx] = append(chunkScores[scoreItem.MovieIdx], scoreItem)
+	}
+
+	moviesScoreMap := make(map[int]float64, len(chunkScores))
+	for movieIdx, movieChunkScores := range chunkScores {
+		values := make([]float64, len(movieChunkScores))
+		for i, c := range movieChunkScores {
+			values[i] = c.Score
 		}
+		moviesScoreMap[movieIdx] = css.Aggregation.Score(values)
 	}
 
 	movieScores := make([]SimilarityScore, 0, len(moviesScoreMap))
@@ -210,6 +364,7 @@ func (css *ChunkedSemanticSearch) SearchChunked(query string, limit int) ([]Sema
 		movieScores = append(movieScores, SimilarityScore{
 			Score: movieScore,
 			Movie: css.Documents[movieIdx],
+			Idx:   movieIdx,
 		})
 	}
 
@@ -218,14 +373,35 @@ func (css *ChunkedSemanticSearch) SearchChunked(query string, limit int) ([]Sema
 	})
 
 	results := make([]SemanticSearchResult, 0, limit)
-	for _, item := range movieScores[:limit] {
+	for _, item := range movieScores[:min(limit, len(movieScores))] {
 		results = append(results, SemanticSearchResult{
 			DocID:       item.Movie.ID,
 			Score:       item.Score,
 			Title:       item.Movie.Title,
 			Description: item.Movie.Description,
+			Passages:    css.passages(chunkScores[item.Idx]),
 		})
 	}
 
 	return results, nil
 }
+
+// passages returns the best-scoring chunks of one document, best first.
+func (css *ChunkedSemanticSearch) passages(scores []ChunkSimilarityScore) []Passage {
+	sort.Slice(scores, func(i, j int) bool {
+		return scores[i].Score > scores[j].Score
+	})
+
+	passages := make([]Passage, 0, min(css.MaxPassages, len(scores)))
+	for _, s := range scores[:min(max(css.MaxPassages, 0), len(scores))] {
+		meta := css.ChunksMetadata[s.Index]
+		passages = append(passages, Passage{
+			ChunkIdx: meta.ChunkIdx,
+			Start:    meta.Start,
+			End:      meta.End,
+			Text:     meta.Text,
+			Score:    s.Score,
+		})
+	}
+	return passages
+}
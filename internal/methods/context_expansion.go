@@ -0,0 +1,109 @@
+package methods
+
+import (
+	"sort"
+)
+
+// How much of a matching document goes into a RAG prompt.
+const (
+	ContextChunk  = "chunk"  // the matching chunks only
+	ContextWindow = "window" // the matching chunks and their n neighbours on each side
+	ContextParent = "parent" // the whole description
+)
+
+var ContextModes = []string{ContextChunk, ContextWindow, ContextParent}
+
+// ExpandContext rewrites the passages of every result for mode. Results
+// without passages (keyword-only matches) keep the whole description.
+func (hs *HybridSearch) ExpandContext(results []RRFSearchResult, mode string, window int) {
+	for i := range results {
+		results[i].Passages = hs.Css.ExpandContext(results[i].DocID, results[i].Passages, mode, window)
+	}
+}
+
+// ExpandContext turns the matching passages of one document into the
+// passages to show. In chunk and window mode, chunk ranges that overlap or
+// touch are merged and cut once from the description, so text shared by
+// overlapping chunks appears only once. A merged passage keeps the best
+// score of the passages in it; passages come back in document order. Parent
+// mode returns no passages, so the description is used as is.
+func (css *ChunkedSemanticSearch) ExpandContext(docID int, passages []Passage, mode string, window int) []Passage {
+	if len(passages) == 0 || mode == ContextParent {
+		return nil
+	}
+
+	first, total, ok := css.docChunks(docID)
+	if !ok {
+		return passages
+	}
+	if mode != ContextWindow {
+		window = 0
+	}
+	window = max(window, 0)
+
+	type span struct {
+		from, to int // chunk indices, inclusive
+		score    float64
+	}
+	spans := make([]span, 0, len(passages))
+	for _, p := range passages {
+		spans = append(spans, span{
+			from:  max(p.ChunkIdx-window, 0),
+			to:    min(p.ChunkIdx+window, total-1),
+			score: p.Score,
+		})
+	}
+	sort.Slice(spans, func(i, j int) bool {
+		return spans[i].from < spans[j].from
+	})
+
+	merged := spans[:1]
+	for _, s := range spans[1:] {
+		last := &merged[len(merged)-1]
+		if s.from > last.to+1 {
+			merged = append(merged, s)
+			continue
+		}
+		last.to = max(last.to, s.to)
+		last.score = max(last.score, s.score)
+	}
+
+	description := []rune(css.DocumentMap[docID].Description)
+	out := make([]Passage, 0, len(merged))
+	for _, s := range merged {
+		start := css.ChunksMetadata[first+s.from].Start
+		end := css.ChunksMetadata[first+s.to].End
+		// Overlapping chunks can end before an earlier one does
+		for i := first + s.from; i <= first+s.to; i++ {
+			end = max(end, css.ChunksMetadata[i].End)
+		}
+		end = min(end, len(description))
+		out = append(out, Passage{
+			ChunkIdx: s.from,
+			Start:    start,
+			End:      end,
+			Text:     string(description[start:end]),
+			Score:    s.score,
+		})
+	}
+	return out
+}
+
+// docChunks finds the position of a document's first chunk in
+// ChunksMetadata and its chunk count.
+func (css *ChunkedSemanticSearch) docChunks(docID int) (int, int, bool) {
+	if css.chunkStarts == nil {
+		css.chunkStarts = make(map[int]int)
+		for i, meta := range css.ChunksMetadata {
+			if meta.ChunkIdx == 0 && meta.MovieIdx < len(css.Documents) {
+				css.chunkStarts[css.Documents[meta.MovieIdx].ID] = i
+			}
+		}
+	}
+
+	first, ok := css.chunkStarts[docID]
+	if !ok {
+		return 0, 0, false
+	}
+	return first, css.ChunksMetadata[first].TotalChunks, true
+}
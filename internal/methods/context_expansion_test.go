@@ -0,0 +1,113 @@
+package methods
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/model"
+)
+
+// expansionFixture has two documents: 3 overlapping chunks of
+// "aaaa bbbb cccc", then 5 disjoint chunks of "aaaa bbbb cccc dddd eeee".
+func expansionFixture() *ChunkedSemanticSearch {
+	docs := []model.Movie{
+		{ID: 3, Description: "aaaa bbbb cccc"},
+		{ID: 7, Description: "aaaa bbbb cccc dddd eeee"},
+	}
+	ss := &SemanticSearch{Documents: docs, DocumentMap: make(map[int]model.Movie)}
+	for _, doc := range docs {
+		ss.DocumentMap[doc.ID] = doc
+	}
+
+	var metadata []ChunkMetadata
+	for i, span := range [][2]int{{0, 9}, {5, 14}, {10, 14}} {
+		metadata = append(metadata, ChunkMetadata{MovieIdx: 0, ChunkIdx: i, TotalChunks: 3, Start: span[0], End: span[1]})
+	}
+	for i := 0; i < 5; i++ {
+		metadata = append(metadata, ChunkMetadata{MovieIdx: 1, ChunkIdx: i, TotalChunks: 5, Start: 5 * i, End: 5*i + 4})
+	}
+	return &ChunkedSemanticSearch{SemanticSearch: ss, ChunksMetadata: metadata}
+}
+
+func TestExpandContext(t *testing.T) {
+	passage := func(idx int, score float64) Passage {
+		return Passage{ChunkIdx: idx, Score: score}
+	}
+
+	tests := []struct {
+		name     string
+		docID    int
+		passages []Passage
+		mode     string
+		window   int
+		want     []Passage
+	}{
+		{
+			name: "no passages", docID: 7, mode: ContextChunk,
+			want: nil,
+		},
+		{
+			name: "parent", docID: 7, passages: []Passage{passage(2, 0.5)}, mode: ContextParent,
+			want: nil,
+		},
+		{
+			name: "unknown document", docID: 99, passages: []Passage{passage(2, 0.5)}, mode: ContextChunk,
+			want: []Passage{passage(2, 0.5)},
+		},
+		{
+			name: "chunk", docID: 7, passages: []Passage{passage(2, 0.5)}, mode: ContextChunk,
+			want: []Passage{{ChunkIdx: 2, Start: 10, End: 14, Text: "cccc", Score: 0.5}},
+		},
+		{
+			name: "chunk ignores window", docID: 7, passages: []Passage{passage(2, 0.5)}, mode: ContextChunk, window: 3,
+			want: []Passage{{ChunkIdx: 2, Start: 10, End: 14, Text: "cccc", Score: 0.5}},
+		},
+		{
+			name: "adjacent chunks merge", docID: 7, passages: []Passage{passage(2, 0.5), passage(1, 0.8)}, mode: ContextChunk,
+			want: []Passage{{ChunkIdx: 1, Start: 5, End: 14, Text: "bbbb cccc", Score: 0.8}},
+		},
+		{
+			name: "distant chunks in document order", docID: 7, passages: []Passage{passage(3, 0.9), passage(0, 0.4)}, mode: ContextChunk,
+			want: []Passage{
+				{ChunkIdx: 0, Start: 0, End: 4, Text: "aaaa", Score: 0.4},
+				{ChunkIdx: 3, Start: 15, End: 19, Text: "dddd", Score: 0.9},
+			},
+		},
+		{
+			name: "window", docID: 7, passages: []Passage{passage(2, 0.5)}, mode: ContextWindow, window: 1,
+			want: []Passage{{ChunkIdx: 1, Start: 5, End: 19, Text: "bbbb cccc dddd", Score: 0.5}},
+		},
+		{
+			name: "window clipped to the document", docID: 7, passages: []Passage{passage(0, 0.5)}, mode: ContextWindow, window: 2,
+			want: []Passage{{ChunkIdx: 0, Start: 0, End: 14, Text: "aaaa bbbb cccc", Score: 0.5}},
+		},
+		{
+			name: "touching windows merge", docID: 7, passages: []Passage{passage(0, 0.3), passage(3, 0.6)}, mode: ContextWindow, window: 1,
+			want: []Passage{{ChunkIdx: 0, Start: 0, End: 24, Text: "aaaa bbbb cccc dddd eeee", Score: 0.6}},
+		},
+		{
+			name: "separate windows", docID: 7, passages: []Passage{passage(4, 0.6), passage(0, 0.3)}, mode: ContextWindow, window: 1,
+			want: []Passage{
+				{ChunkIdx: 0, Start: 0, End: 9, Text: "aaaa bbbb", Score: 0.3},
+				{ChunkIdx: 3, Start: 15, End: 24, Text: "dddd eeee", Score: 0.6},
+			},
+		},
+		{
+			name: "overlapping chunks cut once", docID: 3, passages: []Passage{passage(0, 0.2), passage(1, 0.7)}, mode: ContextChunk,
+			want: []Passage{{ChunkIdx: 0, Start: 0, End: 14, Text: "aaaa bbbb cccc", Score: 0.7}},
+		},
+		{
+			name: "ends at the furthest chunk end", docID: 3, passages: []Passage{passage(1, 0.7), passage(2, 0.1)}, mode: ContextChunk,
+			want: []Passage{{ChunkIdx: 1, Start: 5, End: 14, Text: "bbbb cccc", Score: 0.7}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			css := expansionFixture()
+			got := css.ExpandContext(tt.docID, tt.passages, tt.mode, tt.window)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
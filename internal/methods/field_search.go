@@ -0,0 +1,293 @@
+package methods
+
+import (
+	"context"
+	"encoding/gob"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/fs"
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/llms"
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/manifest"
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/model"
+)
+
+// Document fields that are embedded on their own for field search
+const (
+	FieldTitle       = "title"
+	FieldDescription = "description"
+	FieldKeywords    = "keywords" // generated by the LLM (llms.MovieKeywords)
+)
+
+var Fields = []string{FieldTitle, FieldDescription, FieldKeywords}
+
+// ParseFieldWeights parses weights like "title=0.3,description=0.7".
+// Fields left out weigh 0 and aren't embedded; an empty string is no
+// weights (field search off).
+func ParseFieldWeights(s string) (map[string]float64, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil, nil
+	}
+
+	weights := make(map[string]float64)
+	total := 0.0
+	for _, part := range strings.Split(s, ",") {
+		name, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		name = strings.TrimSpace(name)
+		if !ok {
+			return nil, fmt.Errorf("invalid field weight %q (expected field=weight)", part)
+		}
+		if !slices.Contains(Fields, name) {
+			return nil, fmt.Errorf("unknown field %q (allowed: %s)", name, strings.Join(Fields, ", "))
+		}
+		weight, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil || weight < 0 || math.IsNaN(weight) || math.IsInf(weight, 0) {
+			return nil, fmt.Errorf("invalid weight for field %s: %q (must be a number >= 0)", name, value)
+		}
+		if weight > 0 {
+			weights[name] = weight
+			total += weight
+		}
+	}
+	if total == 0 {
+		return nil, fmt.Errorf("field weights %q are all 0", s)
+	}
+	return weights, nil
+}
+
+// LoadOrCreateFieldEmbeddings loads (or builds) the embeddings of every
+// field with a weight and makes Search combine them: a document scores the
+// weighted mean of its fields' cosine similarities to the query.
+func (ss *SemanticSearch) LoadOrCreateFieldEmbeddings(docs []model.Movie, weights map[string]float64) error {
+	ss.Documents = docs
+	for _, doc := range docs {
+		ss.DocumentMap[doc.ID] = doc
+	}
+	ss.FieldWeights = weights
+	ss.FieldEmbeddings = make(map[string][][]float32, len(weights))
+	ss.fieldVectors = make(map[string]*vectorStore, len(weights))
+
+	for _, field := range sortedFields(weights) {
+		vectors, err := ss.loadOrCreateField(field)
+		if err != nil {
+			return err
+		}
+		ss.FieldEmbeddings[field] = vectors
+		ss.fieldVectors[field] = &vectorStore{}
+	}
+	return nil
+}
+
+func (ss *SemanticSearch) loadOrCreateField(field string) ([][]float32, error) {
+	path := fs.FieldEmbeddingsPath(field)
+	artifact := "movie_embeddings." + field + ".gob"
+
+	var vectors [][]float32
+	if file, err := os.Open(path); err == nil {
+		err = gob.NewDecoder(file).Decode(&vectors)
+		file.Close()
+		if err == nil {
+			if len(vectors) != len(ss.Documents) {
+				fmt.Printf("🔄 %s has %d vectors for %d documents, rebuilding\n", artifact, len(vectors), len(ss.Documents))
+			} else {
+				reasons := manifest.CheckEmbeddings(path, ss.fieldManifest(field, vectors), vectors, ss.Embedder.Info().Dimension)
+				rebuild, err := manifest.Resolve(artifact, reasons)
+				if err != nil {
+					return nil, err
+				}
+				if !rebuild {
+					fmt.Printf("📂 Loaded %s embeddings from disk.\n", field)
+					return vectors, nil
+				}
+			}
+		}
+	}
+
+	return ss.buildField(field)
+}
+
+func (ss *SemanticSearch) buildField(field string) ([][]float32, error) {
+	fmt.Printf("🔄 Building %s embeddings…\n", field)
+
+	texts, err := ss.fieldTexts(context.Background(), field)
+	if err != nil {
+		return nil, err
+	}
+
+	path := fs.FieldEmbeddingsPath(field)
+	vectors, ckpt, err := ss.createEmbeddingsParallel(texts, path, ss.fieldManifest(field, nil))
+	if err != nil {
+		return nil, err
+	}
+
+	err = fs.WriteAtomic(path, func(w io.Writer) error {
+		return gob.NewEncoder(w).Encode(vectors)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := manifest.Save(path, ss.fieldManifest(field, vectors)); err != nil {
+		return nil, err
+	}
+	if err := ckpt.Remove(); err != nil {
+		return nil, err
+	}
+
+	fmt.Printf("✅ %s embeddings built and saved.\n", field)
+	return vectors, nil
+}
+
+// fieldManifest describes the embeddings of one field; generated fields
+// also record the LLM, so switching models rebuilds them.
+func (ss *SemanticSearch) fieldManifest(field string, vectors [][]float32) manifest.Manifest {
+	params := "field=" + field
+	if field == FieldKeywords {
+		params += "|llm=" + llms.ModelName()
+	}
+	m := manifest.Manifest{
+		Artifact:       "movie_embeddings." + field + ".gob",
+		SourceHash:     manifest.SourceHash(ss.Documents),
+		DocIDs:         manifest.DocIDsHash(ss.Documents),
+		Documents:      len(ss.Documents),
+		EmbeddingModel: ss.Embedder.Info().Name(),
+		Params:         params,
+	}
+	m.SetVectors(vectors, ss.Embedder.Info().Dimension)
+	return m
+}
+
+// fieldTexts returns the text of field for every document, asking the LLM
+// (through its cache) for generated fields.
+func (ss *SemanticSearch) fieldTexts(ctx context.Context, field string) ([]string, error) {
+	texts := make([]string, len(ss.Documents))
+	switch field {
+	case FieldTitle:
+		for i, doc := range ss.Documents {
+			texts[i] = doc.Title
+		}
+		return texts, nil
+	case FieldDescription:
+		for i, doc := range ss.Documents {
+			texts[i] = doc.Description
+		}
+		return texts, nil
+	}
+
+	cache, err := llms.OpenEnrichCache()
+	if err != nil {
+		return nil, err
+	}
+	// Keep what was generated even if a later call fails
+	defer cache.Save()
+
+	hits, calls := 0, 0
+	defer func() {
+		fmt.Printf("🏷️ Keywords: %d cached, %d generated\n", hits, calls)
+	}()
+
+	for i, doc := range ss.Documents {
+		key := cache.Key(field, doc.Title, doc.Description)
+		if text, ok := cache.Texts[key]; ok {
+			texts[i] = text
+			hits++
+			continue
+		}
+
+		text, err := llms.MovieKeywords(ctx, doc.Title, doc.Description)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate %s of %q: %w", field, doc.Title, err)
+		}
+		cache.Put(key, text)
+		texts[i] = text
+		calls++
+		if calls%100 == 0 {
+			if err := cache.Save(); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return texts, nil
+}
+
+// searchFields scores every document by the weighted mean of its fields'
+// similarities to the query.
+func (ss *SemanticSearch) searchFields(query string, limit int) ([]SemanticSearchResult, error) {
+	fields := sortedFields(ss.FieldWeights)
+	queryEmbedding, err := ss.EmbedQuery(query, ss.FieldEmbeddings[fields[0]])
+	if err != nil {
+		return nil, fmt.Errorf("❌ Failed to create embedding of the query: %v\n", err)
+	}
+
+	total := 0.0
+	for _, field := range fields {
+		total += ss.FieldWeights[field]
+	}
+
+	scores := make([]float64, len(ss.Documents))
+	fieldScores := make(map[string][]float32, len(fields))
+	for _, field := range fields {
+		vectors := ss.FieldEmbeddings[field]
+		if len(vectors) != len(ss.Documents) {
+			return nil, fmt.Errorf("No %s embeddings loaded. Call `LoadOrCreateFieldEmbeddings` first.", field)
+		}
+		rebuild, err := checkQueryDimension("movie_embeddings."+field+".gob", ss.Embedder, queryEmbedding, vectors)
+		if err != nil {
+			return nil, err
+		}
+		if rebuild {
+			if vectors, err = ss.buildField(field); err != nil {
+				return nil, err
+			}
+			ss.FieldEmbeddings[field] = vectors
+		}
+
+		weight := ss.FieldWeights[field] / total
+		fieldScores[field] = ss.fieldVectors[field].get(vectors).Scores(queryEmbedding)
+		for i, score := range fieldScores[field] {
+			scores[i] += weight * float64(score)
+		}
+	}
+
+	order := make([]int, len(scores))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return scores[order[a]] > scores[order[b]]
+	})
+
+	results := make([]SemanticSearchResult, 0, limit)
+	for _, i := range order[:min(limit, len(order))] {
+		doc := ss.Documents[i]
+		perField := make(map[string]float64, len(fields))
+		for _, field := range fields {
+			perField[field] = float64(fieldScores[field][i])
+		}
+		results = append(results, SemanticSearchResult{
+			DocID:       doc.ID,
+			Score:       scores[i],
+			Title:       doc.Title,
+			Description: doc.Description,
+			FieldScores: perField,
+		})
+	}
+	return results, nil
+}
+
+// sortedFields lists the weighted fields in Fields order.
+func sortedFields(weights map[string]float64) []string {
+	fields := make([]string, 0, len(weights))
+	for _, field := range Fields {
+		if _, ok := weights[field]; ok {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
@@ -0,0 +1,43 @@
+package methods
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseFieldWeights(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      string
+		want    map[string]float64
+		wantErr bool
+	}{
+		{name: "empty", in: "", want: nil},
+		{name: "blank", in: "  ", want: nil},
+		{name: "one field", in: "title=1", want: map[string]float64{"title": 1}},
+		{name: "several fields", in: "title=0.3,description=0.7", want: map[string]float64{"title": 0.3, "description": 0.7}},
+		{name: "spaces", in: " title = 0.3 , keywords=0.2 ", want: map[string]float64{"title": 0.3, "keywords": 0.2}},
+		{name: "zero weights are left out", in: "title=0,description=1", want: map[string]float64{"description": 1}},
+		{name: "missing weight", in: "title", wantErr: true},
+		{name: "empty part", in: "title=1,", wantErr: true},
+		{name: "unknown field", in: "genre=1", wantErr: true},
+		{name: "field names are case sensitive", in: "Title=1", wantErr: true},
+		{name: "not a number", in: "title=high", wantErr: true},
+		{name: "empty weight", in: "title=", wantErr: true},
+		{name: "negative", in: "title=-0.5,description=1", wantErr: true},
+		{name: "NaN", in: "title=NaN", wantErr: true},
+		{name: "infinite", in: "title=Inf", wantErr: true},
+		{name: "all zero", in: "title=0,description=0", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseFieldWeights(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseFieldWeights(%q) err = %v, wantErr %v", tt.in, err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseFieldWeights(%q) = %v, want %v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
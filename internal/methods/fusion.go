@@ -0,0 +1,316 @@
+package methods
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/config"
+)
+
+// Fusion methods
+const (
+	FuseRRF     = "rrf"     // weighted Reciprocal Rank Fusion: Σ weight / (k + rank)
+	FuseRBF     = "rbf"     // rank-biased fusion: Σ weight·(1-p)·p^rank
+	FuseCombSUM = "combsum" // Σ weight·normalized score
+	FuseCombMNZ = "combmnz" // CombSUM × the number of legs that found the document
+)
+
+var FusionMethods = []string{FuseRRF, FuseRBF, FuseCombSUM, FuseCombMNZ}
+
+// Score normalizations applied per leg by CombSUM and CombMNZ
+const (
+	NormMinMax = "minmax" // Normalize
+	NormZScore = "zscore" // NormalizeZScore
+	NormDBSF   = "dbsf"   // NormalizeDBSF
+)
+
+var Normalizations = []string{NormMinMax, NormZScore, NormDBSF}
+
+// FuseOptions select how rankings are combined.
+type FuseOptions struct {
+	Method        string
+	Normalization string  // combsum, combmnz
+	K             int     // rrf
+	Persistence   float64 // rbf: chance of looking past each rank, 0..1
+}
+
+// FuseOptionsFromConfig are the fusion section's settings.
+func FuseOptionsFromConfig() FuseOptions {
+	cfg := config.Get().Fusion
+	return FuseOptions{
+		Method:        cfg.Method,
+		Normalization: cfg.Normalization,
+		K:             cfg.K,
+		Persistence:   cfg.RBFPersistence,
+	}
+}
+
+func (o FuseOptions) String() string {
+	switch o.Method {
+	case FuseRRF:
+		return fmt.Sprintf("rrf, k=%d", o.K)
+	case FuseRBF:
+		return fmt.Sprintf("rbf, p=%g", o.Persistence)
+	}
+	return o.Method + ", " + o.Normalization
+}
+
+// FusionLeg is a retriever with its weight in the fusion and the number of
+// candidates it retrieves (0 = the fusion's default depth).
+type FusionLeg struct {
+	Retriever Retriever
+	Weight    float64
+	Depth     int
+}
+
+// Fusion runs any number of retrievers and merges their rankings (see
+// Fuse).
+type Fusion struct {
+	Legs         []FusionLeg
+	Options      FuseOptions
+	DefaultDepth int
+}
+
+// FusedResult is a fused document; Ranks and Scores follow the order of
+// the legs (rank -1 and score 0 where a leg didn't retrieve it).
+type FusedResult struct {
+	DocID    int
+	Score    float64
+	Ranks    []int
+	Scores   []float64
+	Passages []Passage // from the first leg that has them
+}
+
+// Search runs every leg on query and returns the limit best fused results.
+func (f *Fusion) Search(ctx context.Context, query string, limit int) ([]FusedResult, error) {
+	rankings, err := f.Retrieve(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	results, err := Fuse(rankings, f.Weights(), f.Options)
+	if err != nil {
+		return nil, err
+	}
+	if limit < len(results) {
+		results = results[:limit]
+	}
+	return results, nil
+}
+
+// Retrieve runs every leg on query and returns their rankings, in the order
+// of the legs.
+func (f *Fusion) Retrieve(ctx context.Context, query string) ([][]Candidate, error) {
+	rankings := make([][]Candidate, len(f.Legs))
+	for i, l := range f.Legs {
+		depth := l.Depth
+		if depth <= 0 {
+			depth = f.DefaultDepth
+		}
+		candidates, err := l.Retriever.Retrieve(ctx, query, depth)
+		if err != nil {
+			return nil, fmt.Errorf("%s retriever failed: %w", l.Retriever.Name(), err)
+		}
+		rankings[i] = candidates
+	}
+	return rankings, nil
+}
+
+// Weights are the weights of the legs, in order.
+func (f *Fusion) Weights() []float64 {
+	weights := make([]float64, len(f.Legs))
+	for i, l := range f.Legs {
+		weights[i] = l.Weight
+	}
+	return weights
+}
+
+// Fuse merges rankings (one per leg, best first) into one, each leg
+// counting by its weight. Ties keep the document found at the best rank
+// first, then the lowest ID.
+func Fuse(rankings [][]Candidate, weights []float64, opts FuseOptions) ([]FusedResult, error) {
+	if !slices.Contains(FusionMethods, opts.Method) {
+		return nil, fmt.Errorf("unknown fusion method %q (allowed: %s)", opts.Method, strings.Join(FusionMethods, ", "))
+	}
+	combine := opts.Method == FuseCombSUM || opts.Method == FuseCombMNZ
+	if combine && !slices.Contains(Normalizations, opts.Normalization) {
+		return nil, fmt.Errorf("unknown normalization %q (allowed: %s)", opts.Normalization, strings.Join(Normalizations, ", "))
+	}
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+
+	byDoc := make(map[int]*FusedResult)
+	found := make(map[int]int)
+	for leg, candidates := range rankings {
+		candidates = dedupeCandidates(candidates)
+		var normalized []float64
+		if combine {
+			scores := make([]float64, len(candidates))
+			for i, c := range candidates {
+				scores[i] = c.Score
+			}
+			normalized = normalizeWith(opts.Normalization, scores)
+		}
+
+		for rank, c := range candidates {
+			r, ok := byDoc[c.DocID]
+			if !ok {
+				r = &FusedResult{
+					DocID:  c.DocID,
+					Ranks:  slices.Repeat([]int{-1}, len(rankings)),
+					Scores: make([]float64, len(rankings)),
+				}
+				byDoc[c.DocID] = r
+			}
+			r.Ranks[leg] = rank
+			r.Scores[leg] = c.Score
+			found[c.DocID]++
+			if r.Passages == nil {
+				r.Passages = c.Passages
+			}
+
+			switch opts.Method {
+			case FuseRRF:
+				r.Score += weights[leg] * CalcRRFScore(rank, opts.K)
+			case FuseRBF:
+				r.Score += weights[leg] * (1 - opts.Persistence) * math.Pow(opts.Persistence, float64(rank))
+			default:
+				r.Score += weights[leg] * normalized[rank]
+			}
+		}
+	}
+
+	results := make([]FusedResult, 0, len(byDoc))
+	for _, r := range byDoc {
+		if opts.Method == FuseCombMNZ {
+			r.Score *= float64(found[r.DocID])
+		}
+		results = append(results, *r)
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		if bi, bj := bestRank(results[i].Ranks), bestRank(results[j].Ranks); bi != bj {
+			return bi < bj
+		}
+		return results[i].DocID < results[j].DocID
+	})
+	return results, nil
+}
+
+// Validate rejects combinations that can't rank: CombMNZ multiplies by
+// the number of legs, which sinks documents with negative z-scores the more
+// legs find them.
+func (o FuseOptions) Validate() error {
+	if o.Method == FuseCombMNZ && o.Normalization == NormZScore {
+		return fmt.Errorf("%s needs non-negative scores; use %s or %s normalization", FuseCombMNZ, NormMinMax, NormDBSF)
+	}
+	return nil
+}
+
+// dedupeCandidates keeps the best-ranked entry of each document.
+func dedupeCandidates(candidates []Candidate) []Candidate {
+	seen := make(map[int]bool, len(candidates))
+	deduped := candidates[:0:0]
+	for _, c := range candidates {
+		if seen[c.DocID] {
+			continue
+		}
+		seen[c.DocID] = true
+		deduped = append(deduped, c)
+	}
+	return deduped
+}
+
+func normalizeWith(method string, scores []float64) []float64 {
+	switch method {
+	case NormZScore:
+		return NormalizeZScore(scores)
+	case NormDBSF:
+		return NormalizeDBSF(scores)
+	}
+	return Normalize(scores)
+}
+
+func bestRank(ranks []int) int {
+	best := -1
+	for _, rank := range ranks {
+		if rank >= 0 && (best < 0 || rank < best) {
+			best = rank
+		}
+	}
+	return best
+}
+
+// LegSpec is one entry of a retrievers spec.
+type LegSpec struct {
+	Name   string
+	Weight float64
+	Depth  int
+}
+
+// ParseLegSpecs parses a retrievers spec like "bm25=1,chunked=1,rm3=0.5@100":
+// name=weight, optionally @depth. A retriever may appear once.
+func ParseLegSpecs(spec string) ([]LegSpec, error) {
+	var specs []LegSpec
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(part, "=")
+		name = strings.TrimSpace(name)
+		if !ok {
+			return nil, fmt.Errorf("invalid retriever %q (expected name=weight[@depth])", part)
+		}
+		if !slices.Contains(RetrieverNames, name) {
+			return nil, fmt.Errorf("unknown retriever %q (allowed: %s)", name, strings.Join(RetrieverNames, ", "))
+		}
+		if slices.ContainsFunc(specs, func(s LegSpec) bool { return s.Name == name }) {
+			return nil, fmt.Errorf("retriever %q is listed twice", name)
+		}
+
+		weightStr, depthStr, hasDepth := strings.Cut(value, "@")
+		weight, err := strconv.ParseFloat(strings.TrimSpace(weightStr), 64)
+		if err != nil || weight < 0 {
+			return nil, fmt.Errorf("invalid weight for retriever %s: %q (must be a number >= 0)", name, weightStr)
+		}
+		depth := 0
+		if hasDepth {
+			depth, err = strconv.Atoi(strings.TrimSpace(depthStr))
+			if err != nil || depth < 0 {
+				return nil, fmt.Errorf("invalid depth for retriever %s: %q (must be an integer >= 0)", name, depthStr)
+			}
+		}
+		specs = append(specs, LegSpec{Name: name, Weight: weight, Depth: depth})
+	}
+	if len(specs) == 0 {
+		return nil, fmt.Errorf("no retrievers in %q", spec)
+	}
+	return specs, nil
+}
+
+// Fusion builds the fusion of a retrievers spec over this search, with
+// the default depth for limit results.
+func (hs *HybridSearch) Fusion(spec string, opts FuseOptions, limit int) (*Fusion, error) {
+	specs, err := ParseLegSpecs(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	f := &Fusion{Options: opts, DefaultDepth: hs.candidateDepth(limit)}
+	for _, s := range specs {
+		retriever, err := hs.Retriever(s.Name)
+		if err != nil {
+			return nil, err
+		}
+		f.Legs = append(f.Legs, FusionLeg{Retriever: retriever, Weight: s.Weight, Depth: s.Depth})
+	}
+	return f, nil
+}
@@ -0,0 +1,255 @@
+package methods
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+const epsilon = 1e-9
+
+func approxEqual(a, b []float64, tolerance float64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if math.Abs(a[i]-b[i]) > tolerance {
+			return false
+		}
+	}
+	return true
+}
+
+func TestNormalizations(t *testing.T) {
+	tests := []struct {
+		name      string
+		normalize func([]float64) []float64
+		in        []float64
+		want      []float64
+	}{
+		{"minmax", Normalize, []float64{1, 2, 3}, []float64{0, 0.5, 1}},
+		{"minmax equal", Normalize, []float64{2, 2}, []float64{1, 1}},
+		{"minmax empty", Normalize, nil, []float64{}},
+		{"zscore", NormalizeZScore, []float64{1, 2, 3}, []float64{-math.Sqrt(1.5), 0, math.Sqrt(1.5)}},
+		{"zscore equal", NormalizeZScore, []float64{4, 4, 4}, []float64{0, 0, 0}},
+		{"zscore empty", NormalizeZScore, nil, []float64{}},
+		{"dbsf", NormalizeDBSF, []float64{1, 2, 3}, []float64{0.5 - math.Sqrt(1.5)/6, 0.5, 0.5 + math.Sqrt(1.5)/6}},
+		{"dbsf equal", NormalizeDBSF, []float64{4, 4}, []float64{1, 1}},
+		// The outlier is more than 3σ above the mean, so it's clipped to 1
+		{"dbsf clips", NormalizeDBSF, []float64{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1}, []float64{
+			0.5 - math.Sqrt(0.1)/6, 0.5 - math.Sqrt(0.1)/6, 0.5 - math.Sqrt(0.1)/6, 0.5 - math.Sqrt(0.1)/6, 0.5 - math.Sqrt(0.1)/6,
+			0.5 - math.Sqrt(0.1)/6, 0.5 - math.Sqrt(0.1)/6, 0.5 - math.Sqrt(0.1)/6, 0.5 - math.Sqrt(0.1)/6, 0.5 - math.Sqrt(0.1)/6, 1,
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.normalize(tt.in); !approxEqual(got, tt.want, epsilon) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func candidates(ids ...int) []Candidate {
+	out := make([]Candidate, len(ids))
+	for i, id := range ids {
+		out[i] = Candidate{DocID: id, Score: float64(len(ids) - i)}
+	}
+	return out
+}
+
+func scored(pairs ...float64) []Candidate {
+	out := make([]Candidate, 0, len(pairs)/2)
+	for i := 0; i+1 < len(pairs); i += 2 {
+		out = append(out, Candidate{DocID: int(pairs[i]), Score: pairs[i+1]})
+	}
+	return out
+}
+
+func TestFuse(t *testing.T) {
+	// Leg 0 ranks 1, 2, 3; leg 1 ranks 3, 1, 4
+	rankings := [][]Candidate{
+		scored(1, 10, 2, 5, 3, 0),
+		scored(3, 4, 1, 3, 4, 0),
+	}
+	tests := []struct {
+		name       string
+		rankings   [][]Candidate
+		weights    []float64
+		opts       FuseOptions
+		wantIDs    []int
+		wantScores []float64
+	}{
+		{
+			name:       "rrf",
+			rankings:   rankings,
+			weights:    []float64{1, 1},
+			opts:       FuseOptions{Method: FuseRRF, K: 60},
+			wantIDs:    []int{1, 3, 2, 4},
+			wantScores: []float64{1.0/60 + 1.0/61, 1.0/62 + 1.0/60, 1.0 / 61, 1.0 / 62},
+		},
+		{
+			name:       "rrf zero weight leg",
+			rankings:   rankings,
+			weights:    []float64{1, 0},
+			opts:       FuseOptions{Method: FuseRRF, K: 60},
+			wantIDs:    []int{1, 2, 3, 4},
+			wantScores: []float64{1.0 / 60, 1.0 / 61, 1.0 / 62, 0},
+		},
+		{
+			name:       "rbf",
+			rankings:   rankings,
+			weights:    []float64{1, 1},
+			opts:       FuseOptions{Method: FuseRBF, Persistence: 0.5},
+			wantIDs:    []int{1, 3, 2, 4},
+			wantScores: []float64{0.75, 0.625, 0.25, 0.125},
+		},
+		{
+			name:       "combsum minmax",
+			rankings:   rankings,
+			weights:    []float64{1, 1},
+			opts:       FuseOptions{Method: FuseCombSUM, Normalization: NormMinMax},
+			wantIDs:    []int{1, 3, 2, 4},
+			wantScores: []float64{1.75, 1, 0.5, 0},
+		},
+		{
+			name:       "combmnz minmax",
+			rankings:   rankings,
+			weights:    []float64{1, 1},
+			opts:       FuseOptions{Method: FuseCombMNZ, Normalization: NormMinMax},
+			wantIDs:    []int{1, 3, 2, 4},
+			wantScores: []float64{3.5, 2, 0.5, 0},
+		},
+		{
+			name:       "equal scores at the same best rank go to the lowest id",
+			rankings:   [][]Candidate{candidates(5, 7), candidates(2)},
+			weights:    []float64{1, 1},
+			opts:       FuseOptions{Method: FuseRRF, K: 60},
+			wantIDs:    []int{2, 5, 7},
+			wantScores: []float64{1.0 / 60, 1.0 / 60, 1.0 / 61},
+		},
+		{
+			name:       "duplicates keep their best rank",
+			rankings:   [][]Candidate{candidates(1, 2, 1)},
+			weights:    []float64{1},
+			opts:       FuseOptions{Method: FuseRRF, K: 60},
+			wantIDs:    []int{1, 2},
+			wantScores: []float64{1.0 / 60, 1.0 / 61},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results, err := Fuse(tt.rankings, tt.weights, tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ids := make([]int, len(results))
+			scores := make([]float64, len(results))
+			for i, r := range results {
+				ids[i], scores[i] = r.DocID, r.Score
+			}
+			if !reflect.DeepEqual(ids, tt.wantIDs) {
+				t.Errorf("ids = %v, want %v", ids, tt.wantIDs)
+			}
+			if !approxEqual(scores, tt.wantScores, epsilon) {
+				t.Errorf("scores = %v, want %v", scores, tt.wantScores)
+			}
+		})
+	}
+}
+
+func TestFuseRanksAndPassages(t *testing.T) {
+	passages := []Passage{{}}
+	rankings := [][]Candidate{
+		candidates(1, 2),
+		{{DocID: 2, Score: 1, Passages: passages}},
+	}
+	results, err := Fuse(rankings, []float64{1, 1}, FuseOptions{Method: FuseRRF, K: 60})
+	if err != nil {
+		t.Fatal(err)
+	}
+	byID := map[int]FusedResult{}
+	for _, r := range results {
+		byID[r.DocID] = r
+	}
+	if got := byID[1].Ranks; !reflect.DeepEqual(got, []int{0, -1}) {
+		t.Errorf("ranks of doc 1 = %v, want [0 -1]", got)
+	}
+	if got := byID[2].Ranks; !reflect.DeepEqual(got, []int{1, 0}) {
+		t.Errorf("ranks of doc 2 = %v, want [1 0]", got)
+	}
+	if len(byID[2].Passages) != 1 || byID[1].Passages != nil {
+		t.Errorf("passages should come from the leg that has them: %+v", results)
+	}
+}
+
+func TestFuseErrors(t *testing.T) {
+	tests := []FuseOptions{
+		{Method: "borda"},
+		{Method: FuseCombSUM, Normalization: "rank"},
+		{Method: FuseCombMNZ, Normalization: NormZScore},
+	}
+	for _, opts := range tests {
+		if _, err := Fuse([][]Candidate{candidates(1)}, []float64{1}, opts); err == nil {
+			t.Errorf("%+v: expected an error", opts)
+		}
+	}
+}
+
+func TestFuseOptionsValidate(t *testing.T) {
+	tests := []struct {
+		opts    FuseOptions
+		wantErr bool
+	}{
+		{FuseOptions{Method: FuseCombMNZ, Normalization: NormZScore}, true},
+		{FuseOptions{Method: FuseCombMNZ, Normalization: NormMinMax}, false},
+		{FuseOptions{Method: FuseCombMNZ, Normalization: NormDBSF}, false},
+		{FuseOptions{Method: FuseCombSUM, Normalization: NormZScore}, false},
+		// Normalization is ignored by rank-based methods
+		{FuseOptions{Method: FuseRRF, Normalization: NormZScore, K: 60}, false},
+	}
+	for _, tt := range tests {
+		if err := tt.opts.Validate(); (err != nil) != tt.wantErr {
+			t.Errorf("Validate(%+v) = %v, want error: %v", tt.opts, err, tt.wantErr)
+		}
+	}
+}
+
+func TestParseLegSpecs(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    []LegSpec
+		wantErr bool
+	}{
+		{spec: "bm25=1", want: []LegSpec{{Name: RetrieverBM25, Weight: 1}}},
+		{
+			spec: "bm25=1,chunked=1,rm3=0.5@100",
+			want: []LegSpec{
+				{Name: RetrieverBM25, Weight: 1},
+				{Name: RetrieverChunked, Weight: 1},
+				{Name: RetrieverRM3, Weight: 0.5, Depth: 100},
+			},
+		},
+		{spec: " semantic = 2 @ 10 , ", want: []LegSpec{{Name: RetrieverSemantic, Weight: 2, Depth: 10}}},
+		{spec: "hyde=0", want: []LegSpec{{Name: RetrieverHyDE}}},
+		{spec: "", wantErr: true},
+		{spec: " , ", wantErr: true},
+		{spec: "bm25", wantErr: true},
+		{spec: "dense=1", wantErr: true},
+		{spec: "bm25=1,bm25=2", wantErr: true},
+		{spec: "bm25=-1", wantErr: true},
+		{spec: "bm25=x", wantErr: true},
+		{spec: "bm25=1@-5", wantErr: true},
+		{spec: "bm25=1@ten", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseLegSpecs(tt.spec)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseLegSpecs(%q) error = %v, want error: %v", tt.spec, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseLegSpecs(%q) = %+v, want %+v", tt.spec, got, tt.want)
+		}
+	}
+}
@@ -4,10 +4,12 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"math"
 	"slices"
 	"sort"
 	"strings"
 
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/config"
 	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/fs"
 	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/index"
 	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/llms"
@@ -30,17 +32,8 @@ type WeightedSearchResult struct {
 	HybridScore   float64
 	KeywordScore  float64
 	SemanticScore float64
+	Passages      []Passage
 }
-type RankedDoc struct {
-	DocID int
-	Ranks *CombinedRanks
-}
-type CombinedRanks struct {
-	RRFScore     float64
-	KeywordRank  int
-	SemanticRank int
-}
-
 type RRFSearchResult struct {
 	DocID        int
 	Title        string
@@ -48,6 +41,7 @@ type RRFSearchResult struct {
 	RRFScore     float64
 	KeywordRank  int
 	SemanticRank int
+	Passages     []Passage
 }
 
 type RRFSearchReRankedResult struct {
@@ -58,9 +52,12 @@ type RRFSearchReRankedResult struct {
 type HybridSearch struct {
 	Idx *index.InvertedIndex
 	Css *ChunkedSemanticSearch
+	// SemanticQuery, when set, is searched by the semantic leg instead of
+	// the query (e.g. a HyDE passage); the keyword leg keeps the query.
+	SemanticQuery string
 }
 
-func NewHybridSearch(modelName string) (*HybridSearch, error) {
+func NewHybridSearch() (*HybridSearch, error) {
 	// Create and build an inverted index
 	idx := index.NewInvertedIndex()
 	if err := idx.Build(); err != nil {
@@ -71,7 +68,7 @@ func NewHybridSearch(modelName string) (*HybridSearch, error) {
 	}
 
 	// Create and build chunked semantic search
-	css, err := NewChunkedSemanticSearch(modelName)
+	css, err := NewChunkedSemanticSearch()
 	if err != nil {
 		return nil, err
 	}
@@ -90,6 +87,13 @@ func NewHybridSearch(modelName string) (*HybridSearch, error) {
 	}, nil
 }
 
+func (hs *HybridSearch) semanticQuery(query string) string {
+	if hs.SemanticQuery != "" {
+		return hs.SemanticQuery
+	}
+	return query
+}
+
 func (hs *HybridSearch) bm25Search(query string, limit int) ([]index.SearchResult, error) {
 	err := hs.Idx.Load()
 	if err != nil {
@@ -100,7 +104,7 @@ func (hs *HybridSearch) bm25Search(query string, limit int) ([]index.SearchResul
 }
 
 func (hs *HybridSearch) WeightedSearch(query string, alpha float64, limit int) ([]WeightedSearchResult, error) {
-	searchLimit := min(limit*500, len(hs.Css.Documents))
+	searchLimit := hs.candidateDepth(limit)
 
 	// keyword search
 	keywordResults, err := hs.bm25Search(query, searchLimit)
@@ -115,7 +119,7 @@ func (hs *HybridSearch) WeightedSearch(query string, alpha float64, limit int) (
 	normalizedKeywordScores := Normalize(scores)
 
 	// semantic search
-	semanticResults, err := hs.Css.SearchChunked(query, searchLimit)
+	semanticResults, err := hs.Css.SearchChunked(hs.semanticQuery(query), searchLimit)
 	if err != nil {
 		return nil, fmt.Errorf("Failed to perform SearchChunked: %v\n", err)
 	}
@@ -166,6 +170,7 @@ func (hs *HybridSearch) WeightedSearch(query string, alpha float64, limit int) (
 		scoredDocs = scoredDocs[:limit]
 	}
 
+	passages := passagesByDoc(semanticResults)
 	results := make([]WeightedSearchResult, len(scoredDocs))
 
 	for i, d := range scoredDocs {
@@ -177,6 +182,7 @@ func (hs *HybridSearch) WeightedSearch(query string, alpha float64, limit int) (
 			HybridScore:   d.Scores.HybridScore,
 			KeywordScore:  d.Scores.KeywordScore,
 			SemanticScore: d.Scores.SemanticScore,
+			Passages:      passages[d.DocID],
 		}
 	}
 
@@ -184,80 +190,55 @@ func (hs *HybridSearch) WeightedSearch(query string, alpha float64, limit int) (
 
 }
 
+// RRFSearch fuses the keyword and chunked semantic rankings with
+// Reciprocal Rank Fusion (see Fusion).
 func (hs *HybridSearch) RRFSearch(query string, k int, limit int) ([]RRFSearchResult, error) {
-	searchLimit := min(limit*500, len(hs.Css.Documents))
-
-	// keyword search
-	keywordResults, err := hs.bm25Search(query, searchLimit)
-	if err != nil {
-		return nil, fmt.Errorf("Failed to perform bm25Search: %v\n", err)
+	fusion := &Fusion{
+		Legs: []FusionLeg{
+			{Retriever: &BM25Retriever{Idx: hs.Idx}, Weight: 1},
+			{Retriever: &ChunkedRetriever{Css: hs.Css, Query: hs.SemanticQuery}, Weight: 1},
+		},
+		Options:      FuseOptions{Method: FuseRRF, K: k},
+		DefaultDepth: hs.candidateDepth(limit),
 	}
-	// semantic search
-	semanticResults, err := hs.Css.SearchChunked(query, searchLimit)
+	fused, err := fusion.Search(context.Background(), query, limit)
 	if err != nil {
-		return nil, fmt.Errorf("Failed to perform SearchChunked: %v\n", err)
-	}
-
-	// combine results from both keyword and semantic
-	// map of docId -> rank info + score
-	combined := make(map[int]*CombinedRanks)
-
-	// Fill keyword ranks (+score)
-	for rank, result := range keywordResults {
-		combined[result.DocID] = &CombinedRanks{
-			KeywordRank:  rank,
-			SemanticRank: -1,
-			RRFScore:     CalcRRFScore(rank, k),
-		}
-	}
-
-	// Fill semantic ranks (+score)
-	for rank, result := range semanticResults {
-		cr, ok := combined[result.DocID]
-		if !ok {
-			combined[result.DocID] = &CombinedRanks{
-				KeywordRank:  -1,
-				SemanticRank: rank,
-				RRFScore:     CalcRRFScore(rank, k),
-			}
-		} else {
-			cr.SemanticRank = rank
-			cr.RRFScore = cr.RRFScore + CalcRRFScore(rank, k)
-		}
-	}
-
-	// map -> slice for sorting
-	rankedDocs := make([]RankedDoc, 0, len(combined))
-	for docID, ranksInfo := range combined {
-		rankedDocs = append(rankedDocs, RankedDoc{DocID: docID, Ranks: ranksInfo})
-	}
-
-	// sort by RRFScore (desc)
-	sort.Slice(rankedDocs, func(i, j int) bool {
-		return rankedDocs[i].Ranks.RRFScore > rankedDocs[j].Ranks.RRFScore
-	})
-
-	if limit < len(rankedDocs) {
-		rankedDocs = rankedDocs[:limit]
+		return nil, err
 	}
 
-	results := make([]RRFSearchResult, len(rankedDocs))
-
-	for i, d := range rankedDocs {
-		doc := hs.Css.DocumentMap[d.DocID]
+	results := make([]RRFSearchResult, len(fused))
+	for i, f := range fused {
+		doc := hs.Css.DocumentMap[f.DocID]
 		results[i] = RRFSearchResult{
-			DocID:        d.DocID,
+			DocID:        f.DocID,
 			Title:        doc.Title,
 			Description:  doc.Description,
-			RRFScore:     d.Ranks.RRFScore,
-			KeywordRank:  d.Ranks.KeywordRank,
-			SemanticRank: d.Ranks.SemanticRank,
+			RRFScore:     f.Score,
+			KeywordRank:  f.Ranks[0],
+			SemanticRank: f.Ranks[1],
+			Passages:     f.Passages,
 		}
 	}
 
 	return results, nil
 }
 
+// candidateDepth is how many candidates each leg retrieves for limit
+// results.
+func (hs *HybridSearch) candidateDepth(limit int) int {
+	return min(limit*config.Get().Hybrid.CandidateMultiplier, len(hs.Css.Documents))
+}
+
+// passagesByDoc indexes the matching passages of semantic results by DocID.
+// Documents found only by keyword have none.
+func passagesByDoc(results []SemanticSearchResult) map[int][]Passage {
+	passages := make(map[int][]Passage, len(results))
+	for _, r := range results {
+		passages[r.DocID] = r.Passages
+	}
+	return passages
+}
+
 func Normalize(inputs []float64) []float64 {
 	if len(inputs) == 0 {
 		return []float64{}
@@ -282,6 +263,52 @@ func Normalize(inputs []float64) []float64 {
 	return results
 }
 
+// NormalizeZScore maps scores to standard scores (how many standard
+// deviations from the mean); all 0 when the scores are equal.
+func NormalizeZScore(inputs []float64) []float64 {
+	mean, std := meanStd(inputs)
+	results := make([]float64, len(inputs))
+	if std == 0 {
+		return results
+	}
+	for i, v := range inputs {
+		results[i] = (v - mean) / std
+	}
+	return results
+}
+
+// NormalizeDBSF is distribution-based score fusion normalization: scores
+// are scaled from [mean-3σ, mean+3σ] to [0, 1] and clipped, so a single
+// outlier doesn't squash the others the way it does with min-max. Equal
+// scores all become 1, like Normalize.
+func NormalizeDBSF(inputs []float64) []float64 {
+	mean, std := meanStd(inputs)
+	results := make([]float64, len(inputs))
+	for i, v := range inputs {
+		if std == 0 {
+			results[i] = 1.0
+			continue
+		}
+		results[i] = min(max((v-(mean-3*std))/(6*std), 0), 1)
+	}
+	return results
+}
+
+func meanStd(inputs []float64) (float64, float64) {
+	if len(inputs) == 0 {
+		return 0, 0
+	}
+	var sum, sq float64
+	for _, v := range inputs {
+		sum += v
+	}
+	mean := sum / float64(len(inputs))
+	for _, v := range inputs {
+		sq += (v - mean) * (v - mean)
+	}
+	return mean, math.Sqrt(sq / float64(len(inputs)))
+}
+
 // alpha (or "α") is just a constant that we can use to dynamically control
 // the weighting between the two scores
 
@@ -289,6 +316,8 @@ func Normalize(inputs []float64) []float64 {
 // Exact match	  "The Revenant"	  0.8	            Title search needs keywords
 // Conceptual	  	"family movies"	  0.2	            Meaning matters more
 // Mixed	        "2015 comedies"	  0.5	            Both year AND concept
+//
+// --alpha auto picks it this way per query (see AutoAlpha).
 
 func HybridScore(bm25Score float64, semanticScore float64, alpha float64) float64 {
 	return alpha*bm25Score + (1-alpha)*semanticScore
@@ -376,8 +405,8 @@ func ReRankResults(query string, results []RRFSearchResult, method string) ([]RR
 			)
 		}
 
-		// Call Cohere rerank API
-		scores, err := llms.CohereRerankCrossEncoder(ctx, query, docs)
+		// Call Cohere rerank API (or the mock provider when offline)
+		scores, err := llms.CrossEncode(ctx, query, docs)
 		if err != nil {
 			return nil, err
 		}
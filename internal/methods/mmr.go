@@ -0,0 +1,146 @@
+package methods
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/config"
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/vecstore"
+)
+
+// DiversifyCandidates is how many candidates to fetch for limit results
+// when diversifying, so MMR has something to choose from.
+func DiversifyCandidates(limit int) int {
+	return limit * max(config.Get().MMR.CandidateMultiplier, 1)
+}
+
+// Diversify re-ranks candidates (relevance order, with their scores) by
+// Maximal Marginal Relevance and returns the positions of the first k picks.
+// Each pick maximizes
+//
+//	lambda*relevance - (1-lambda)*max similarity to the documents already picked
+//
+// where relevance is min-max normalized over the candidates and similarity
+// is the cosine between the stored document embeddings. lambda 1 keeps the
+// relevance order; lower values trade relevance for variety.
+func (ss *SemanticSearch) Diversify(docIDs []int, scores []float64, lambda float64, k int) ([]int, error) {
+	if lambda < 0 || lambda > 1 {
+		return nil, fmt.Errorf("diversify lambda must be between 0 and 1 (got %g)", lambda)
+	}
+	if len(ss.Embeddings) != len(ss.Documents) {
+		if _, err := ss.LoadOrCreateEmbeddings(ss.Documents); err != nil {
+			return nil, err
+		}
+	}
+
+	store := ss.vectors.get(ss.Embeddings)
+	positions := make(map[int]int, len(ss.Documents))
+	for i, doc := range ss.Documents {
+		positions[doc.ID] = i
+	}
+	vectors := make([][]float32, len(docIDs))
+	for i, id := range docIDs {
+		if pos, ok := positions[id]; ok {
+			vectors[i] = store.Vector(pos)
+		}
+	}
+
+	relevance := Normalize(scores)
+	k = min(k, len(docIDs))
+	picked := make([]int, 0, k)
+	taken := make([]bool, len(docIDs))
+	// maxSim[i] is candidate i's highest similarity to any pick so far
+	maxSim := make([]float64, len(docIDs))
+
+	for len(picked) < k {
+		best, bestScore := -1, math.Inf(-1)
+		for i := range docIDs {
+			if taken[i] {
+				continue
+			}
+			score := lambda * relevance[i]
+			if len(picked) > 0 {
+				score -= (1 - lambda) * maxSim[i]
+			}
+			if score > bestScore {
+				best, bestScore = i, score
+			}
+		}
+
+		picked = append(picked, best)
+		taken[best] = true
+		for i := range docIDs {
+			if !taken[i] {
+				maxSim[i] = max(maxSim[i], similarity(vectors[i], vectors[best]))
+			}
+		}
+	}
+
+	return picked, nil
+}
+
+// similarity is the cosine of two normalized vectors, 0 if either is
+// missing.
+func similarity(a, b []float32) float64 {
+	if a == nil || b == nil {
+		return 0
+	}
+	return float64(vecstore.Dot(a, b))
+}
+
+// DiversifySemantic applies Diversify to semantic or chunked results.
+func (ss *SemanticSearch) DiversifySemantic(results []SemanticSearchResult, lambda float64, limit int) ([]SemanticSearchResult, error) {
+	docIDs := make([]int, len(results))
+	scores := make([]float64, len(results))
+	for i, r := range results {
+		docIDs[i], scores[i] = r.DocID, r.Score
+	}
+	order, err := ss.Diversify(docIDs, scores, lambda, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	diversified := make([]SemanticSearchResult, len(order))
+	for i, idx := range order {
+		diversified[i] = results[idx]
+	}
+	return diversified, nil
+}
+
+// DiversifyRRF applies Diversify to RRF results.
+func (hs *HybridSearch) DiversifyRRF(results []RRFSearchResult, lambda float64, limit int) ([]RRFSearchResult, error) {
+	docIDs := make([]int, len(results))
+	scores := make([]float64, len(results))
+	for i, r := range results {
+		docIDs[i], scores[i] = r.DocID, r.RRFScore
+	}
+	order, err := hs.Css.Diversify(docIDs, scores, lambda, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	diversified := make([]RRFSearchResult, len(order))
+	for i, idx := range order {
+		diversified[i] = results[idx]
+	}
+	return diversified, nil
+}
+
+// DiversifyWeighted applies Diversify to weighted hybrid results.
+func (hs *HybridSearch) DiversifyWeighted(results []WeightedSearchResult, lambda float64, limit int) ([]WeightedSearchResult, error) {
+	docIDs := make([]int, len(results))
+	scores := make([]float64, len(results))
+	for i, r := range results {
+		docIDs[i], scores[i] = r.DocID, r.HybridScore
+	}
+	order, err := hs.Css.Diversify(docIDs, scores, lambda, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	diversified := make([]WeightedSearchResult, len(order))
+	for i, idx := range order {
+		diversified[i] = results[idx]
+	}
+	return diversified, nil
+}
@@ -0,0 +1,68 @@
+package methods
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/model"
+)
+
+// mmrFixture has a near duplicate of document 1 (2), an unrelated document
+// (3) and one in between (4).
+func mmrFixture() *SemanticSearch {
+	docs := []model.Movie{{ID: 1}, {ID: 2}, {ID: 3}, {ID: 4}}
+	return &SemanticSearch{
+		Documents:  docs,
+		Embeddings: [][]float32{{1, 0}, {0.99, 0.14}, {0, 1}, {0.6, 0.8}},
+	}
+}
+
+func TestDiversify(t *testing.T) {
+	docIDs := []int{1, 2, 3, 4}
+	scores := []float64{1.0, 0.9, 0.5, 0.1}
+
+	tests := []struct {
+		name    string
+		docIDs  []int
+		lambda  float64
+		k       int
+		want    []int
+		wantErr bool
+	}{
+		{name: "lambda 1 keeps relevance order", docIDs: docIDs, lambda: 1, k: 4, want: []int{0, 1, 2, 3}},
+		{name: "lambda 0 only avoids similar picks", docIDs: docIDs, lambda: 0, k: 4, want: []int{0, 2, 3, 1}},
+		{name: "lambda 0.5", docIDs: docIDs, lambda: 0.5, k: 4, want: []int{0, 2, 1, 3}},
+		{name: "first k picks", docIDs: docIDs, lambda: 0, k: 2, want: []int{0, 2}},
+		{name: "k over candidates", docIDs: docIDs, lambda: 1, k: 10, want: []int{0, 1, 2, 3}},
+		// Without an embedding, 9 is similar to nothing
+		{name: "unknown document", docIDs: []int{1, 2, 9, 4}, lambda: 0, k: 4, want: []int{0, 2, 3, 1}},
+		{name: "lambda below 0", docIDs: docIDs, lambda: -0.1, k: 4, wantErr: true},
+		{name: "lambda above 1", docIDs: docIDs, lambda: 1.1, k: 4, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := mmrFixture().Diversify(tt.docIDs, scores, tt.lambda, tt.k)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Diversify = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiversifySemantic(t *testing.T) {
+	results := []SemanticSearchResult{
+		{DocID: 1, Score: 0.9},
+		{DocID: 2, Score: 0.8},
+		{DocID: 3, Score: 0.2},
+	}
+	got, err := mmrFixture().DiversifySemantic(results, 0, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0].DocID != 1 || got[1].DocID != 3 {
+		t.Errorf("DiversifySemantic = %+v, want documents 1 and 3", got)
+	}
+}
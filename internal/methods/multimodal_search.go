@@ -4,15 +4,18 @@ import (
 	"context"
 	"encoding/gob"
 	"fmt"
+	"io"
 	"log"
-	"math"
 	"mime"
 	"os"
 	"path/filepath"
-	"sort"
 
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/ann"
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/config"
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/embed"
 	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/fs"
 	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/llms"
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/manifest"
 	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/model"
 )
 
@@ -20,26 +23,37 @@ type ImageSearchResult struct {
 	ID          int
 	Title       string
 	Description string
-	Score       float32
+	Score       float64
 }
 
 type similaryScore struct {
 	idx   int
-	score float32
+	score float64
 }
 
 type MultimodalSearch struct {
+	Embedder       embed.Embedder
 	Documents      []model.Movie
 	DocsEmbeddings [][]float32
+	ANN            *ann.HNSW // optional, set by LoadOrBuildANN
+
+	vectors vectorStore // exact scans over DocsEmbeddings
 }
 
+// NewMultimodalSearch uses the embedder configured in the multimodal section.
 func NewMultimodalSearch() (*MultimodalSearch, error) {
 	docs, err := fs.LoadMovies()
 	if err != nil {
 		log.Fatalf("❌ Failed to load movies: %v\n", err)
 	}
 
+	embedder, err := embed.MultimodalFromConfig()
+	if err != nil {
+		return nil, err
+	}
+
 	return &MultimodalSearch{
+		Embedder:       embedder,
 		Documents:      docs,
 		DocsEmbeddings: make([][]float32, 0),
 	}, nil
@@ -52,12 +66,19 @@ func (mms *MultimodalSearch) LoadOrCreateEmbeddings() ([][]float32, error) {
 	if embErr == nil {
 		// Try loading embeddings
 		if err := mms.loadDocsEmbeddings(); err == nil {
-			// Check dimensions mismatch
-			if len(mms.DocsEmbeddings) > 0 && len(mms.DocsEmbeddings[0]) != 768 {
-				fmt.Printf("Dimension mismatch (got %d, expected 768). Rebuilding all embeddings...\n", len(mms.DocsEmbeddings[0]))
+			// One vector per document, or the artifact is unusable
+			if len(mms.DocsEmbeddings) != len(mms.Documents) {
+				fmt.Printf("🔄 multimodal_embeddings.gob has %d vectors for %d documents, rebuilding\n", len(mms.DocsEmbeddings), len(mms.Documents))
 			} else {
-				fmt.Println("Loaded existing chunk embeddings + metadata from disk.")
-				return mms.DocsEmbeddings, nil
+				reasons := manifest.CheckEmbeddings(fs.MultimodalEmbeddingsPath, mms.embeddingsManifest(), mms.DocsEmbeddings, mms.Embedder.Info().Dimension)
+				rebuild, err := manifest.Resolve("multimodal_embeddings.gob", reasons)
+				if err != nil {
+					return nil, err
+				}
+				if !rebuild {
+					fmt.Println("Loaded existing multimodal embeddings from disk.")
+					return mms.DocsEmbeddings, nil
+				}
 			}
 		} else {
 			// If loading fails → rebuild
@@ -83,39 +104,49 @@ func (mms *MultimodalSearch) loadDocsEmbeddings() error {
 }
 
 func (mms *MultimodalSearch) saveEmbeddings() error {
-	file, err := os.Create(fs.MultimodalEmbeddingsPath)
-	if err != nil {
-		return err
-	}
-	defer file.Close()
-
-	encoder := gob.NewEncoder(file)
-	return encoder.Encode(mms.DocsEmbeddings)
+	return fs.WriteAtomic(fs.MultimodalEmbeddingsPath, func(w io.Writer) error {
+		return gob.NewEncoder(w).Encode(mms.DocsEmbeddings)
+	})
 }
 
 func (mms *MultimodalSearch) BuildEmbeddings() ([][]float32, error) {
 	// Build strings: "title: description"
 	docsAsStrings := make([]string, len(mms.Documents))
 	for i, doc := range mms.Documents {
-		// mms.DocumentMap[doc.ID] = doc
 		docsAsStrings[i] = fmt.Sprintf("%s: %s", doc.Title, doc.Description)
 	}
 
-	// Create embeddings for all dcos
-	embeddings, err := llms.BuildMultimodalEmbeddingsBatch(context.Background(), mms.Documents)
+	// Create embeddings for all docs, one batch at a time
+	embeddings, stats, err := embed.Parallel(context.Background(), mms.Embedder, docsAsStrings, 1, config.Get().Embedding.MaxRetries, nil)
 	if err != nil {
 		return nil, err
 	}
+	fmt.Printf("⏱️ Embedded %s\n", stats)
 
 	mms.DocsEmbeddings = embeddings
 
 	if err = mms.saveEmbeddings(); err != nil {
 		return nil, fmt.Errorf("Error saving ChunksEmbeddings to file: %w", err)
 	}
+	if err = manifest.Save(fs.MultimodalEmbeddingsPath, mms.embeddingsManifest()); err != nil {
+		return nil, err
+	}
 
 	return mms.DocsEmbeddings, nil
 }
 
+func (mms *MultimodalSearch) embeddingsManifest() manifest.Manifest {
+	m := manifest.Manifest{
+		Artifact:       "multimodal_embeddings.gob",
+		SourceHash:     manifest.SourceHash(mms.Documents),
+		DocIDs:         manifest.DocIDsHash(mms.Documents),
+		Documents:      len(mms.Documents),
+		EmbeddingModel: mms.Embedder.Info().Name(),
+	}
+	m.SetVectors(mms.DocsEmbeddings, mms.Embedder.Info().Dimension)
+	return m
+}
+
 func (mms *MultimodalSearch) ImageSearch(ctx context.Context, imagePath string, limit int) ([]ImageSearchResult, error) {
 
 	imageBytes, err := os.ReadFile(imagePath)
@@ -130,23 +161,40 @@ func (mms *MultimodalSearch) ImageSearch(ctx context.Context, imagePath string,
 		mime = "image/jpeg"
 	}
 
-	imageEmbedding, err := llms.EmbedImage(ctx, imageBytes, mime)
+	// Describe-then-embed: see llms.DescribeImage
+	description, err := llms.DescribeImage(ctx, imageBytes, mime)
+	if err != nil {
+		return nil, err
+	}
+	imageEmbedding, err := embed.One(ctx, mms.Embedder, description)
 	if err != nil {
 		return nil, fmt.Errorf("Error creating image embedding: %v", err)
 	}
+	rebuild, err := checkQueryDimension("multimodal_embeddings.gob", mms.Embedder, imageEmbedding, mms.DocsEmbeddings)
+	if err != nil {
+		return nil, err
+	}
+	if rebuild {
+		if _, err := mms.BuildEmbeddings(); err != nil {
+			return nil, err
+		}
+		// The index over the old vectors is rebuilt on the next load
+		mms.ANN = nil
+	}
 
 	var scores []similaryScore
-	for i, emb := range mms.DocsEmbeddings {
-		score := CosineSimilarityFloat32(imageEmbedding, emb)
-		scores = append(scores, similaryScore{i, score})
+	if mms.ANN != nil {
+		for _, hit := range mms.ANN.Search(imageEmbedding, limit) {
+			scores = append(scores, similaryScore{hit.ID, float64(hit.Score)})
+		}
+	} else {
+		for _, hit := range mms.vectors.get(mms.DocsEmbeddings).Search(imageEmbedding, limit) {
+			scores = append(scores, similaryScore{hit.ID, float64(hit.Score)})
+		}
 	}
 
-	sort.Slice(scores, func(i, j int) bool {
-		return scores[i].score > scores[j].score
-	})
-
 	var results []ImageSearchResult
-	for _, s := range scores[:limit] {
+	for _, s := range scores[:min(limit, len(scores))] {
 		m := mms.Documents[s.idx]
 		results = append(results, ImageSearchResult{
 			ID:          m.ID,
@@ -158,13 +206,3 @@ func (mms *MultimodalSearch) ImageSearch(ctx context.Context, imagePath string,
 
 	return results, nil
 }
-
-func CosineSimilarityFloat32(a, b []float32) float32 {
-	var dot, na, nb float32
-	for i := range a {
-		dot += a[i] * b[i]
-		na += a[i] * a[i]
-		nb += b[i] * b[i]
-	}
-	return dot / float32(math.Sqrt(float64(na*nb)))
-}
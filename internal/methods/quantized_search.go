@@ -0,0 +1,151 @@
+package methods
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/config"
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/fs"
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/manifest"
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/quant"
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/vecstore"
+)
+
+// QuantizeParams returns the configured product quantization parameters.
+func QuantizeParams() quant.Params {
+	cfg := config.Get().Quantize
+	return quant.Params{
+		Subvectors: cfg.PQSubvectors,
+		Iterations: cfg.PQIterations,
+	}
+}
+
+// QuantizedPaths returns the quantized file for a target (docs or chunks)
+// and the full-precision embeddings it is derived from.
+func QuantizedPaths(target string, kind string) (path string, sourcePath string) {
+	sourcePath = fs.EmbeddingsPath
+	if target == ANNTargetChunks {
+		sourcePath = fs.ChunksEmbeddingsPath
+	}
+	return fs.QuantizedPath(sourcePath, kind), sourcePath
+}
+
+// BuildQuantized quantizes vectors and saves them with their manifest.
+func BuildQuantized(target string, kind string, vectors [][]float32, params quant.Params) (quant.Quantizer, error) {
+	path, sourcePath := QuantizedPaths(target, kind)
+
+	fmt.Printf("🔄 Quantizing %d %s vectors as %s…\n", len(vectors), target, quant.Fingerprint(kind, params))
+	q, err := quant.Train(kind, vectors, params)
+	if err != nil {
+		return nil, err
+	}
+	if err := quant.Save(path, q); err != nil {
+		return nil, err
+	}
+
+	m, err := derivedManifest(path, sourcePath, vectors, quant.Fingerprint(kind, params))
+	if err != nil {
+		return nil, err
+	}
+	if err := manifest.Save(path, m); err != nil {
+		return nil, err
+	}
+
+	return q, nil
+}
+
+// LoadOrBuildQuantized loads the quantized copy of a target's embeddings,
+// rebuilding it when missing or stale.
+func LoadOrBuildQuantized(target string, kind string, vectors [][]float32, params quant.Params) (quant.Quantizer, error) {
+	path, sourcePath := QuantizedPaths(target, kind)
+
+	if _, err := os.Stat(path); err == nil {
+		q, err := quant.Load(path)
+		if err == nil {
+			expected, err := derivedManifest(path, sourcePath, vectors, quant.Fingerprint(kind, params))
+			if err != nil {
+				return nil, err
+			}
+			reasons := manifest.Check(path, expected)
+			rebuild, err := manifest.Resolve(filepath.Base(path), reasons)
+			if err != nil {
+				return nil, err
+			}
+			if !rebuild {
+				fmt.Printf("📂 Loaded %s embeddings from disk.\n", kind)
+				return q, nil
+			}
+		} else {
+			fmt.Printf("⚠️ %v. Rebuilding quantized embeddings...\n", err)
+		}
+	}
+
+	return BuildQuantized(target, kind, vectors, params)
+}
+
+// UseStorage switches Search to the quantized copy of the document
+// embeddings. rescore > 0 re-ranks limit*rescore candidates with the
+// full-precision vectors.
+func (ss *SemanticSearch) UseStorage(kind string, rescore int) error {
+	if kind == quant.KindFloat {
+		return nil
+	}
+	q, err := LoadOrBuildQuantized(ANNTargetDocs, kind, ss.Embeddings, QuantizeParams())
+	if err != nil {
+		return err
+	}
+	ss.Quantized = q
+	ss.Rescore = rescore
+	return nil
+}
+
+// UseChunksStorage is UseStorage for the chunk embeddings.
+func (css *ChunkedSemanticSearch) UseChunksStorage(kind string, rescore int) error {
+	if kind == quant.KindFloat {
+		return nil
+	}
+	q, err := LoadOrBuildQuantized(ANNTargetChunks, kind, css.ChunksEmbeddings, QuantizeParams())
+	if err != nil {
+		return err
+	}
+	css.ChunksQuantized = q
+	css.Rescore = rescore
+	return nil
+}
+
+type scoredVector struct {
+	idx   int
+	score float64
+}
+
+// quantizedTopK scores every vector with the quantized codes and returns
+// the best n. With rescore > 0, n*rescore candidates are re-ranked using
+// the full-precision vectors first.
+func quantizedTopK(q quant.Quantizer, full [][]float32, query []float32, n int, rescore int) ([]scoredVector, error) {
+	scores, err := q.Scores(query)
+	if err != nil {
+		return nil, err
+	}
+
+	if rescore <= 0 {
+		ids := vecstore.TopScores(scores, n)
+		results := make([]scoredVector, len(ids))
+		for i, id := range ids {
+			results[i] = scoredVector{id, float64(scores[id])}
+		}
+		return results, nil
+	}
+
+	ids := vecstore.TopScores(scores, n*rescore)
+	results := make([]scoredVector, len(ids))
+	for i, id := range ids {
+		results[i] = scoredVector{id, CosineSimilarity(query, full[id])}
+	}
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].score > results[j].score
+	})
+
+	return results[:min(n, len(results))], nil
+}
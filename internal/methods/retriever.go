@@ -0,0 +1,154 @@
+package methods
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/config"
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/index"
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/llms"
+)
+
+// Candidate is a document found by a Retriever, best first.
+type Candidate struct {
+	DocID    int
+	Score    float64
+	Passages []Passage // best-matching chunks (chunked retrievers only)
+}
+
+// Retriever is one leg of a fusion: it returns the k documents it ranks
+// best for query.
+type Retriever interface {
+	Name() string
+	Retrieve(ctx context.Context, query string, k int) ([]Candidate, error)
+}
+
+// Retrievers available to the fusion engine
+const (
+	RetrieverBM25     = "bm25"     // keyword search
+	RetrieverRM3      = "rm3"      // keyword search with an RM3-expanded query
+	RetrieverSemantic = "semantic" // one embedding per document
+	RetrieverChunked  = "chunked"  // chunk embeddings, aggregated per document
+	RetrieverHyDE     = "hyde"     // chunk embeddings of a hypothetical description
+)
+
+var RetrieverNames = []string{RetrieverBM25, RetrieverRM3, RetrieverSemantic, RetrieverChunked, RetrieverHyDE}
+
+// Retriever returns the named retriever over this search's index and
+// embeddings.
+func (hs *HybridSearch) Retriever(name string) (Retriever, error) {
+	switch name {
+	case RetrieverBM25:
+		return &BM25Retriever{Idx: hs.Idx}, nil
+	case RetrieverRM3:
+		cfg := config.Get().Keyword
+		return &RM3Retriever{Idx: hs.Idx, Params: index.RM3Params{
+			FeedbackDocs:   cfg.RM3FeedbackDocs,
+			FeedbackTerms:  cfg.RM3FeedbackTerms,
+			OriginalWeight: cfg.RM3OriginalWeight,
+		}}, nil
+	case RetrieverSemantic:
+		return &SemanticRetriever{Ss: hs.Css.SemanticSearch}, nil
+	case RetrieverChunked:
+		return &ChunkedRetriever{Css: hs.Css, Query: hs.SemanticQuery}, nil
+	case RetrieverHyDE:
+		return &HyDERetriever{Css: hs.Css}, nil
+	}
+	return nil, fmt.Errorf("unknown retriever %q", name)
+}
+
+type BM25Retriever struct {
+	Idx *index.InvertedIndex
+}
+
+func (r *BM25Retriever) Name() string { return RetrieverBM25 }
+
+func (r *BM25Retriever) Retrieve(ctx context.Context, query string, k int) ([]Candidate, error) {
+	return indexCandidates(r.Idx.Bm25Search(query, k)), nil
+}
+
+type RM3Retriever struct {
+	Idx    *index.InvertedIndex
+	Params index.RM3Params
+}
+
+func (r *RM3Retriever) Name() string { return RetrieverRM3 }
+
+func (r *RM3Retriever) Retrieve(ctx context.Context, query string, k int) ([]Candidate, error) {
+	return indexCandidates(r.Idx.RM3Search(query, k, r.Params)), nil
+}
+
+func indexCandidates(results []index.SearchResult) []Candidate {
+	candidates := make([]Candidate, len(results))
+	for i, r := range results {
+		candidates[i] = Candidate{DocID: r.DocID, Score: r.Score}
+	}
+	return candidates
+}
+
+// SemanticRetriever loads the document embeddings on first use.
+type SemanticRetriever struct {
+	Ss *SemanticSearch
+}
+
+func (r *SemanticRetriever) Name() string { return RetrieverSemantic }
+
+func (r *SemanticRetriever) Retrieve(ctx context.Context, query string, k int) ([]Candidate, error) {
+	if len(r.Ss.Embeddings) != len(r.Ss.Documents) {
+		if _, err := r.Ss.LoadOrCreateEmbeddings(r.Ss.Documents); err != nil {
+			return nil, err
+		}
+	}
+	results, err := r.Ss.Search(query, k)
+	if err != nil {
+		return nil, err
+	}
+	return semanticCandidates(results), nil
+}
+
+// ChunkedRetriever searches Query instead of the query when it's set.
+type ChunkedRetriever struct {
+	Css   *ChunkedSemanticSearch
+	Query string
+}
+
+func (r *ChunkedRetriever) Name() string { return RetrieverChunked }
+
+func (r *ChunkedRetriever) Retrieve(ctx context.Context, query string, k int) ([]Candidate, error) {
+	if r.Query != "" {
+		query = r.Query
+	}
+	results, err := r.Css.SearchChunked(query, k)
+	if err != nil {
+		return nil, err
+	}
+	return semanticCandidates(results), nil
+}
+
+// HyDERetriever has the LLM write a hypothetical description answering the
+// query and searches the chunks with it.
+type HyDERetriever struct {
+	Css *ChunkedSemanticSearch
+}
+
+func (r *HyDERetriever) Name() string { return RetrieverHyDE }
+
+func (r *HyDERetriever) Retrieve(ctx context.Context, query string, k int) ([]Candidate, error) {
+	passage, err := llms.PreProcessQuery(ctx, query, llms.EnhanceHyDE)
+	if err != nil {
+		return nil, err
+	}
+	results, err := r.Css.SearchChunked(passage, k)
+	if err != nil {
+		return nil, err
+	}
+	return semanticCandidates(results), nil
+}
+
+func semanticCandidates(results []SemanticSearchResult) []Candidate {
+	candidates := make([]Candidate, len(results))
+	for i, r := range results {
+		candidates[i] = Candidate{DocID: r.DocID, Score: r.Score, Passages: r.Passages}
+	}
+	return candidates
+}
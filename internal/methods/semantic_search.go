@@ -4,18 +4,25 @@ import (
 	"context"
 	"encoding/gob"
 	"fmt"
+	"io"
 	"math"
 	"os"
-	"regexp"
+	"os/signal"
 	"runtime"
-	"sort"
 	"strings"
 	"sync"
+	"syscall"
 
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/ann"
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/checkpoint"
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/chunk"
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/config"
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/embed"
 	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/fs"
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/manifest"
 	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/model"
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/quant"
 
-	ollama "github.com/ollama/ollama/api"
 	"github.com/vbauerster/mpb/v8"
 	"github.com/vbauerster/mpb/v8/decor"
 )
@@ -23,6 +30,7 @@ import (
 type SimilarityScore struct {
 	Score float64
 	Movie model.Movie
+	Idx   int // position in Documents
 }
 
 type SemanticSearchResult struct {
@@ -30,81 +38,85 @@ type SemanticSearchResult struct {
 	Score       float64
 	Title       string
 	Description string
+	Passages    []Passage          // best-matching chunks (chunked search only)
+	FieldScores map[string]float64 // similarity per field (field search only)
 }
 
 type SemanticSearch struct {
-	Model       string
-	client      *ollama.Client
+	Embedder    embed.Embedder
 	Documents   []model.Movie
 	DocumentMap map[int]model.Movie
-	Embeddings  [][]float64
+	Embeddings  [][]float32
+	ANN         *ann.HNSW // optional, set by LoadOrBuildANN
+
+	// Optional quantized copy of Embeddings, set by UseStorage
+	Quantized quant.Quantizer
+	Rescore   int
+
+	// Resume continues an interrupted embedding build from its checkpoint
+	Resume bool
+
+	// Optional per-field embeddings, set by LoadOrCreateFieldEmbeddings;
+	// Search then combines the fields' similarities with FieldWeights
+	FieldEmbeddings map[string][][]float32
+	FieldWeights    map[string]float64
+
+	vectors      vectorStore // exact scans over Embeddings
+	fieldVectors map[string]*vectorStore
 }
 
-func NewSemanticSearch(modelName string) (*SemanticSearch, error) {
-	client, err := ollama.ClientFromEnvironment()
+// NewSemanticSearch uses the embedder configured in the embedding section.
+func NewSemanticSearch() (*SemanticSearch, error) {
+	embedder, err := embed.FromConfig()
 	if err != nil {
 		return nil, err
 	}
 	return &SemanticSearch{
-		Model:       modelName,
-		client:      client,
+		Embedder:    embedder,
 		DocumentMap: make(map[int]model.Movie),
 	}, nil
 }
 
 func (ss *SemanticSearch) VerifyModel() error {
-	fmt.Println("Model loaded:", ss.Model)
-	resp, err := ss.client.Embeddings(context.Background(), &ollama.EmbeddingRequest{
-		Model:  ss.Model,
-		Prompt: "test",
-	})
+	info := ss.Embedder.Info()
+	fmt.Printf("Model loaded: %s (%s)\n", info.Model, info.Provider)
+	embedding, err := ss.EmbedText("test")
 	if err != nil {
 		return err
 	}
 
-	fmt.Println("Vector dimensions:", len(resp.Embedding))
+	fmt.Println("Vector dimensions:", len(embedding))
 	return nil
 }
 
-func (ss *SemanticSearch) EmbedText(text string) ([]float64, error) {
-	resp, err := ss.client.Embeddings(context.Background(), &ollama.EmbeddingRequest{
-		Model:  ss.Model,
-		Prompt: text,
-	})
-	if err != nil {
-		return nil, err
-	}
+func (ss *SemanticSearch) EmbedText(text string) ([]float32, error) {
+	return embed.One(context.Background(), ss.Embedder, text)
+}
 
-	return resp.Embedding, nil
+// EmbedQuery embeds a search query through the persistent query cache.
+// stored are the vectors it will be compared with: a cached vector of
+// another dimension is embedded again.
+func (ss *SemanticSearch) EmbedQuery(query string, stored [][]float32) ([]float32, error) {
+	dim := 0
+	if len(stored) > 0 {
+		dim = len(stored[0])
+	}
+	return embed.Query(context.Background(), ss.Embedder, query, dim)
 }
 
-func (ss *SemanticSearch) BuildEmbeddings() ([][]float64, error) {
+func (ss *SemanticSearch) BuildEmbeddings() ([][]float32, error) {
 	fmt.Println("🔄 Building embeddings…")
 
 	// Build strings: "title: description"
-	strings := make([]string, len(ss.Documents))
+	texts := make([]string, len(ss.Documents))
 	for i, doc := range ss.Documents {
 		ss.DocumentMap[doc.ID] = doc
-		strings[i] = fmt.Sprintf("%s: %s", doc.Title, doc.Description)
-	}
-
-	// Generate embeddings one by one (Ollama does not batch today)
-	// embeddings := make([][]float64, 0, len(strings))
-	// for i, text := range strings {
-	// 	fmt.Println("Creating embedding for doc:", i)
-	// 	resp, err := ss.client.Embeddings(context.Background(), &api.EmbeddingRequest{
-	// 		Model:  ss.Model,
-	// 		Prompt: text,
-	// 	})
-	// 	if err != nil {
-	// 		return nil, err
-	// 	}
-	// 	embeddings = append(embeddings, resp.Embedding)
-	// }
-	// ss.Embeddings = embeddings
+		texts[i] = fmt.Sprintf("%s: %s", doc.Title, doc.Description)
+	}
 
 	// Parallel embeddings creation
-	embeddings, err := ss.createEmbeddingsParallel(strings)
+	ss.Embeddings = nil
+	embeddings, ckpt, err := ss.createEmbeddingsParallel(texts, fs.EmbeddingsPath, ss.embeddingsManifest())
 	if err != nil {
 		return nil, err
 	}
@@ -114,20 +126,21 @@ func (ss *SemanticSearch) BuildEmbeddings() ([][]float64, error) {
 	if err := ss.saveEmbeddings(); err != nil {
 		return nil, err
 	}
+	if err := manifest.Save(fs.EmbeddingsPath, ss.embeddingsManifest()); err != nil {
+		return nil, err
+	}
+	if err := ckpt.Remove(); err != nil {
+		return nil, err
+	}
 
 	fmt.Println("✅ Embeddings built and saved.")
 	return embeddings, nil
 }
 
 func (ss *SemanticSearch) saveEmbeddings() error {
-	file, err := os.Create(fs.EmbeddingsPath)
-	if err != nil {
-		return err
-	}
-	defer file.Close()
-
-	encoder := gob.NewEncoder(file)
-	return encoder.Encode(ss.Embeddings)
+	return fs.WriteAtomic(fs.EmbeddingsPath, func(w io.Writer) error {
+		return gob.NewEncoder(w).Encode(ss.Embeddings)
+	})
 }
 
 func (ss *SemanticSearch) loadEmbeddings() error {
@@ -141,20 +154,66 @@ func (ss *SemanticSearch) loadEmbeddings() error {
 	return decoder.Decode(&ss.Embeddings)
 }
 
-func (ss *SemanticSearch) createEmbeddingsParallel(strings []string) ([][]float64, error) {
-	docCount := len(strings)
-	workerCount := runtime.NumCPU()
+// createEmbeddingsParallel embeds texts for the artifact at artifactPath,
+// checkpointing finished vectors as it goes (see checkpoint.Checkpoint).
+// On Ctrl-C or a failed batch, what's done so far is flushed so the build
+// can be continued with --resume. The caller removes the checkpoint once
+// the artifact is saved.
+func (ss *SemanticSearch) createEmbeddingsParallel(texts []string, artifactPath string, m manifest.Manifest) ([][]float32, *checkpoint.Checkpoint, error) {
+	cfg := config.Get()
+	docCount := len(texts)
+	workerCount := cfg.Embedding.Workers
+	if workerCount <= 0 {
+		workerCount = runtime.NumCPU()
+	}
+	info := ss.Embedder.Info()
 
-	fmt.Println("Starting embedding generation")
-	fmt.Printf("Documents: %d | Workers: %d\n", docCount, workerCount)
+	ckpt, done, err := checkpoint.Open(fs.CheckpointDir(artifactPath), checkpointKey(m), docCount, cfg.Embedding.CheckpointEvery, ss.Resume)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Texts embedded by an earlier build (of any collection) are reused as-is
+	var cache *embed.Cache
+	if cfg.Embedding.Cache {
+		cache, err = embed.OpenCache(info.Name())
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	// Learn the model's dimension before trusting cached vectors
+	if cache != nil && cache.Len() > 0 && info.Dimension == 0 && len(texts) > 0 {
+		if _, err := ss.EmbedText(texts[0]); err != nil {
+			return nil, nil, err
+		}
+		info = ss.Embedder.Info()
+	}
 
-	// Output slice (pre-allocate)
-	embeddings := make([][]float64, docCount)
+	// Only embed what the checkpoint and cache don't have yet
+	missing := make([]int, 0, docCount-len(done))
+	missingTexts := make([]string, 0, docCount-len(done))
+	hits := 0
+	for i, text := range texts {
+		if _, ok := done[i]; ok {
+			continue
+		}
+		if cache != nil {
+			if vector, ok := cache.Get(text, info.Dimension); ok {
+				done[i] = vector
+				hits++
+				continue
+			}
+		}
+		missing = append(missing, i)
+		missingTexts = append(missingTexts, text)
+	}
+	if cache != nil {
+		fmt.Printf("🗃️ Embedding cache: %d hits, %d to embed\n", hits, len(missing))
+	}
 
-	// Channels
-	jobs := make(chan int, docCount)
-	errChan := make(chan error, 1) // allow only 1 error to signal shutdown
-	wg := sync.WaitGroup{}
+	fmt.Println("Starting embedding generation")
+	fmt.Printf("Documents: %d | Batch size: %d | Max workers: %d | Model: %s\n", docCount, info.BatchSize, workerCount, info.Name())
 
 	// === Progress bar setup ===
 	p := mpb.New(mpb.WithWidth(60))
@@ -167,69 +226,68 @@ func (ss *SemanticSearch) createEmbeddingsParallel(strings []string) ([][]float6
 			decor.Percentage(),
 		),
 	)
-
-	// Worker function
-	worker := func(id int) {
-		defer wg.Done()
-
-		for idx := range jobs {
-			text := strings[idx]
-
-			// fmt.Printf("Worker %d processing doc number: %d\n", id, idx+1)
-
-			resp, err := ss.client.Embeddings(context.Background(), &ollama.EmbeddingRequest{
-				Model:  ss.Model,
-				Prompt: text,
-			})
-			if err != nil {
-				// first error wins, avoids blocking
-				select {
-				case errChan <- fmt.Errorf("embedding error on doc %d: %w", idx, err):
-				default:
-				}
-				return
+	bar.SetCurrent(int64(len(done)))
+
+	// Ctrl-C cancels in-flight batches instead of killing the process
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Batches are spread over the workers; the bar and checkpoint handle concurrent calls
+	var ckptErr error
+	var ckptOnce sync.Once
+	embeddings, stats, err := embed.Parallel(ctx, ss.Embedder, missingTexts, workerCount, cfg.Embedding.MaxRetries, func(from int, vectors [][]float32) {
+		if err := ckpt.Add(missing[from:from+len(vectors)], vectors); err != nil {
+			ckptOnce.Do(func() { ckptErr = err })
+		}
+		if cache != nil {
+			for j, vector := range vectors {
+				cache.Put(missingTexts[from+j], vector)
 			}
-
-			embeddings[idx] = resp.Embedding
-
-			// Increment progress bar (it handles it thread-safely internally)
-			bar.Increment()
 		}
+		bar.IncrBy(len(vectors))
+	})
+	if err == nil {
+		err = ckptErr
 	}
-
-	// Start workers
-	wg.Add(workerCount)
-	for i := 0; i < workerCount; i++ {
-		go worker(i + 1)
+	if cache != nil {
+		// Saved even on failure: finished batches are reused by the next run
+		if cacheErr := cache.Save(); cacheErr != nil && err == nil {
+			err = cacheErr
+		}
 	}
-
-	// Push jobs
-	for i := 0; i < docCount; i++ {
-		jobs <- i
+	if err != nil {
+		bar.Abort(false)
+		p.Wait()
+		if flushErr := ckpt.Flush(); flushErr != nil {
+			return nil, nil, fmt.Errorf("%w (and saving the checkpoint failed: %v)", err, flushErr)
+		}
+		if ctx.Err() != nil {
+			err = fmt.Errorf("interrupted")
+		}
+		return nil, nil, fmt.Errorf("%w; %d/%d embeddings checkpointed, rerun with --resume to continue", err, ckpt.Saved(), docCount)
 	}
-	close(jobs)
 
-	// Wait for workers
-	done := make(chan struct{})
-	go func() {
-		wg.Wait()
-		close(done)
-	}()
+	// Must wait for mpb to flush + close
+	p.Wait()
+	fmt.Printf("⏱️ Embedded %s\n", stats)
 
-	// If any worker errors, stop early
-	select {
-	case err := <-errChan:
-		return nil, err
-	case <-done:
+	all := make([][]float32, docCount)
+	for i, vector := range done {
+		all[i] = vector
+	}
+	for j, i := range missing {
+		all[i] = embeddings[j]
 	}
 
-	// Must wait for mpb to flush + close
-	p.Wait()
+	return all, ckpt, nil
+}
 
-	return embeddings, nil
+// checkpointKey ties a checkpoint to the inputs of the build it belongs to.
+func checkpointKey(m manifest.Manifest) string {
+	return strings.Join([]string{m.Artifact, m.SourceHash, m.EmbeddingModel, m.Chunking}, "|")
 }
 
-func (ss *SemanticSearch) LoadOrCreateEmbeddings(docs []model.Movie) ([][]float64, error) {
+func (ss *SemanticSearch) LoadOrCreateEmbeddings(docs []model.Movie) ([][]float32, error) {
 	ss.Documents = docs
 	for _, doc := range docs {
 		ss.DocumentMap[doc.ID] = doc
@@ -238,10 +296,19 @@ func (ss *SemanticSearch) LoadOrCreateEmbeddings(docs []model.Movie) ([][]float6
 	// If file exists → try loading
 	if _, err := os.Stat(fs.EmbeddingsPath); err == nil {
 		if err := ss.loadEmbeddings(); err == nil {
-			// Verify vector count matches document count
-			if len(ss.Embeddings) == len(ss.Documents) {
-				fmt.Println("📂 Loaded embeddings from disk.")
-				return ss.Embeddings, nil
+			// One vector per document, or the artifact is unusable
+			if len(ss.Embeddings) != len(ss.Documents) {
+				fmt.Printf("🔄 movie_embeddings.gob has %d vectors for %d documents, rebuilding\n", len(ss.Embeddings), len(ss.Documents))
+			} else {
+				reasons := manifest.CheckEmbeddings(fs.EmbeddingsPath, ss.embeddingsManifest(), ss.Embeddings, ss.Embedder.Info().Dimension)
+				rebuild, err := manifest.Resolve("movie_embeddings.gob", reasons)
+				if err != nil {
+					return nil, err
+				}
+				if !rebuild {
+					fmt.Println("📂 Loaded embeddings from disk.")
+					return ss.Embeddings, nil
+				}
 			}
 		}
 	}
@@ -250,53 +317,121 @@ func (ss *SemanticSearch) LoadOrCreateEmbeddings(docs []model.Movie) ([][]float6
 	return ss.BuildEmbeddings()
 }
 
+// embeddingsManifest describes the document embeddings for the current
+// documents and model (dimension from the embedder, or the vectors when the
+// embedder doesn't know it yet).
+func (ss *SemanticSearch) embeddingsManifest() manifest.Manifest {
+	m := manifest.Manifest{
+		Artifact:       "movie_embeddings.gob",
+		SourceHash:     manifest.SourceHash(ss.Documents),
+		DocIDs:         manifest.DocIDsHash(ss.Documents),
+		Documents:      len(ss.Documents),
+		EmbeddingModel: ss.Embedder.Info().Name(),
+	}
+	m.SetVectors(ss.Embeddings, ss.Embedder.Info().Dimension)
+	return m
+}
+
 func (ss *SemanticSearch) Search(query string, limit int) ([]SemanticSearchResult, error) {
+	if len(ss.FieldWeights) > 0 {
+		return ss.searchFields(query, limit)
+	}
 	if len(ss.Embeddings) == 0 || len(ss.Embeddings) != len(ss.Documents) {
 		return nil, fmt.Errorf("No embeddings loaded. Call `load_or_create_embeddings` first.")
 	}
 
-	query_embedding, err := ss.EmbedText(query)
+	query_embedding, err := ss.EmbedQuery(query, ss.Embeddings)
 	if err != nil {
 		return nil, fmt.Errorf("❌ Failed to create embedding of the query: %v\n", err)
 	}
+	rebuild, err := checkQueryDimension("movie_embeddings.gob", ss.Embedder, query_embedding, ss.Embeddings)
+	if err != nil {
+		return nil, err
+	}
+	if rebuild {
+		if _, err := ss.BuildEmbeddings(); err != nil {
+			return nil, err
+		}
+		// Indexes over the old vectors are rebuilt on the next load
+		ss.ANN, ss.Quantized = nil, nil
+	}
 
-	similarities := make([]SimilarityScore, 0, len(ss.Embeddings))
-
-	for i, doc_embedding := range ss.Embeddings {
-		similarity_score := CosineSimilarity(query_embedding, doc_embedding)
-		similarities = append(similarities, SimilarityScore{
-			Score: similarity_score,
-			Movie: ss.Documents[i],
-		})
+	if ss.ANN != nil {
+		results := make([]SemanticSearchResult, 0, limit)
+		for _, hit := range ss.ANN.Search(query_embedding, limit) {
+			doc := ss.Documents[hit.ID]
+			results = append(results, SemanticSearchResult{
+				DocID:       doc.ID,
+				Score:       float64(hit.Score),
+				Title:       doc.Title,
+				Description: doc.Description,
+			})
+		}
+		return results, nil
 	}
 
-	sort.Slice(similarities, func(i, j int) bool {
-		return similarities[i].Score > similarities[j].Score
-	})
+	if ss.Quantized != nil {
+		hits, err := quantizedTopK(ss.Quantized, ss.Embeddings, query_embedding, limit, ss.Rescore)
+		if err != nil {
+			return nil, err
+		}
+		results := make([]SemanticSearchResult, 0, limit)
+		for _, hit := range hits {
+			doc := ss.Documents[hit.idx]
+			results = append(results, SemanticSearchResult{
+				DocID:       doc.ID,
+				Score:       hit.score,
+				Title:       doc.Title,
+				Description: doc.Description,
+			})
+		}
+		return results, nil
+	}
 
 	results := make([]SemanticSearchResult, 0, limit)
-	for _, item := range similarities[:limit] {
+	for _, hit := range ss.vectors.get(ss.Embeddings).Search(query_embedding, limit) {
+		doc := ss.Documents[hit.ID]
 		results = append(results, SemanticSearchResult{
-			DocID:       item.Movie.ID,
-			Score:       item.Score,
-			Title:       item.Movie.Title,
-			Description: item.Movie.Description,
+			DocID:       doc.ID,
+			Score:       float64(hit.Score),
+			Title:       doc.Title,
+			Description: doc.Description,
 		})
 	}
 
 	return results, nil
 }
 
-func CosineSimilarity(vec1, vec2 []float64) float64 {
+// checkQueryDimension makes sure a query embedding can be compared with the
+// stored vectors. On a mismatch (the model changed since they were built) it
+// fails, or reports that they must be rebuilt under cache.on_stale: rebuild.
+func checkQueryDimension(artifact string, embedder embed.Embedder, query []float32, stored [][]float32) (bool, error) {
+	if len(stored) == 0 || len(query) == len(stored[0]) {
+		return false, nil
+	}
+
+	reason := fmt.Sprintf("query embedding from %s has dimension %d, stored vectors have %d", embedder.Info().Name(), len(query), len(stored[0]))
+	if config.Get().Cache.OnStale != manifest.PolicyRebuild {
+		return false, fmt.Errorf("%s doesn't match the embedding model: %s (rebuild it or set cache.on_stale: rebuild)", artifact, reason)
+	}
+	fmt.Printf("🔄 %s doesn't match the embedding model, rebuilding:\n\t- %s\n", artifact, reason)
+	return true, nil
+}
+
+// CosineSimilarity is 0 for vectors of different dimensions; searches check
+// the query dimension first (checkQueryDimension).
+func CosineSimilarity(vec1, vec2 []float32) float64 {
 	if len(vec1) != len(vec2) {
 		return 0.0 // or panic, but returning 0 is safer
 	}
 
+	// Accumulate in float64 so long vectors don't lose precision
 	var dot, norm1, norm2 float64
 	for i := range vec1 {
-		dot += vec1[i] * vec2[i]
-		norm1 += vec1[i] * vec1[i]
-		norm2 += vec2[i] * vec2[i]
+		a, b := float64(vec1[i]), float64(vec2[i])
+		dot += a * b
+		norm1 += a * a
+		norm2 += b * b
 	}
 
 	if norm1 == 0 || norm2 == 0 {
@@ -306,40 +441,13 @@ func CosineSimilarity(vec1, vec2 []float64) float64 {
 	return dot / (math.Sqrt(norm1) * math.Sqrt(norm2))
 }
 
+// SemanticChunk groups maxChunkSize sentences per chunk, overlapping by
+// overlap sentences (the "sentences" chunking strategy).
 func SemanticChunk(text string, maxChunkSize int, overlap int) []string {
-	// remove leading and trailing whitespace
-	text = strings.TrimSpace(text)
-
-	// TODO: this is removing the punctuation (add it back?)
-	sentenceRegex := regexp.MustCompile(`([.!?])\s+`)
-	rawSentences := sentenceRegex.Split(text, -1)
-
-	sentences := make([]string, 0)
-	for _, s := range rawSentences {
-		// remove leading and trailing whitespace
-		sentence := strings.TrimSpace(s)
-		if len(sentence) > 0 {
-			sentences = append(sentences, sentence)
-		}
-	}
-
-	if len(sentences) == 0 {
+	chunker, err := chunk.New(chunk.StrategySentences, chunk.Options{Size: maxChunkSize, Overlap: overlap})
+	if err != nil {
 		return []string{}
 	}
-
-	var chunks []string
-	start := 0
-	end := min(maxChunkSize, len(sentences))
-	c := strings.Join(sentences[start:end], " ")
-	chunks = append(chunks, c)
-
-	start = min(maxChunkSize-overlap, len(sentences))
-	for start+overlap < len(sentences) {
-		end = min(start+maxChunkSize, len(sentences))
-		c = strings.Join(sentences[start:end], " ")
-		chunks = append(chunks, c)
-		start = end - overlap
-	}
-
-	return chunks
+	chunks, _ := chunker.Split(context.Background(), []string{text})
+	return chunk.Texts(chunks[0])
 }
@@ -0,0 +1,22 @@
+package methods
+
+import (
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/config"
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/vecstore"
+)
+
+// vectorStore holds the contiguous store for a set of embeddings, built on
+// first use and again whenever the embeddings are replaced (loaded or
+// rebuilt into a new slice).
+type vectorStore struct {
+	store *vecstore.Store
+	src   [][]float32
+}
+
+func (v *vectorStore) get(vectors [][]float32) *vecstore.Store {
+	if v.store == nil || len(v.src) != len(vectors) || (len(vectors) > 0 && &v.src[0] != &vectors[0]) {
+		v.store = vecstore.New(vectors, config.Get().Search.Workers)
+		v.src = vectors
+	}
+	return v.store
+}
@@ -0,0 +1,206 @@
+package quant
+
+import (
+	"math"
+	"math/rand"
+	"runtime"
+	"sync"
+)
+
+const (
+	pqCentroids      = 256 // one byte per subvector code
+	pqTrainingSample = 4096
+)
+
+// PQ (product quantization) splits vectors into subspaces and stores, per
+// subspace, the id of the nearest of 256 k-means centroids.
+type PQ struct {
+	Dim       int
+	Bounds    []int         // subspace s covers dims Bounds[s]:Bounds[s+1]
+	Centroids [][][]float32 // subspace -> centroid -> sub-vector
+	Codes     []uint8       // len = vectors * subspaces
+	Norms     []float32     // norms of the reconstructed vectors
+}
+
+func TrainPQ(vectors [][]float32, params Params) *PQ {
+	dim := len(vectors[0])
+	subs := params.Subvectors
+	if subs <= 0 {
+		subs = max(1, dim/8)
+	}
+	subs = min(subs, dim)
+	iterations := params.Iterations
+	if iterations <= 0 {
+		iterations = 10
+	}
+
+	p := &PQ{
+		Dim:       dim,
+		Bounds:    make([]int, subs+1),
+		Centroids: make([][][]float32, subs),
+		Codes:     make([]uint8, len(vectors)*subs),
+		Norms:     make([]float32, len(vectors)),
+	}
+	for s := 0; s <= subs; s++ {
+		p.Bounds[s] = s * dim / subs
+	}
+
+	rng := rand.New(rand.NewSource(params.Seed))
+	sample := vectors
+	if len(vectors) > pqTrainingSample {
+		sample = make([][]float32, pqTrainingSample)
+		for i, j := range rng.Perm(len(vectors))[:pqTrainingSample] {
+			sample[i] = vectors[j]
+		}
+	}
+
+	// Subspaces are independent: train them in parallel
+	jobs := make(chan int, subs)
+	for s := 0; s < subs; s++ {
+		jobs <- s
+	}
+	close(jobs)
+
+	var wg sync.WaitGroup
+	for w := 0; w < runtime.NumCPU(); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for s := range jobs {
+				lo, hi := p.Bounds[s], p.Bounds[s+1]
+				p.Centroids[s] = kmeans(sample, lo, hi, iterations, params.Seed+int64(s))
+				for i, v := range vectors {
+					p.Codes[i*subs+s] = uint8(nearest(p.Centroids[s], v[lo:hi]))
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	for i := range vectors {
+		var sum float64
+		for s := 0; s < subs; s++ {
+			for _, x := range p.Centroids[s][p.Codes[i*subs+s]] {
+				sum += float64(x) * float64(x)
+			}
+		}
+		p.Norms[i] = float32(math.Sqrt(sum))
+	}
+
+	return p
+}
+
+func (p *PQ) Kind() string { return KindPQ }
+func (p *PQ) Len() int     { return len(p.Norms) }
+
+func (p *PQ) subspaces() int {
+	return len(p.Bounds) - 1
+}
+
+func (p *PQ) Bytes() int {
+	centroids := 0
+	for _, c := range p.Centroids {
+		centroids += len(c) * len(c[0]) * 4
+	}
+	return len(p.Codes) + centroids + 4*len(p.Norms)
+}
+
+// Scores uses asymmetric distance computation: the dot product of the query
+// with every centroid is tabulated once, then each vector is a sum of
+// table lookups.
+func (p *PQ) Scores(query []float32) ([]float32, error) {
+	if err := checkDim(query, p.Dim); err != nil {
+		return nil, err
+	}
+	subs := p.subspaces()
+	table := make([][]float32, subs)
+	for s := 0; s < subs; s++ {
+		q := query[p.Bounds[s]:p.Bounds[s+1]]
+		table[s] = make([]float32, len(p.Centroids[s]))
+		for c, centroid := range p.Centroids[s] {
+			var dot float32
+			for d, x := range centroid {
+				dot += q[d] * x
+			}
+			table[s][c] = dot
+		}
+	}
+	qn := norm(query)
+
+	scores := make([]float32, p.Len())
+	for i := range scores {
+		codes := p.Codes[i*subs : (i+1)*subs]
+		var dot float32
+		for s, c := range codes {
+			dot += table[s][c]
+		}
+		scores[i] = cosine(dot, qn, p.Norms[i])
+	}
+	return scores, nil
+}
+
+// kmeans clusters the [lo:hi) slice of every vector; empty clusters keep
+// their previous centroid.
+func kmeans(vectors [][]float32, lo int, hi int, iterations int, seed int64) [][]float32 {
+	k := min(pqCentroids, len(vectors))
+	width := hi - lo
+	rng := rand.New(rand.NewSource(seed))
+
+	centroids := make([][]float32, k)
+	for c, i := range rng.Perm(len(vectors))[:k] {
+		centroids[c] = append([]float32(nil), vectors[i][lo:hi]...)
+	}
+
+	assign := make([]int, len(vectors))
+	for it := 0; it < iterations; it++ {
+		changed := false
+		for i, v := range vectors {
+			c := nearest(centroids, v[lo:hi])
+			if c != assign[i] || it == 0 {
+				changed = true
+			}
+			assign[i] = c
+		}
+		if !changed {
+			break
+		}
+
+		sums := make([][]float64, k)
+		counts := make([]int, k)
+		for c := range sums {
+			sums[c] = make([]float64, width)
+		}
+		for i, v := range vectors {
+			c := assign[i]
+			counts[c]++
+			for d, x := range v[lo:hi] {
+				sums[c][d] += float64(x)
+			}
+		}
+		for c := range centroids {
+			if counts[c] == 0 {
+				continue
+			}
+			for d := range centroids[c] {
+				centroids[c][d] = float32(sums[c][d] / float64(counts[c]))
+			}
+		}
+	}
+
+	return centroids
+}
+
+func nearest(centroids [][]float32, v []float32) int {
+	best, bestDist := 0, float32(math.MaxFloat32)
+	for c, centroid := range centroids {
+		var dist float32
+		for d, x := range centroid {
+			diff := v[d] - x
+			dist += diff * diff
+		}
+		if dist < bestDist {
+			best, bestDist = c, dist
+		}
+	}
+	return best
+}
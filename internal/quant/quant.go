@@ -0,0 +1,136 @@
+package quant
+
+import (
+	"encoding/gob"
+	"fmt"
+	"io"
+	"math"
+	"os"
+
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/fs"
+)
+
+// Storage kinds for embeddings. Float keeps the full-precision vectors only.
+const (
+	KindFloat = "float32"
+	KindInt8  = "int8"
+	KindPQ    = "pq"
+)
+
+var Kinds = []string{KindFloat, KindInt8, KindPQ}
+
+// Quantizer is a compressed copy of a set of vectors that can score a
+// full-precision query against every stored vector (asymmetric distance:
+// the query itself is never quantized).
+type Quantizer interface {
+	Kind() string
+	Len() int
+	// Scores returns the approximate cosine similarity of query to every
+	// vector, or an error if query doesn't have the trained dimension.
+	Scores(query []float32) ([]float32, error)
+	// Bytes is the in-memory size of the codes plus codebooks.
+	Bytes() int
+}
+
+type Params struct {
+	// Subvectors is the number of PQ subspaces (one byte each per vector).
+	// 0 means one subspace per 8 dimensions.
+	Subvectors int
+	// Iterations of k-means per PQ subspace.
+	Iterations int
+	Seed       int64
+}
+
+// Fingerprint identifies how a quantizer was trained, for cache manifests.
+func Fingerprint(kind string, params Params) string {
+	if kind == KindPQ {
+		return fmt.Sprintf("pq(subvectors=%d,iterations=%d)", params.Subvectors, params.Iterations)
+	}
+	return kind
+}
+
+// Train quantizes vectors with the given kind.
+func Train(kind string, vectors [][]float32, params Params) (Quantizer, error) {
+	if len(vectors) == 0 {
+		return nil, fmt.Errorf("no vectors to quantize")
+	}
+
+	switch kind {
+	case KindInt8:
+		return TrainScalar(vectors), nil
+	case KindPQ:
+		return TrainPQ(vectors, params), nil
+	default:
+		return nil, fmt.Errorf("unknown quantization %q", kind)
+	}
+}
+
+// file is the on-disk form; gob can't encode the interface directly.
+type file struct {
+	Scalar *Scalar
+	PQ     *PQ
+}
+
+func Save(path string, q Quantizer) error {
+	var f file
+	switch v := q.(type) {
+	case *Scalar:
+		f.Scalar = v
+	case *PQ:
+		f.PQ = v
+	default:
+		return fmt.Errorf("unsupported quantizer %T", q)
+	}
+
+	err := fs.WriteAtomic(path, func(w io.Writer) error {
+		return gob.NewEncoder(w).Encode(f)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write quantized embeddings: %w", err)
+	}
+	return nil
+}
+
+func Load(path string) (Quantizer, error) {
+	in, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open quantized embeddings file: %w", err)
+	}
+	defer in.Close()
+
+	var f file
+	if err := gob.NewDecoder(in).Decode(&f); err != nil {
+		return nil, fmt.Errorf("failed to decode quantized embeddings: %w", err)
+	}
+
+	switch {
+	case f.Scalar != nil:
+		return f.Scalar, nil
+	case f.PQ != nil:
+		return f.PQ, nil
+	default:
+		return nil, fmt.Errorf("empty quantized embeddings file %s", path)
+	}
+}
+
+func checkDim(query []float32, dim int) error {
+	if len(query) != dim {
+		return fmt.Errorf("query has %d dimensions, quantized vectors have %d", len(query), dim)
+	}
+	return nil
+}
+
+func norm(v []float32) float32 {
+	var sum float64
+	for _, x := range v {
+		sum += float64(x) * float64(x)
+	}
+	return float32(math.Sqrt(sum))
+}
+
+func cosine(dot float32, queryNorm float32, vectorNorm float32) float32 {
+	if queryNorm == 0 || vectorNorm == 0 {
+		return 0
+	}
+	return dot / (queryNorm * vectorNorm)
+}
@@ -0,0 +1,182 @@
+package quant
+
+import (
+	"math"
+	"math/rand"
+	"path/filepath"
+	"testing"
+)
+
+func randomVectors(n int, dim int, seed int64) [][]float32 {
+	rng := rand.New(rand.NewSource(seed))
+	vectors := make([][]float32, n)
+	for i := range vectors {
+		vectors[i] = make([]float32, dim)
+		for j := range vectors[i] {
+			vectors[i][j] = float32(rng.NormFloat64())
+		}
+	}
+	return vectors
+}
+
+func exactCosine(a, b []float32) float32 {
+	var dot float32
+	for i := range a {
+		dot += a[i] * b[i]
+	}
+	return cosine(dot, norm(a), norm(b))
+}
+
+func TestScalarRoundTrip(t *testing.T) {
+	vectors := randomVectors(300, 24, 1)
+	s := TrainScalar(vectors)
+
+	for i, v := range vectors {
+		for d, x := range v {
+			decoded := s.Offset[d] + s.Scale[d]*float32(s.Codes[i*s.Dim+d])
+			if diff := math.Abs(float64(decoded - x)); diff > float64(s.Scale[d])/2+1e-6 {
+				t.Fatalf("vector %d dim %d: decoded %f, want %f ± %f", i, d, decoded, x, s.Scale[d]/2)
+			}
+		}
+	}
+}
+
+func TestScalarConstantDimension(t *testing.T) {
+	s := TrainScalar([][]float32{{1, 0}, {1, 2}})
+	if s.Scale[0] != 1 {
+		t.Errorf("scale of a constant dimension = %f, want 1", s.Scale[0])
+	}
+	decoded := s.Offset[0] + s.Scale[0]*float32(s.Codes[0])
+	if decoded != 1 {
+		t.Errorf("constant dimension decoded as %f, want 1", decoded)
+	}
+}
+
+// ADC scores should match the exact cosine of the query with each stored
+// vector within the quantization error.
+func TestScoresApproximateCosine(t *testing.T) {
+	vectors := randomVectors(500, 32, 2)
+	queries := randomVectors(20, 32, 3)
+
+	tests := []struct {
+		name    string
+		kind    string
+		params  Params
+		maxMean float64 // mean absolute error of the scores
+	}{
+		{"int8", KindInt8, Params{}, 0.005},
+		{"pq 8 subvectors", KindPQ, Params{Subvectors: 8, Iterations: 10, Seed: 1}, 0.05},
+		{"pq one per dimension", KindPQ, Params{Subvectors: 32, Iterations: 10, Seed: 1}, 0.01},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := Train(tt.kind, vectors, tt.params)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if q.Len() != len(vectors) {
+				t.Fatalf("Len = %d, want %d", q.Len(), len(vectors))
+			}
+
+			var sum float64
+			for _, query := range queries {
+				scores, err := q.Scores(query)
+				if err != nil {
+					t.Fatal(err)
+				}
+				for i, v := range vectors {
+					sum += math.Abs(float64(scores[i] - exactCosine(query, v)))
+				}
+			}
+			if mean := sum / float64(len(queries)*len(vectors)); mean > tt.maxMean {
+				t.Errorf("mean score error = %.4f, want <= %.4f", mean, tt.maxMean)
+			}
+		})
+	}
+}
+
+// The table lookups of PQ.Scores must equal the cosine with the
+// reconstructed vectors exactly (up to float rounding).
+func TestPQScoresMatchReconstruction(t *testing.T) {
+	vectors := randomVectors(300, 16, 4)
+	p := TrainPQ(vectors, Params{Subvectors: 4, Iterations: 5, Seed: 1})
+	query := randomVectors(1, 16, 5)[0]
+
+	scores, err := p.Scores(query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	subs := p.subspaces()
+	for i := range vectors {
+		decoded := make([]float32, 0, p.Dim)
+		for s := 0; s < subs; s++ {
+			decoded = append(decoded, p.Centroids[s][p.Codes[i*subs+s]]...)
+		}
+		if want := exactCosine(query, decoded); math.Abs(float64(scores[i]-want)) > 1e-4 {
+			t.Fatalf("vector %d: score %f, want %f", i, scores[i], want)
+		}
+	}
+}
+
+func TestScoresDimensionMismatch(t *testing.T) {
+	vectors := randomVectors(50, 8, 6)
+	for _, kind := range []string{KindInt8, KindPQ} {
+		t.Run(kind, func(t *testing.T) {
+			q, err := Train(kind, vectors, Params{Subvectors: 2, Iterations: 2})
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, dim := range []int{4, 16} {
+				if _, err := q.Scores(make([]float32, dim)); err == nil {
+					t.Errorf("query of dimension %d: expected an error", dim)
+				}
+			}
+		})
+	}
+}
+
+func TestTrainErrors(t *testing.T) {
+	if _, err := Train(KindInt8, nil, Params{}); err == nil {
+		t.Error("no vectors: expected an error")
+	}
+	if _, err := Train("int4", randomVectors(2, 2, 1), Params{}); err == nil {
+		t.Error("unknown kind: expected an error")
+	}
+}
+
+func TestSaveLoad(t *testing.T) {
+	vectors := randomVectors(100, 8, 7)
+	query := randomVectors(1, 8, 8)[0]
+
+	for _, kind := range []string{KindInt8, KindPQ} {
+		t.Run(kind, func(t *testing.T) {
+			q, err := Train(kind, vectors, Params{Subvectors: 2, Iterations: 3})
+			if err != nil {
+				t.Fatal(err)
+			}
+			path := filepath.Join(t.TempDir(), "vectors.quant")
+			if err := Save(path, q); err != nil {
+				t.Fatal(err)
+			}
+			loaded, err := Load(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if loaded.Kind() != kind {
+				t.Fatalf("loaded kind %q, want %q", loaded.Kind(), kind)
+			}
+
+			want, _ := q.Scores(query)
+			got, err := loaded.Scores(query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i := range want {
+				if got[i] != want[i] {
+					t.Fatalf("score %d after load = %f, want %f", i, got[i], want[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package quant
+
+import "math"
+
+// Scalar stores each dimension as an int8 code: x ≈ Offset[d] + Scale[d]*code,
+// with Offset/Scale fitted to that dimension's min/max.
+type Scalar struct {
+	Dim    int
+	Offset []float32
+	Scale  []float32
+	Codes  []int8    // len = vectors * Dim
+	Norms  []float32 // norms of the reconstructed vectors
+}
+
+func TrainScalar(vectors [][]float32) *Scalar {
+	dim := len(vectors[0])
+	s := &Scalar{
+		Dim:    dim,
+		Offset: make([]float32, dim),
+		Scale:  make([]float32, dim),
+		Codes:  make([]int8, len(vectors)*dim),
+		Norms:  make([]float32, len(vectors)),
+	}
+
+	for d := 0; d < dim; d++ {
+		lo, hi := vectors[0][d], vectors[0][d]
+		for _, v := range vectors {
+			lo = min(lo, v[d])
+			hi = max(hi, v[d])
+		}
+		s.Offset[d] = (lo + hi) / 2
+		s.Scale[d] = (hi - lo) / 254
+		if s.Scale[d] == 0 {
+			s.Scale[d] = 1
+		}
+	}
+
+	decoded := make([]float32, dim)
+	for i, v := range vectors {
+		codes := s.Codes[i*dim : (i+1)*dim]
+		for d, x := range v {
+			c := math.Round(float64((x - s.Offset[d]) / s.Scale[d]))
+			codes[d] = int8(max(-127, min(127, c)))
+			decoded[d] = s.Offset[d] + s.Scale[d]*float32(codes[d])
+		}
+		s.Norms[i] = norm(decoded)
+	}
+
+	return s
+}
+
+func (s *Scalar) Kind() string { return KindInt8 }
+func (s *Scalar) Len() int     { return len(s.Norms) }
+
+func (s *Scalar) Bytes() int {
+	return len(s.Codes) + 4*(len(s.Offset)+len(s.Scale)+len(s.Norms))
+}
+
+// Scores folds the per-dimension scale into the query once, so each vector
+// costs one multiply-add per dimension on its int8 codes.
+func (s *Scalar) Scores(query []float32) ([]float32, error) {
+	if err := checkDim(query, s.Dim); err != nil {
+		return nil, err
+	}
+	scaled := make([]float32, s.Dim)
+	var base float32
+	for d, x := range query {
+		scaled[d] = x * s.Scale[d]
+		base += x * s.Offset[d]
+	}
+	qn := norm(query)
+
+	scores := make([]float32, s.Len())
+	for i := range scores {
+		codes := s.Codes[i*s.Dim : (i+1)*s.Dim]
+		dot := base
+		for d, c := range codes {
+			dot += scaled[d] * float32(c)
+		}
+		scores[i] = cosine(dot, qn, s.Norms[i])
+	}
+	return scores, nil
+}
@@ -7,6 +7,10 @@ import (
 	"github.com/reiver/go-porterstemmer"
 )
 
+// Analyzer identifies the Tokenize pipeline in cache manifests.
+// Change it whenever Tokenize changes so indexes built with the old one are rebuilt.
+const Analyzer = "lowercase|strip-punct|porter"
+
 // HasMatchingToken checks if any token from queryTokens is contained within any token of titleTokens.
 func HasMatchingToken(queryTokens, titleTokens []string) bool {
 	for _, q := range queryTokens {
@@ -0,0 +1,137 @@
+// Package trec reads and writes rankings in the TREC run format, one line
+// per retrieved document:
+//
+//	<query id> Q0 <doc id> <rank> <score> <run tag>
+//
+// with 1-based ranks, so runs can be saved, compared and fused later.
+package trec
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/fs"
+)
+
+// Entry is one retrieved document of a query.
+type Entry struct {
+	DocID string
+	Score float64
+}
+
+// Run holds the ranking of every query, best first.
+type Run struct {
+	Tag     string
+	Queries map[string][]Entry
+}
+
+func NewRun(tag string) *Run {
+	return &Run{Tag: tag, Queries: make(map[string][]Entry)}
+}
+
+// QueryIDs lists the queries of the run, sorted.
+func (r *Run) QueryIDs() []string {
+	ids := make([]string, 0, len(r.Queries))
+	for id := range r.Queries {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// Read parses a run file. Lines are ordered by rank within each query, so
+// files that aren't sorted still load as rankings; repeated documents keep
+// their best rank.
+func Read(path string) (*Run, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	type line struct {
+		entry Entry
+		rank  int
+	}
+	lines := make(map[string][]line)
+	run := NewRun("")
+
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 6 {
+			return nil, fmt.Errorf("%s:%d: expected 6 fields (qid Q0 docid rank score tag), got %d", path, n, len(fields))
+		}
+		rank, err := strconv.Atoi(fields[3])
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: invalid rank %q", path, n, fields[3])
+		}
+		score, err := strconv.ParseFloat(fields[4], 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: invalid score %q", path, n, fields[4])
+		}
+		lines[fields[0]] = append(lines[fields[0]], line{Entry{DocID: fields[2], Score: score}, rank})
+		run.Tag = fields[5]
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	for qid, ls := range lines {
+		sort.SliceStable(ls, func(i, j int) bool { return ls[i].rank < ls[j].rank })
+		// A document listed twice keeps its best rank
+		seen := make(map[string]bool, len(ls))
+		entries := make([]Entry, 0, len(ls))
+		for _, l := range ls {
+			if seen[l.entry.DocID] {
+				continue
+			}
+			seen[l.entry.DocID] = true
+			entries = append(entries, l.entry)
+		}
+		run.Queries[qid] = entries
+	}
+	return run, nil
+}
+
+// Write saves the run, queries sorted by ID.
+func (r *Run) Write(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return err
+	}
+	return fs.WriteAtomic(path, func(w io.Writer) error {
+		bw := bufio.NewWriter(w)
+		for _, qid := range r.QueryIDs() {
+			for i, e := range r.Queries[qid] {
+				if _, err := fmt.Fprintf(bw, "%s Q0 %s %d %.6f %s\n", qid, e.DocID, i+1, e.Score, r.Tag); err != nil {
+					return err
+				}
+			}
+		}
+		return bw.Flush()
+	})
+}
+
+// Update sets the ranking of one query in the run file at path, keeping the
+// other queries already in it.
+func Update(path string, tag string, qid string, entries []Entry) error {
+	run, err := Read(path)
+	if os.IsNotExist(err) {
+		run, err = NewRun(tag), nil
+	}
+	if err != nil {
+		return err
+	}
+	run.Tag = tag
+	run.Queries[qid] = entries
+	return run.Write(path)
+}
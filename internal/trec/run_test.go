@@ -0,0 +1,75 @@
+package trec
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestRead(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    map[string][]Entry
+		wantErr bool
+	}{
+		{
+			name:    "sorted by rank",
+			content: "q1 Q0 b 2 0.5 bm25\nq1 Q0 a 1 0.9 bm25\n\nq2 Q0 c 1 0.7 bm25\n",
+			want: map[string][]Entry{
+				"q1": {{DocID: "a", Score: 0.9}, {DocID: "b", Score: 0.5}},
+				"q2": {{DocID: "c", Score: 0.7}},
+			},
+		},
+		{
+			name:    "duplicates keep their best rank",
+			content: "q1 Q0 a 3 0.1 bm25\nq1 Q0 b 2 0.5 bm25\nq1 Q0 a 1 0.9 bm25\n",
+			want: map[string][]Entry{
+				"q1": {{DocID: "a", Score: 0.9}, {DocID: "b", Score: 0.5}},
+			},
+		},
+		{name: "missing field", content: "q1 Q0 a 1 0.9\n", wantErr: true},
+		{name: "bad rank", content: "q1 Q0 a first 0.9 bm25\n", wantErr: true},
+		{name: "bad score", content: "q1 Q0 a 1 high bm25\n", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "run.txt")
+			if err := os.WriteFile(path, []byte(tt.content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			run, err := Read(path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Read error = %v, want error: %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if run.Tag != "bm25" {
+				t.Errorf("tag = %q, want bm25", run.Tag)
+			}
+			if !reflect.DeepEqual(run.Queries, tt.want) {
+				t.Errorf("queries = %+v, want %+v", run.Queries, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteRead(t *testing.T) {
+	run := NewRun("fused")
+	run.Queries["q2"] = []Entry{{DocID: "7", Score: 0.25}}
+	run.Queries["q1"] = []Entry{{DocID: "3", Score: 1.5}, {DocID: "1", Score: 0.5}}
+
+	path := filepath.Join(t.TempDir(), "runs", "fused.txt")
+	if err := run.Write(path); err != nil {
+		t.Fatal(err)
+	}
+	got, err := Read(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, run) {
+		t.Errorf("read back %+v, want %+v", got, run)
+	}
+}
@@ -3,13 +3,25 @@ package utils
 import (
 	"fmt"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/agustin-carnevale/advanced-search-hoopla-go/internal/methods"
 )
 
+// ResultsListToStr formats results for RAG prompts. Results with matching
+// passages contribute only those, not the whole description.
 func ResultsListToStr(results []methods.RRFSearchResult) string {
 	parts := make([]string, len(results))
 	for i, doc := range results {
+		if len(doc.Passages) > 0 {
+			parts[i] = fmt.Sprintf(
+				"ID: %d\nTitle: %s\nPassages: %s",
+				doc.DocID,
+				doc.Title,
+				PassagesToStr(doc.Passages),
+			)
+			continue
+		}
 		parts[i] = fmt.Sprintf(
 			"ID: %d\nTitle: %s\nDescription: %s",
 			doc.DocID,
@@ -21,3 +33,25 @@ func ResultsListToStr(results []methods.RRFSearchResult) string {
 
 	return resultListStr
 }
+
+// PassagesToStr joins passages on one line, each with its whitespace
+// collapsed, separated by an ellipsis.
+func PassagesToStr(passages []methods.Passage) string {
+	texts := make([]string, len(passages))
+	for i, p := range passages {
+		texts[i] = strings.Join(strings.Fields(p.Text), " ")
+	}
+	return strings.Join(texts, " … ")
+}
+
+// Truncate cuts s to at most max bytes, at a rune boundary, when it's
+// longer.
+func Truncate(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	for max > 0 && !utf8.RuneStart(s[max]) {
+		max--
+	}
+	return s[:max]
+}
@@ -0,0 +1,118 @@
+package vecstore
+
+import (
+	"runtime"
+	"sync"
+)
+
+// minPerWorker keeps small scans on one goroutine: below this many vectors
+// per worker, starting goroutines costs more than it saves.
+const minPerWorker = 2048
+
+// Store keeps unit-length vectors back to back in one buffer, so cosine
+// similarity is a dot product over memory that is read sequentially.
+type Store struct {
+	dim     int
+	n       int
+	data    []float32
+	workers int
+}
+
+// Hit is a vector position and its cosine similarity to the query.
+type Hit struct {
+	ID    int
+	Score float32
+}
+
+// New copies and normalizes vectors (which must share one dimension). Zero
+// vectors stay zero and score 0, like CosineSimilarity. workers <= 0 uses
+// GOMAXPROCS.
+func New(vectors [][]float32, workers int) *Store {
+	s := &Store{n: len(vectors), workers: workers}
+	if s.workers <= 0 {
+		s.workers = runtime.GOMAXPROCS(0)
+	}
+	if s.n == 0 {
+		return s
+	}
+
+	s.dim = len(vectors[0])
+	s.data = make([]float32, 0, s.n*s.dim)
+	for _, v := range vectors {
+		s.data = append(s.data, Normalize(v)...)
+	}
+	return s
+}
+
+func (s *Store) Len() int { return s.n }
+
+func (s *Store) Dim() int { return s.dim }
+
+// Vector is the normalized vector i (a view into the buffer).
+func (s *Store) Vector(i int) []float32 {
+	return s.data[i*s.dim : (i+1)*s.dim : (i+1)*s.dim]
+}
+
+// Search returns the k vectors most similar to query, best first (none
+// for a query of another dimension). Each worker scans its own range into
+// its own top-k heap; the heaps are merged at the end.
+func (s *Store) Search(query []float32, k int) []Hit {
+	k = min(k, s.n)
+	if k <= 0 || len(query) != s.dim {
+		return []Hit{}
+	}
+	q := Normalize(query)
+
+	var (
+		mu   sync.Mutex
+		best []Hit
+	)
+	s.parallel(func(from, to int) {
+		top := NewTopK(k)
+		for i := from; i < to; i++ {
+			top.Push(Hit{i, Dot(q, s.Vector(i))})
+		}
+		mu.Lock()
+		best = append(best, top.h...)
+		mu.Unlock()
+	})
+
+	SortHits(best)
+	return best[:k]
+}
+
+// Scores returns the similarity of query to every vector, in order (all 0
+// for a query of another dimension).
+func (s *Store) Scores(query []float32) []float32 {
+	q := Normalize(query)
+	scores := make([]float32, s.n)
+	if len(q) != s.dim {
+		return scores
+	}
+	s.parallel(func(from, to int) {
+		for i := from; i < to; i++ {
+			scores[i] = Dot(q, s.Vector(i))
+		}
+	})
+	return scores
+}
+
+// parallel splits [0, n) into one contiguous range per worker.
+func (s *Store) parallel(scan func(from, to int)) {
+	workers := min(s.workers, max(s.n/minPerWorker, 1))
+	if workers <= 1 {
+		scan(0, s.n)
+		return
+	}
+
+	size := (s.n + workers - 1) / workers
+	var wg sync.WaitGroup
+	for from := 0; from < s.n; from += size {
+		wg.Add(1)
+		go func(from, to int) {
+			defer wg.Done()
+			scan(from, to)
+		}(from, min(from+size, s.n))
+	}
+	wg.Wait()
+}
@@ -0,0 +1,49 @@
+package vecstore
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTopScores(t *testing.T) {
+	tests := []struct {
+		name   string
+		scores []float32
+		k      int
+		want   []int
+	}{
+		{"best first", []float32{0.1, 0.9, 0.5, 0.7}, 2, []int{1, 3}},
+		{"ties go to the lower id", []float32{0.5, 0.9, 0.5, 0.5}, 3, []int{1, 0, 2}},
+		{"k above len", []float32{0.2, 0.4}, 5, []int{1, 0}},
+		{"k zero", []float32{0.2, 0.4}, 0, []int{}},
+		{"no scores", nil, 3, []int{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := TopScores(tt.scores, tt.k); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("TopScores = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStoreSearch(t *testing.T) {
+	vectors := [][]float32{{1, 0}, {0, 2}, {3, 3}, {-1, 0}, {0, 0}}
+	s := New(vectors, 0)
+
+	// Vectors 1 and 4 tie at 0: the lower id wins
+	hits := s.Search([]float32{5, 0}, 3)
+	ids := make([]int, len(hits))
+	for i, h := range hits {
+		ids[i] = h.ID
+	}
+	if want := []int{0, 2, 1}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("ids = %v, want %v", ids, want)
+	}
+	if hits[0].Score < 0.999 {
+		t.Errorf("score of the same direction = %f, want 1", hits[0].Score)
+	}
+	if got := s.Search([]float32{1, 0, 0}, 3); len(got) != 0 {
+		t.Errorf("query of another dimension returned %v", got)
+	}
+}
@@ -0,0 +1,90 @@
+package vecstore
+
+import (
+	"container/heap"
+	"sort"
+)
+
+// TopK keeps the k best hits pushed into it. Ties go to the lower ID, so
+// results don't depend on the order hits arrive in.
+type TopK struct {
+	k int
+	h hitHeap
+}
+
+func NewTopK(k int) *TopK {
+	return &TopK{k: k, h: make(hitHeap, 0, max(k, 0))}
+}
+
+func (t *TopK) Len() int { return len(t.h) }
+
+// Full reports whether k hits are kept, so only better ones get in.
+func (t *TopK) Full() bool { return len(t.h) >= t.k }
+
+// Worst is the weakest hit kept (Len must be > 0).
+func (t *TopK) Worst() Hit { return t.h[0] }
+
+// Push keeps hit if there is room or it beats the weakest one kept, and
+// reports whether it was kept.
+func (t *TopK) Push(hit Hit) bool {
+	switch {
+	case t.k <= 0:
+		return false
+	case len(t.h) < t.k:
+		heap.Push(&t.h, hit)
+	case better(hit, t.h[0]):
+		t.h[0] = hit
+		heap.Fix(&t.h, 0)
+	default:
+		return false
+	}
+	return true
+}
+
+// Hits returns the hits kept, best first.
+func (t *TopK) Hits() []Hit {
+	hits := append([]Hit(nil), t.h...)
+	SortHits(hits)
+	return hits
+}
+
+// SortHits sorts hits best first, ties by ID.
+func SortHits(hits []Hit) {
+	sort.Slice(hits, func(i, j int) bool { return better(hits[i], hits[j]) })
+}
+
+// TopScores returns the indices of the k highest scores, best first.
+func TopScores(scores []float32, k int) []int {
+	t := NewTopK(min(k, len(scores)))
+	for i, s := range scores {
+		t.Push(Hit{i, s})
+	}
+	hits := t.Hits()
+	ids := make([]int, len(hits))
+	for i, h := range hits {
+		ids[i] = h.ID
+	}
+	return ids
+}
+
+func better(a, b Hit) bool {
+	if a.Score != b.Score {
+		return a.Score > b.Score
+	}
+	return a.ID < b.ID
+}
+
+// hitHeap is a min-heap, so the weakest of the current top-k is on top.
+type hitHeap []Hit
+
+func (h hitHeap) Len() int           { return len(h) }
+func (h hitHeap) Less(i, j int) bool { return better(h[j], h[i]) }
+func (h hitHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *hitHeap) Push(x any)        { *h = append(*h, x.(Hit)) }
+func (h *hitHeap) Pop() any {
+	old := *h
+	n := len(old)
+	x := old[n-1]
+	*h = old[:n-1]
+	return x
+}